@@ -0,0 +1,154 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/shopspring/decimal"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/stafiprotocol/eth-lsd-relay/pkg/config"
+	"github.com/stafiprotocol/eth-lsd-relay/pkg/utils"
+	"github.com/stafiprotocol/eth-lsd-relay/service"
+)
+
+const (
+	flagLsdToken = "lsd-token"
+	flagEpoch    = "epoch"
+	flagDataPath = "data-path"
+	flagJson     = "json"
+)
+
+func computeBalancesCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "compute-balances",
+		Args:  cobra.ExactArgs(0),
+		Short: "Compute the balances submitBalances would vote for at an epoch, no keystore needed and no tx sent",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			basePath, err := cmd.Flags().GetString(flagBasePath)
+			if err != nil {
+				return err
+			}
+			cfg, err := config.Load(basePath)
+			if err != nil {
+				return err
+			}
+
+			logLevelStr, err := cmd.Flags().GetString(flagLogLevel)
+			if err != nil {
+				return err
+			}
+			logLevel, err := logrus.ParseLevel(logLevelStr)
+			if err != nil {
+				return err
+			}
+			logrus.SetLevel(logLevel)
+
+			lsdToken, err := cmd.Flags().GetString(flagLsdToken)
+			if err != nil {
+				return err
+			}
+			if lsdToken == "" {
+				lsdToken = cfg.Contracts.LsdTokenAddress
+			}
+			if !common.IsHexAddress(lsdToken) {
+				return fmt.Errorf("invalid lsd token address: %s", lsdToken)
+			}
+			epoch, err := cmd.Flags().GetUint64(flagEpoch)
+			if err != nil {
+				return err
+			}
+			dataPath, err := cmd.Flags().GetString(flagDataPath)
+			if err != nil {
+				return err
+			}
+			if dataPath != "" {
+				dataPath = strings.TrimSuffix(dataPath, "/")
+				cfg.BlockstoreFilePath = dataPath + "/blockstore"
+				cfg.StateStorePath = dataPath + "/state"
+				cfg.BeaconBlockStorePath = dataPath + "/beacon_blocks"
+			}
+			printJson, err := cmd.Flags().GetBool(flagJson)
+			if err != nil {
+				return err
+			}
+
+			// offline: no api server, no pinata unpinning
+			cfg.Api.ListenAddr = ""
+			cfg.Pinata.PinDays = 0
+
+			srvManager, err := service.NewServiceManager(cfg, nil)
+			if err != nil {
+				return fmt.Errorf("NewServiceManager err: %w (stop the relay or use --%s if the stores are locked)", err, flagDataPath)
+			}
+			defer srvManager.Stop()
+
+			breakdown, err := srvManager.ComputeBalances(utils.ShutdownListener(), common.HexToAddress(lsdToken).String(), epoch)
+			if err != nil {
+				return err
+			}
+
+			if printJson {
+				encoder := json.NewEncoder(os.Stdout)
+				encoder.SetIndent("", "  ")
+				return encoder.Encode(breakdown)
+			}
+			printBalancesBreakdown(breakdown)
+			return nil
+		},
+	}
+
+	cmd.Flags().String(flagBasePath, defaultBasePath, "base path a directory where your config.toml resids")
+	cmd.Flags().String(flagLogLevel, logrus.WarnLevel.String(), "The logging level (trace|debug|info|warn|error|fatal|panic)")
+	cmd.Flags().String(flagLsdToken, "", "lsd token address, default to the lsdTokenAddress of config")
+	cmd.Flags().Uint64(flagEpoch, 0, "target epoch")
+	cmd.Flags().String(flagDataPath, "", "directory of the local stores, default to base path, use another one while the relay is running")
+	cmd.Flags().Bool(flagJson, false, "print the breakdown in json")
+	_ = cmd.MarkFlagRequired(flagEpoch)
+
+	return cmd
+}
+
+func printBalancesBreakdown(b *service.BalancesBreakdown) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	defer w.Flush()
+
+	fmt.Fprintf(w, "lsd token:\t%s\n", b.LsdToken)
+	fmt.Fprintf(w, "target epoch:\t%d\n", b.TargetEpoch)
+	fmt.Fprintf(w, "target block:\t%d\n", b.TargetBlock)
+	fmt.Fprintf(w, "latest distribute withdrawals height:\t%d\n", b.LatestDistributeWithdrawalsHeight)
+	fmt.Fprintf(w, "latest distribute priority fee height:\t%d\n", b.LatestDistributePriorityFeeHeight)
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "user eth from validators:\t%s\n", ether(b.TotalUserEthFromValidator))
+	fmt.Fprintf(w, "+ deposit pool balance:\t%s\n", ether(b.UserDepositPoolBalance))
+	fmt.Fprintf(w, "+ undistributed withdrawals:\t%s\n", ether(b.UserUndistributedWithdrawals))
+	fmt.Fprintf(w, "+ undistributed priority fee:\t%s\n", ether(b.UserUndistributedPriorityFee))
+	fmt.Fprintf(w, "- total missing amount for withdraw:\t%s\n", ether(b.TotalMissingAmountForWithdraw))
+	adjusted := ""
+	if b.TotalUserEthAdjusted {
+		adjusted = " (raised to lsd token total supply)"
+	}
+	fmt.Fprintf(w, "= total user eth:\t%s%s\n", ether(b.TotalUserEth), adjusted)
+	fmt.Fprintf(w, "lsd token total supply:\t%s\n", ether(b.LsdTokenTotalSupply))
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "old exchange rate:\t%s\n", ether(b.OldExchangeRate))
+	fmt.Fprintf(w, "new exchange rate:\t%s\n", ether(b.NewExchangeRate))
+	fmt.Fprintf(w, "rate change:\t%s (limit %s, exceeded: %v)\n", ether(b.RateChange), ether(b.RateChangeLimit), b.RateChangeExceeded)
+	fmt.Fprintln(w)
+
+	fmt.Fprintf(w, "validators (%d):\n", len(b.Validators))
+	fmt.Fprintln(w, "pubkey\tindex\tnode\tnodeType\tonchainStatus\tbeaconStatus\tbalance(Gwei)\tuserEth")
+	for _, v := range b.Validators {
+		fmt.Fprintf(w, "%s\t%d\t%s\t%d\t%d\t%d\t%d\t%s\n",
+			v.Pubkey, v.ValidatorIndex, v.NodeAddress, v.NodeType, v.OnchainStatus, v.BeaconStatus, v.Balance, ether(v.UserEth))
+	}
+}
+
+// ether formats an amount of decimals 18
+func ether(amount decimal.Decimal) string {
+	return amount.Div(decimal.NewFromInt(1e18)).StringFixed(18)
+}
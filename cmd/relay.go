@@ -5,14 +5,17 @@ package cmd
 
 import (
 	"fmt"
+	"time"
 
-	"github.com/shopspring/decimal"
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"github.com/stafiprotocol/chainbridge/utils/crypto/secp256k1"
 	"github.com/stafiprotocol/chainbridge/utils/keystore"
 	"github.com/stafiprotocol/eth-lsd-relay/pkg/config"
+	"github.com/stafiprotocol/eth-lsd-relay/pkg/connection"
 	"github.com/stafiprotocol/eth-lsd-relay/pkg/log"
+	"github.com/stafiprotocol/eth-lsd-relay/pkg/notify"
 	"github.com/stafiprotocol/eth-lsd-relay/pkg/utils"
 	"github.com/stafiprotocol/eth-lsd-relay/service"
 )
@@ -30,7 +33,15 @@ func startRelayCmd() *cobra.Command {
 			if err != nil {
 				return err
 			}
-			fmt.Printf("keystore path: %s\n", cfg.KeystorePath)
+			if cfg.Signer.Type == config.SignerTypeKeystore {
+				fmt.Printf("keystore path: %s\n", cfg.KeystorePath)
+			}
+
+			dryRun, err := cmd.Flags().GetBool(flagDryRun)
+			if err != nil {
+				return err
+			}
+			cfg.DryRun = cfg.DryRun || dryRun
 
 			logLevelStr, err := cmd.Flags().GetString(flagLogLevel)
 			if err != nil {
@@ -42,48 +53,49 @@ func startRelayCmd() *cobra.Command {
 			}
 			logrus.SetLevel(logLevel)
 
-			// init constant variables
-			utils.StandardEffectiveBalance = cfg.Eth2EffectiveBalance * 1e9                                                        // unit Gwei
-			utils.StandardEffectiveBalanceDeci = decimal.NewFromInt(int64(utils.StandardEffectiveBalance)).Mul(utils.GweiDeci)     // unit wei
-			utils.MaxPartialWithdrawalAmount = cfg.MaxPartialWithdrawalAmount * 1e9                                                // unit Gwei
-			utils.MaxPartialWithdrawalAmountDeci = decimal.NewFromInt(int64(utils.MaxPartialWithdrawalAmount)).Mul(utils.GweiDeci) // unit wei
-
 			logrus.Infof(
 				`config info:
   logFilePath: %s
   logLevel: %s
   account: %s
+  signer: %s
   runForEntrustedLsdNetwork: %v
+  dryRun: %v
   lsdTokenAddress: %s
   factoryAddress: %s
   batchRequestBlocksNumber: %d
   eventFilterMaxSpanBlocks: %d
+  eventConfirmationBlocks: %d
   maxEjectedValPerCycle: %d
   maxGasPrice: %s Gwei
   gasPriceMultiplier: %.2f
+  quorum: %d/%d
   endpoints: %v`,
-				cfg.LogFilePath, logLevelStr, cfg.Account,
-				cfg.RunForEntrustedLsdNetwork, cfg.Contracts.LsdTokenAddress, cfg.Contracts.LsdFactoryAddress,
-				cfg.BatchRequestBlocksNumber, cfg.EventFilterMaxSpanBlocks, cfg.MaxEjectedValPerCycle, cfg.MaxGasPrice, cfg.GasPriceMultiplier, cfg.Endpoints)
+				cfg.LogFilePath, logLevelStr, cfg.Account, cfg.Signer.Type,
+				cfg.RunForEntrustedLsdNetwork, cfg.DryRun, cfg.Contracts.LsdTokenAddress, cfg.Contracts.LsdFactoryAddress,
+				cfg.BatchRequestBlocksNumber, cfg.EventFilterMaxSpanBlocks, cfg.EventConfirmationBlocks, cfg.MaxEjectedValPerCycle, cfg.MaxGasPrice, cfg.GasPriceMultiplier, cfg.Quorum.Threshold, cfg.Quorum.Size, cfg.Endpoints)
 
 			err = log.InitLogFile(cfg.LogFilePath + "/relay")
 			if err != nil {
 				return fmt.Errorf("InitLogFile failed: %w", err)
 			}
 
+			if notifier := notify.NewFromConfig(cfg.Notify); notifier != nil {
+				notifier.Start()
+				notify.SetDefault(notifier)
+				// deliver the alert of a requested shutdown before exit
+				defer notify.Close(10 * time.Second)
+			}
+
 			//interrupt signal
 			ctx := utils.ShutdownListener()
 
 			// load voter account
-			kpI, err := keystore.KeypairFromAddress(cfg.Account, keystore.EthChain, cfg.KeystorePath, false)
+			signer, err := newSigner(cfg)
 			if err != nil {
 				return err
 			}
-			kp, ok := kpI.(*secp256k1.Keypair)
-			if !ok {
-				return fmt.Errorf(" keypair err")
-			}
-			srvManager, err := service.NewServiceManager(cfg, kp)
+			srvManager, err := service.NewServiceManager(cfg, signer)
 			if err != nil {
 				return fmt.Errorf("NewServiceManager err: %w", err)
 			}
@@ -104,6 +116,28 @@ func startRelayCmd() *cobra.Command {
 
 	cmd.Flags().String(flagBasePath, defaultBasePath, "base path a directory where your config.toml resids")
 	cmd.Flags().String(flagLogLevel, logrus.InfoLevel.String(), "The logging level (trace|debug|info|warn|error|fatal|panic)")
+	cmd.Flags().Bool(flagDryRun, false, "compute proposals and record them to a report instead of sending txs")
 
 	return cmd
 }
+
+// newSigner builds the signer of the voter account selected by config
+func newSigner(cfg *config.Config) (connection.Signer, error) {
+	if !common.IsHexAddress(cfg.Account) {
+		return nil, fmt.Errorf("invalid account: %s", cfg.Account)
+	}
+	switch cfg.Signer.Type {
+	case config.SignerTypeRemote:
+		return connection.NewRemoteSigner(cfg.Signer.Endpoint, common.HexToAddress(cfg.Account))
+	default:
+		kpI, err := keystore.KeypairFromAddress(cfg.Account, keystore.EthChain, cfg.KeystorePath, false)
+		if err != nil {
+			return nil, err
+		}
+		kp, ok := kpI.(*secp256k1.Keypair)
+		if !ok {
+			return nil, fmt.Errorf(" keypair err")
+		}
+		return connection.NewKeystoreSigner(kp)
+	}
+}
@@ -0,0 +1,142 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/stafiprotocol/eth-lsd-relay/pkg/config"
+	"github.com/stafiprotocol/eth-lsd-relay/pkg/utils"
+	"github.com/stafiprotocol/eth-lsd-relay/service"
+)
+
+const flagRecompute = "recompute"
+
+func verifyMerkleRootCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "verify-merkle-root",
+		Args:  cobra.ExactArgs(0),
+		Short: "Audit a node rewards merkle root set on chain, no keystore needed and no tx sent",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			basePath, err := cmd.Flags().GetString(flagBasePath)
+			if err != nil {
+				return err
+			}
+			cfg, err := config.Load(basePath)
+			if err != nil {
+				return err
+			}
+
+			logLevelStr, err := cmd.Flags().GetString(flagLogLevel)
+			if err != nil {
+				return err
+			}
+			logLevel, err := logrus.ParseLevel(logLevelStr)
+			if err != nil {
+				return err
+			}
+			logrus.SetLevel(logLevel)
+
+			lsdToken, err := cmd.Flags().GetString(flagLsdToken)
+			if err != nil {
+				return err
+			}
+			if lsdToken == "" {
+				lsdToken = cfg.Contracts.LsdTokenAddress
+			}
+			if !common.IsHexAddress(lsdToken) {
+				return fmt.Errorf("invalid lsd token address: %s", lsdToken)
+			}
+			epoch, err := cmd.Flags().GetUint64(flagEpoch)
+			if err != nil {
+				return err
+			}
+			recompute, err := cmd.Flags().GetBool(flagRecompute)
+			if err != nil {
+				return err
+			}
+			dataPath, err := cmd.Flags().GetString(flagDataPath)
+			if err != nil {
+				return err
+			}
+			if dataPath != "" {
+				dataPath = strings.TrimSuffix(dataPath, "/")
+				cfg.BlockstoreFilePath = dataPath + "/blockstore"
+				cfg.StateStorePath = dataPath + "/state"
+				cfg.BeaconBlockStorePath = dataPath + "/beacon_blocks"
+			}
+			printJson, err := cmd.Flags().GetBool(flagJson)
+			if err != nil {
+				return err
+			}
+
+			// offline: no api server, no pinata unpinning
+			cfg.Api.ListenAddr = ""
+			cfg.Pinata.PinDays = 0
+
+			srvManager, err := service.NewServiceManager(cfg, nil)
+			if err != nil {
+				return fmt.Errorf("NewServiceManager err: %w (stop the relay or use --%s if the stores are locked)", err, flagDataPath)
+			}
+			defer srvManager.Stop()
+
+			audit, err := srvManager.VerifyMerkleRoot(utils.ShutdownListener(), common.HexToAddress(lsdToken).String(), epoch, recompute)
+			if err != nil {
+				return err
+			}
+
+			if printJson {
+				encoder := json.NewEncoder(os.Stdout)
+				encoder.SetIndent("", "  ")
+				if err := encoder.Encode(audit); err != nil {
+					return err
+				}
+			} else {
+				printMerkleRootAudit(audit)
+			}
+			if len(audit.Issues) > 0 {
+				return fmt.Errorf("merkle root of epoch %d failed the audit with %d issues", audit.Epoch, len(audit.Issues))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().String(flagBasePath, defaultBasePath, "base path a directory where your config.toml resids")
+	cmd.Flags().String(flagLogLevel, logrus.WarnLevel.String(), "The logging level (trace|debug|info|warn|error|fatal|panic)")
+	cmd.Flags().String(flagLsdToken, "", "lsd token address, default to the lsdTokenAddress of config")
+	cmd.Flags().Uint64(flagEpoch, 0, "epoch of the merkle root, default to the latest one")
+	cmd.Flags().Bool(flagRecompute, false, "recompute node rewards since the previous merkle root from chain data, slow")
+	cmd.Flags().String(flagDataPath, "", "directory of the local stores, default to base path, use another one while the relay is running")
+	cmd.Flags().Bool(flagJson, false, "print the audit in json")
+
+	return cmd
+}
+
+func printMerkleRootAudit(a *service.MerkleRootAudit) {
+	fmt.Printf("lsd token:               %s\n", a.LsdToken)
+	fmt.Printf("epoch:                   %d (latest: %v)\n", a.Epoch, a.IsLatest)
+	fmt.Printf("merkle root:             %s\n", a.MerkleRoot)
+	fmt.Printf("node rewards file cid:   %s\n", a.NodeRewardsFileCid)
+	fmt.Printf("root of rewards file:    %s\n", a.FileMerkleRoot)
+	fmt.Printf("nodes:                   %d\n", a.Nodes)
+	if a.PreviousFileCid != "" {
+		fmt.Printf("previous epoch:          %d (cid %s)\n", a.PreviousEpoch, a.PreviousFileCid)
+	} else {
+		fmt.Println("previous epoch:          none, first merkle root")
+	}
+	if a.Recomputed {
+		fmt.Printf("recomputed root:         %s\n", a.RecomputedRoot)
+	}
+	if len(a.Issues) == 0 {
+		fmt.Println("\nno issue found")
+		return
+	}
+	fmt.Printf("\n%d issues:\n", len(a.Issues))
+	for _, issue := range a.Issues {
+		fmt.Printf("  - %s\n", issue)
+	}
+}
@@ -5,6 +5,7 @@ package config
 
 import (
 	"fmt"
+	"net/url"
 	"os"
 	"strings"
 
@@ -12,8 +13,26 @@ import (
 )
 
 type Endpoint struct {
-	Eth1 string
-	Eth2 string
+	Eth1          string
+	Eth2          string
+	Eth1RateLimit float64 // requests per second, unlimited if zero
+	Eth2RateLimit float64 // requests per second, unlimited if zero
+	Eth1Options   EndpointOptions
+	Eth2Options   EndpointOptions
+}
+
+// EndpointOptions configs how requests are sent to an endpoint, e.g. for providers requiring an api key header,
+// a jwt bearer token, basic auth or tls client certificates
+type EndpointOptions struct {
+	Headers           map[string]string
+	JwtSecretFile     string // hex encoded 32 bytes secret, tokens are signed with HS256 and refreshed periodically
+	BasicAuthUser     string
+	BasicAuthPassword string
+	CaFile            string // pem certificates trusted besides the system ones
+	CertFile          string // pem client certificate, KeyFile must be set too
+	KeyFile           string
+	Proxy             string // e.g. http://127.0.0.1:3128 or socks5://127.0.0.1:1080, default to the proxy of environment variables
+	TimeoutSeconds    uint64 // per request, no limit other than the defaults of the client if zero
 }
 
 type Config struct {
@@ -21,17 +40,23 @@ type Config struct {
 	Account                    string
 	KeystorePath               string
 	BlockstoreFilePath         string
+	StateStorePath             string
+	BeaconBlockStorePath       string
+	PendingTxsFilePath         string
 	GasLimit                   string
 	MaxGasPrice                string // Gwei
 	GasPriceMultiplier         float64
 	BatchRequestBlocksNumber   uint64
 	EventFilterMaxSpanBlocks   uint64
+	EventConfirmationBlocks    uint64 // events are synced this many blocks behind the latest block, 0 follows the finalized execution block
 	MaxEjectedValPerCycle      int
 	TrustNodeDepositAmount     uint64 // ether
-	Eth2EffectiveBalance       uint64 // ether
-	MaxPartialWithdrawalAmount uint64 // ether
+	Eth2EffectiveBalance       uint64 // ether, overrides the chain profile if not zero
+	MaxPartialWithdrawalAmount uint64 // ether, overrides the chain profile if not zero
 
 	RunForEntrustedLsdNetwork bool
+	DryRun                    bool // compute proposals and record them to a report in LogFilePath instead of sending txs
+	UploadBalancesReport      bool // upload the per-round balances report to the decentralized storage
 	TransferFeeAddresses      []string
 
 	BatchQueryBalanceBlockNumbers      uint64
@@ -39,8 +64,70 @@ type Config struct {
 
 	Contracts   Contracts
 	Endpoints   []Endpoint
+	Quorum      Quorum
+	Signer      Signer
+	TxManager   TxManager
+	Storage     Storage
 	Web3Storage Web3Storage
 	Pinata      Pinata
+	NftStorage  NftStorage
+	Kubo        Kubo
+	Api         Api
+	Notify      Notify
+
+	RelayerBalance RelayerBalance
+	ChainProfiles  []ChainProfile
+	EndpointPool   EndpointPool
+}
+
+// EndpointPool configs the circuit breaker of the endpoints: an endpoint is skipped after FailureThreshold
+// consecutive failed requests, and probed again after OpenSeconds
+type EndpointPool struct {
+	FailureThreshold int    // default 5
+	OpenSeconds      uint64 // default 30
+}
+
+// Quorum enables the verify mode of critical reads, e.g. validator balances of a balances vote:
+// Size endpoints are queried and Threshold of them must agree, no vote is sent otherwise.
+// It is disabled if Size is less than 2
+type Quorum struct {
+	Size      int
+	Threshold int // default a majority of Size
+}
+
+const (
+	SignerTypeKeystore = "keystore"
+	SignerTypeRemote   = "remote"
+)
+
+// Signer selects how txs of Account are signed, default to the local keystore.
+// A remote signer is a json-rpc service supporting eth_accounts and eth_signTransaction, e.g. Web3Signer or Clef
+type Signer struct {
+	Type     string
+	Endpoint string // remote signer only
+}
+
+// TxManager configs the replacement of relay txs not mined in time
+type TxManager struct {
+	BumpAfterSeconds uint64 // default 180
+	BumpPercent      uint64 // raise of both fee caps on each replacement, default 15, at least 10
+}
+
+const (
+	StorageBackendPinata      = "pinata"
+	StorageBackendWeb3Storage = "web3storage"
+	StorageBackendNftStorage  = "nftstorage"
+	StorageBackendKubo        = "kubo"
+	StorageBackendReplicated  = "replicated"
+)
+
+// Storage selects the decentralized storage backend of node reward files, default to pinata.
+// The replicated backend uploads to every backend of Replicas and keeps a local archive copy,
+// an upload fails unless MinReplicas backends stored the file, default to all of them
+type Storage struct {
+	Backend     string
+	Replicas    []string
+	MinReplicas int
 }
 
 type Web3Storage struct {
@@ -55,6 +142,51 @@ type Pinata struct {
 	PinDays  uint
 }
 
+type NftStorage struct {
+	Apikey string
+}
+
+// Kubo configs a self-hosted ipfs node, files pinned by the relay are unpinned after PinDays if not zero
+type Kubo struct {
+	Endpoint string // rpc api, default to http://127.0.0.1:5001
+	PinDays  uint
+}
+
+// Api configs the read-only http api server, it is disabled if ListenAddr is empty
+type Api struct {
+	ListenAddr  string // e.g. "127.0.0.1:8585"
+	MerkleProof bool   // serve merkle proofs of node rewards, each query is checked against the chain
+}
+
+// Notify configs the alert sinks, alerting is disabled if none is set
+type Notify struct {
+	Webhook          string // receives each alert as json
+	SlackWebhook     string
+	DiscordWebhook   string
+	TelegramBotToken string
+	TelegramChatId   string
+	DedupSeconds     uint64 // an alert is sent once per window while it keeps firing, default 1800
+	MaxPerMinute     int    // alerts sent per minute over all sinks, default 10
+}
+
+// RelayerBalance configs the thresholds of the voter account balance monitor, in ether
+type RelayerBalance struct {
+	Warning  string // default 1
+	Critical string // default 0.2
+}
+
+// ChainProfile adds a network or replaces the built-in profile of the same chain id, e.g. for a devnet
+type ChainProfile struct {
+	Name                       string
+	ChainId                    uint64
+	GenesisForkVersion         string // hex, e.g. "0x00000943"
+	DomainDeposit              string // hex, default "0x03000000"
+	SecondsPerSlot             uint64 // checked against the beacon endpoint if not zero
+	SlotsPerEpoch              uint64 // checked against the beacon endpoint if not zero
+	EffectiveBalance           uint64 // ether, default 32
+	MaxPartialWithdrawalAmount uint64 // ether, default 8
+}
+
 type Contracts struct {
 	LsdTokenAddress   string
 	LsdFactoryAddress string
@@ -72,17 +204,14 @@ func Load(basePath string) (*Config, error) {
 	cfg.LogFilePath = basePath + "/log_data"
 	cfg.KeystorePath = KeyStoreFilePath(basePath)
 	cfg.BlockstoreFilePath = basePath + "/blockstore"
+	cfg.StateStorePath = basePath + "/state"
+	cfg.BeaconBlockStorePath = basePath + "/beacon_blocks"
+	cfg.PendingTxsFilePath = basePath + "/pending_txs.json"
 
 	// add default values
 	if cfg.TrustNodeDepositAmount == 0 {
 		cfg.TrustNodeDepositAmount = 1
 	}
-	if cfg.Eth2EffectiveBalance == 0 {
-		cfg.Eth2EffectiveBalance = 32
-	}
-	if cfg.MaxPartialWithdrawalAmount == 0 {
-		cfg.MaxPartialWithdrawalAmount = 8
-	}
 	if cfg.GasLimit == "" {
 		cfg.GasLimit = "3000000"
 	}
@@ -95,6 +224,55 @@ func Load(basePath string) (*Config, error) {
 	if cfg.EventFilterMaxSpanBlocks == 0 {
 		cfg.EventFilterMaxSpanBlocks = 3000
 	}
+	if cfg.Storage.Backend == "" {
+		cfg.Storage.Backend = StorageBackendPinata
+	}
+	if cfg.TxManager.BumpAfterSeconds == 0 {
+		cfg.TxManager.BumpAfterSeconds = 180
+	}
+	if cfg.TxManager.BumpPercent == 0 {
+		cfg.TxManager.BumpPercent = 15
+	}
+	if cfg.Notify.DedupSeconds == 0 {
+		cfg.Notify.DedupSeconds = 1800
+	}
+	if cfg.Notify.MaxPerMinute == 0 {
+		cfg.Notify.MaxPerMinute = 10
+	}
+	if cfg.RelayerBalance.Warning == "" {
+		cfg.RelayerBalance.Warning = "1"
+	}
+	if cfg.RelayerBalance.Critical == "" {
+		cfg.RelayerBalance.Critical = "0.2"
+	}
+	for i := range cfg.ChainProfiles {
+		p := &cfg.ChainProfiles[i]
+		if p.DomainDeposit == "" {
+			p.DomainDeposit = "0x03000000"
+		}
+		if p.EffectiveBalance == 0 {
+			p.EffectiveBalance = 32
+		}
+		if p.MaxPartialWithdrawalAmount == 0 {
+			p.MaxPartialWithdrawalAmount = 8
+		}
+		if p.Name == "" {
+			p.Name = fmt.Sprintf("chain-%d", p.ChainId)
+		}
+	}
+	if cfg.EndpointPool.FailureThreshold == 0 {
+		cfg.EndpointPool.FailureThreshold = 5
+	}
+	if cfg.EndpointPool.OpenSeconds == 0 {
+		cfg.EndpointPool.OpenSeconds = 30
+	}
+	if cfg.Quorum.Size > 1 && cfg.Quorum.Threshold == 0 {
+		cfg.Quorum.Threshold = cfg.Quorum.Size/2 + 1
+	}
+	cfg.Signer.Type = strings.ToLower(cfg.Signer.Type)
+	if cfg.Signer.Type == "" {
+		cfg.Signer.Type = SignerTypeKeystore
+	}
 
 	// handle invalid parameters
 	if cfg.GasPriceMultiplier < 1 {
@@ -106,6 +284,60 @@ func Load(basePath string) (*Config, error) {
 	if cfg.BatchRequestBlocksNumber > 32 {
 		return nil, fmt.Errorf("batchRequestBlocksNumber can not be greater than 32")
 	}
+	if cfg.TxManager.BumpPercent < 10 {
+		return nil, fmt.Errorf("txManager bumpPercent can not be less than 10")
+	}
+	if cfg.EndpointPool.FailureThreshold < 0 {
+		return nil, fmt.Errorf("endpointPool failureThreshold can not be negative")
+	}
+	for _, e := range cfg.Endpoints {
+		if e.Eth1RateLimit < 0 || e.Eth2RateLimit < 0 {
+			return nil, fmt.Errorf("rate limit of endpoint can not be negative")
+		}
+		if err := e.Eth1Options.validate("eth1Options"); err != nil {
+			return nil, err
+		}
+		if err := e.Eth2Options.validate("eth2Options"); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.Quorum.Size > len(cfg.Endpoints) {
+		return nil, fmt.Errorf("quorum size %d can not be greater than the number of endpoints %d", cfg.Quorum.Size, len(cfg.Endpoints))
+	}
+	if cfg.Quorum.Size > 1 && (cfg.Quorum.Threshold <= cfg.Quorum.Size/2 || cfg.Quorum.Threshold > cfg.Quorum.Size) {
+		return nil, fmt.Errorf("quorum threshold %d must be a majority of size %d", cfg.Quorum.Threshold, cfg.Quorum.Size)
+	}
+	switch cfg.Signer.Type {
+	case SignerTypeKeystore:
+	case SignerTypeRemote:
+		if cfg.Signer.Endpoint == "" {
+			return nil, fmt.Errorf("signer endpoint must be set for remote signer")
+		}
+	default:
+		return nil, fmt.Errorf("unsupported signer type: %s", cfg.Signer.Type)
+	}
+	if (cfg.Notify.TelegramBotToken == "") != (cfg.Notify.TelegramChatId == "") {
+		return nil, fmt.Errorf("notify telegramBotToken and telegramChatId must be set together")
+	}
+	chainIds := make(map[uint64]bool)
+	for _, p := range cfg.ChainProfiles {
+		if p.ChainId == 0 {
+			return nil, fmt.Errorf("chainProfile %s chainId must be set", p.Name)
+		}
+		if chainIds[p.ChainId] {
+			return nil, fmt.Errorf("duplicate chainProfile of chainId %d", p.ChainId)
+		}
+		chainIds[p.ChainId] = true
+		if p.GenesisForkVersion == "" {
+			return nil, fmt.Errorf("chainProfile %s genesisForkVersion must be set", p.Name)
+		}
+		if p.MaxPartialWithdrawalAmount >= p.EffectiveBalance {
+			return nil, fmt.Errorf("chainProfile %s maxPartialWithdrawalAmount must be less than effectiveBalance", p.Name)
+		}
+	}
+	if err := cfg.Storage.validate(&cfg); err != nil {
+		return nil, err
+	}
 	if cfg.BatchQueryBalanceBlockNumbers == 0 {
 		cfg.BatchQueryBalanceBlockNumbers = 1000
 	}
@@ -117,6 +349,70 @@ func Load(basePath string) (*Config, error) {
 	return &cfg, nil
 }
 
+func (o EndpointOptions) validate(name string) error {
+	if (o.CertFile == "") != (o.KeyFile == "") {
+		return fmt.Errorf("endpoint %s certFile and keyFile must be set together", name)
+	}
+	if o.JwtSecretFile != "" && (o.BasicAuthUser != "" || o.BasicAuthPassword != "") {
+		return fmt.Errorf("endpoint %s can not use both jwtSecretFile and basic auth", name)
+	}
+	if o.Proxy != "" {
+		u, err := url.Parse(o.Proxy)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("endpoint %s proxy must be a url with scheme and host", name)
+		}
+	}
+	return nil
+}
+
+func (s Storage) validate(cfg *Config) error {
+	if strings.ToLower(s.Backend) != StorageBackendReplicated {
+		return validateStorageBackend(s.Backend, cfg)
+	}
+	if len(s.Replicas) == 0 {
+		return fmt.Errorf("replicas must be set for storage backend %s", s.Backend)
+	}
+	seen := make(map[string]bool)
+	for _, replica := range s.Replicas {
+		name := strings.ToLower(replica)
+		if name == StorageBackendReplicated {
+			return fmt.Errorf("storage backend %s can not be a replica", replica)
+		}
+		if seen[name] {
+			return fmt.Errorf("duplicate replica: %s", replica)
+		}
+		seen[name] = true
+		if err := validateStorageBackend(replica, cfg); err != nil {
+			return err
+		}
+	}
+	if s.MinReplicas < 0 || s.MinReplicas > len(s.Replicas) {
+		return fmt.Errorf("minReplicas must be between 0 and %d", len(s.Replicas))
+	}
+	return nil
+}
+
+func validateStorageBackend(backend string, cfg *Config) error {
+	switch strings.ToLower(backend) {
+	case StorageBackendPinata:
+		if cfg.Pinata.Apikey == "" {
+			return fmt.Errorf("pinata apikey must be set for storage backend %s", backend)
+		}
+	case StorageBackendWeb3Storage:
+		if cfg.Web3Storage.PrivateKey == "" || cfg.Web3Storage.SpaceDid == "" || cfg.Web3Storage.ProofFile == "" {
+			return fmt.Errorf("web3Storage privateKey, spaceDid and proofFile must be set for storage backend %s", backend)
+		}
+	case StorageBackendNftStorage:
+		if cfg.NftStorage.Apikey == "" {
+			return fmt.Errorf("nftStorage apikey must be set for storage backend %s", backend)
+		}
+	case StorageBackendKubo:
+	default:
+		return fmt.Errorf("unsupported storage backend: %s", backend)
+	}
+	return nil
+}
+
 func KeyStoreFilePath(basePath string) string {
 	basePath = strings.TrimSuffix(basePath, "/")
 	return basePath + "/keystore"
@@ -0,0 +1,135 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/prysmaticlabs/prysm/v4/consensus-types/blocks"
+	"github.com/prysmaticlabs/prysm/v4/consensus-types/interfaces"
+	ethpb "github.com/prysmaticlabs/prysm/v4/proto/prysm/v1alpha1"
+	"github.com/stafiprotocol/eth-lsd-relay/pkg/connection/beacon"
+)
+
+const (
+	RequestContentTypeSsz  = "application/octet-stream"
+	ConsensusVersionHeader = "Eth-Consensus-Version"
+)
+
+// errUnsupportedSszVersion is returned for blocks of forks the ssz decoder does not know, they are requested in json instead
+var errUnsupportedSszVersion = errors.New("unsupported ssz block version")
+
+// decodeSszBlock decodes a signed beacon block of the fork version named by the Eth-Consensus-Version header
+func decodeSszBlock(version string, data []byte) (interfaces.ReadOnlySignedBeaconBlock, error) {
+	var block interface {
+		UnmarshalSSZ([]byte) error
+	}
+	switch version {
+	case "phase0":
+		block = &ethpb.SignedBeaconBlock{}
+	case "altair":
+		block = &ethpb.SignedBeaconBlockAltair{}
+	case "bellatrix":
+		block = &ethpb.SignedBeaconBlockBellatrix{}
+	case "capella":
+		block = &ethpb.SignedBeaconBlockCapella{}
+	case "deneb":
+		block = &ethpb.SignedBeaconBlockDeneb{}
+	default:
+		return nil, fmt.Errorf("%w: %s", errUnsupportedSszVersion, version)
+	}
+	if err := block.UnmarshalSSZ(data); err != nil {
+		return nil, fmt.Errorf("could not decode %s beacon block: %w", version, err)
+	}
+	return blocks.NewSignedBeaconBlock(block)
+}
+
+// beaconBlockFromSsz converts a decoded block to the same BeaconBlock as decoded from json
+func beaconBlockFromSsz(signedBlock interfaces.ReadOnlySignedBeaconBlock) beacon.BeaconBlock {
+	block := signedBlock.Block()
+	body := block.Body()
+	beaconBlock := beacon.BeaconBlock{
+		Slot:          uint64(block.Slot()),
+		ProposerIndex: uint64(block.ProposerIndex()),
+	}
+
+	for _, attestation := range body.Attestations() {
+		beaconBlock.Attestations = append(beaconBlock.Attestations, beacon.AttestationInfo{
+			AggregationBits: attestation.AggregationBits,
+			SlotIndex:       uint64(attestation.Data.Slot),
+			CommitteeIndex:  uint64(attestation.Data.CommitteeIndex),
+		})
+	}
+
+	// not in phase0 blocks
+	if syncAggregate, err := body.SyncAggregate(); err == nil && len(syncAggregate.SyncCommitteeBits) > 0 {
+		beaconBlock.SyncAggregate = beacon.SyncAggregate{
+			SyncCommitteeBits:      []byte(syncAggregate.SyncCommitteeBits),
+			SyncCommitteeSignature: hexutil.Encode(syncAggregate.SyncCommitteeSignature),
+		}
+	}
+
+	for _, proposerSlash := range body.ProposerSlashings() {
+		beaconBlock.ProposerSlashings = append(beaconBlock.ProposerSlashings, beacon.ProposerSlashing{
+			SignedHeader1: signedHeaderFromSsz(proposerSlash.Header_1),
+			SignedHeader2: signedHeaderFromSsz(proposerSlash.Header_2),
+		})
+	}
+
+	for _, attesterSlash := range body.AttesterSlashings() {
+		beaconBlock.AttesterSlashing = append(beaconBlock.AttesterSlashing, beacon.AttesterSlashing{
+			Attestation1: attestationFromSsz(attesterSlash.Attestation_1),
+			Attestation2: attestationFromSsz(attesterSlash.Attestation_2),
+		})
+	}
+
+	// execution payload only exists after the merge, withdrawals after capella
+	if execution, err := body.Execution(); err == nil && execution != nil && !execution.IsNil() {
+		if withdrawals, err := execution.Withdrawals(); err == nil {
+			for _, withdrawal := range withdrawals {
+				beaconBlock.Withdrawals = append(beaconBlock.Withdrawals, beacon.Withdrawal{
+					WithdrawIndex:  withdrawal.Index,
+					ValidatorIndex: uint64(withdrawal.ValidatorIndex),
+					Address:        common.BytesToAddress(withdrawal.Address),
+					Amount:         withdrawal.Amount,
+				})
+			}
+		}
+		beaconBlock.ExecutionBlockNumber = execution.BlockNumber()
+	}
+
+	for _, exitMsg := range body.VoluntaryExits() {
+		beaconBlock.VoluntaryExits = append(beaconBlock.VoluntaryExits, beacon.VoluntaryExit{
+			ValidatorIndex: uint64(exitMsg.Exit.ValidatorIndex),
+			Epoch:          uint64(exitMsg.Exit.Epoch),
+		})
+	}
+
+	return beaconBlock
+}
+
+func signedHeaderFromSsz(header *ethpb.SignedBeaconBlockHeader) beacon.SignedHeader {
+	return beacon.SignedHeader{
+		Slot:          uint64(header.Header.Slot),
+		ProposerIndex: uint64(header.Header.ProposerIndex),
+		ParentRoot:    hexutil.Encode(header.Header.ParentRoot),
+		StateRoot:     hexutil.Encode(header.Header.StateRoot),
+		BodyRoot:      hexutil.Encode(header.Header.BodyRoot),
+		Signature:     hexutil.Encode(header.Signature),
+	}
+}
+
+func attestationFromSsz(attestation *ethpb.IndexedAttestation) beacon.Attestation {
+	return beacon.Attestation{
+		AttestingIndices: attestation.AttestingIndices,
+		Signature:        hexutil.Encode(attestation.Signature),
+		Slot:             uint64(attestation.Data.Slot),
+		Index:            uint64(attestation.Data.CommitteeIndex),
+		BeaconBlockRoot:  hexutil.Encode(attestation.Data.BeaconBlockRoot),
+		SourceEpoch:      uint64(attestation.Data.Source.Epoch),
+		SourceRoot:       hexutil.Encode(attestation.Data.Source.Root),
+		TargetEpoch:      uint64(attestation.Data.Target.Epoch),
+		TargetRoot:       hexutil.Encode(attestation.Data.Target.Root),
+	}
+}
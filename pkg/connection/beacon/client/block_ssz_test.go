@@ -0,0 +1,118 @@
+package client
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	enginev1 "github.com/prysmaticlabs/prysm/v4/proto/engine/v1"
+	ethpb "github.com/prysmaticlabs/prysm/v4/proto/prysm/v1alpha1"
+	"github.com/stafiprotocol/eth-lsd-relay/pkg/connection/beacon"
+	"github.com/stretchr/testify/assert"
+)
+
+func testCapellaBlock() *ethpb.SignedBeaconBlockCapella {
+	return &ethpb.SignedBeaconBlockCapella{
+		Block: &ethpb.BeaconBlockCapella{
+			Slot:          100,
+			ProposerIndex: 7,
+			ParentRoot:    make([]byte, 32),
+			StateRoot:     make([]byte, 32),
+			Body: &ethpb.BeaconBlockBodyCapella{
+				RandaoReveal: make([]byte, 96),
+				Eth1Data: &ethpb.Eth1Data{
+					DepositRoot: make([]byte, 32),
+					BlockHash:   make([]byte, 32),
+				},
+				Graffiti: make([]byte, 32),
+				SyncAggregate: &ethpb.SyncAggregate{
+					SyncCommitteeBits:      bytes.Repeat([]byte{0xff}, 64),
+					SyncCommitteeSignature: make([]byte, 96),
+				},
+				VoluntaryExits: []*ethpb.SignedVoluntaryExit{
+					{Exit: &ethpb.VoluntaryExit{Epoch: 3, ValidatorIndex: 9}, Signature: make([]byte, 96)},
+				},
+				ExecutionPayload: &enginev1.ExecutionPayloadCapella{
+					ParentHash:    make([]byte, 32),
+					FeeRecipient:  make([]byte, 20),
+					StateRoot:     make([]byte, 32),
+					ReceiptsRoot:  make([]byte, 32),
+					LogsBloom:     make([]byte, 256),
+					PrevRandao:    make([]byte, 32),
+					BlockNumber:   2000,
+					BaseFeePerGas: make([]byte, 32),
+					BlockHash:     make([]byte, 32),
+					Withdrawals: []*enginev1.Withdrawal{
+						{Index: 11, ValidatorIndex: 12, Address: common.HexToAddress("0x61135C59A4Eb452b89963188eD6B6a7487049764").Bytes(), Amount: 13},
+					},
+				},
+			},
+		},
+		Signature: make([]byte, 96),
+	}
+}
+
+func TestGetBeaconBlockSsz(t *testing.T) {
+	encoded, err := testCapellaBlock().MarshalSSZ()
+	assert.Nil(t, err)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "application/octet-stream", r.Header.Get("Accept"))
+		if r.URL.Path != "/eth/v2/beacon/blocks/100" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("Eth-Consensus-Version", "capella")
+		w.Write(encoded)
+	}))
+	defer server.Close()
+
+	c := &StandardHttpClient{providerAddress: server.URL}
+	block, exists, err := c.GetBeaconBlock(100)
+	assert.Nil(t, err)
+	assert.True(t, exists)
+	assert.Equal(t, uint64(100), block.Slot)
+	assert.Equal(t, uint64(7), block.ProposerIndex)
+	assert.Equal(t, uint64(2000), block.ExecutionBlockNumber)
+	assert.Equal(t, []beacon.Withdrawal{{WithdrawIndex: 11, ValidatorIndex: 12, Address: common.HexToAddress("0x61135C59A4Eb452b89963188eD6B6a7487049764"), Amount: 13}}, block.Withdrawals)
+	assert.Equal(t, []beacon.VoluntaryExit{{ValidatorIndex: 9, Epoch: 3}}, block.VoluntaryExits)
+	assert.Len(t, block.SyncAggregate.SyncCommitteeBits, 64)
+
+	_, exists, err = c.GetBeaconBlock(101)
+	assert.Nil(t, err)
+	assert.False(t, exists)
+	assert.False(t, c.sszUnsupported.Load())
+}
+
+func TestGetBeaconBlockJsonFallback(t *testing.T) {
+	sszRequests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Accept") == "application/octet-stream" {
+			sszRequests++
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"version":"capella","data":{"message":{"slot":"100","proposer_index":"7","body":{"execution_payload":{"block_number":"2000","withdrawals":[{"index":"11","validator_index":"12","address":"0x61135c59a4eb452b89963188ed6b6a7487049764","amount":"13"}]}}}}}`)
+	}))
+	defer server.Close()
+
+	c := &StandardHttpClient{providerAddress: server.URL}
+	for i := 0; i < 2; i++ {
+		block, exists, err := c.GetBeaconBlock(100)
+		assert.Nil(t, err)
+		assert.True(t, exists)
+		assert.Equal(t, uint64(2000), block.ExecutionBlockNumber)
+		assert.Equal(t, uint64(12), block.Withdrawals[0].ValidatorIndex)
+	}
+	// ssz is not requested again once the node answered json
+	assert.Equal(t, 1, sszRequests)
+	assert.True(t, c.sszUnsupported.Load())
+}
+
+func TestDecodeSszBlockUnsupportedVersion(t *testing.T) {
+	_, err := decodeSszBlock("electra", []byte{})
+	assert.ErrorIs(t, err, errUnsupportedSszVersion)
+}
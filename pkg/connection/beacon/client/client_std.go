@@ -5,12 +5,14 @@ import (
 	"context"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"math/big"
 	"net/http"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -18,8 +20,10 @@ import (
 	"golang.org/x/sync/errgroup"
 
 	gtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/prysmaticlabs/prysm/v4/consensus-types/interfaces"
 	ethpb "github.com/prysmaticlabs/prysm/v4/proto/eth/v1"
 	"github.com/stafiprotocol/eth-lsd-relay/pkg/connection/beacon"
+	"github.com/stafiprotocol/eth-lsd-relay/pkg/metrics"
 	"github.com/stafiprotocol/eth-lsd-relay/pkg/utils"
 )
 
@@ -38,20 +42,33 @@ const (
 	RequestBeaconBlockPath           = "/eth/v2/beacon/blocks/%d"
 
 	MaxRequestValidatorsCount = 50
+	// ids are sent in the body of POST requests, so much larger batches are fine
+	MaxPostValidatorsCount = 1000
 )
 
 // Beacon client using the standard Beacon HTTP REST API (https://ethereum.github.io/beacon-APIs/)
 type StandardHttpClient struct {
 	providerAddress string
+	httpClient      *http.Client
 	eth2Config      beacon.Eth2Config
 	signer          gtypes.Signer
+
+	// set once the node is found to not support them, requests fall back to json and GET
+	sszUnsupported            atomic.Bool
+	postValidatorsUnsupported atomic.Bool
 }
 
 // Create a new client instance
 func NewStandardHttpClient(providerAddress string, chainID *big.Int) (*StandardHttpClient, error) {
+	return NewStandardHttpClientWithHttpClient(providerAddress, chainID, http.DefaultClient)
+}
+
+// Create a new client instance sending requests with httpClient, e.g. to go through the transport of an endpoint pool
+func NewStandardHttpClientWithHttpClient(providerAddress string, chainID *big.Int, httpClient *http.Client) (*StandardHttpClient, error) {
 
 	client := &StandardHttpClient{
 		providerAddress: providerAddress,
+		httpClient:      httpClient,
 	}
 	config, err := client.GetEth2Config()
 	if err != nil {
@@ -190,23 +207,9 @@ func (c *StandardHttpClient) getValidatorStatus(ctx context.Context, pubkeyOrInd
 	if len(validators.Data) == 0 {
 		return beacon.ValidatorStatus{}, nil
 	}
-	validator := validators.Data[0]
 
 	// Return response
-	return beacon.ValidatorStatus{
-		Pubkey:                     types.BytesToValidatorPubkey(validator.Validator.Pubkey),
-		Index:                      uint64(validator.Index),
-		WithdrawalCredentials:      common.BytesToHash(validator.Validator.WithdrawalCredentials),
-		Balance:                    uint64(validator.Balance),
-		EffectiveBalance:           uint64(validator.Validator.EffectiveBalance),
-		Slashed:                    validator.Validator.Slashed,
-		ActivationEligibilityEpoch: uint64(validator.Validator.ActivationEligibilityEpoch),
-		ActivationEpoch:            uint64(validator.Validator.ActivationEpoch),
-		ExitEpoch:                  uint64(validator.Validator.ExitEpoch),
-		WithdrawableEpoch:          uint64(validator.Validator.WithdrawableEpoch),
-		Exists:                     true,
-		Status:                     ethpb.ValidatorStatus(ethpb.ValidatorStatus_value[strings.ToUpper(validator.Status)]),
-	}, nil
+	return validatorStatusOf(validators.Data[0]), nil
 
 }
 
@@ -245,20 +248,7 @@ func (c *StandardHttpClient) GetValidatorStatuses(ctx context.Context, pubkeys [
 		pubkey := types.BytesToValidatorPubkey(validator.Validator.Pubkey)
 
 		// Add status
-		statuses[pubkey] = beacon.ValidatorStatus{
-			Pubkey:                     types.BytesToValidatorPubkey(validator.Validator.Pubkey),
-			Index:                      uint64(validator.Index),
-			WithdrawalCredentials:      common.BytesToHash(validator.Validator.WithdrawalCredentials),
-			Balance:                    uint64(validator.Balance),
-			EffectiveBalance:           uint64(validator.Validator.EffectiveBalance),
-			Slashed:                    validator.Validator.Slashed,
-			ActivationEligibilityEpoch: uint64(validator.Validator.ActivationEligibilityEpoch),
-			ActivationEpoch:            uint64(validator.Validator.ActivationEpoch),
-			ExitEpoch:                  uint64(validator.Validator.ExitEpoch),
-			WithdrawableEpoch:          uint64(validator.Validator.WithdrawableEpoch),
-			Exists:                     true,
-			Status:                     ethpb.ValidatorStatus(ethpb.ValidatorStatus_value[strings.ToUpper(validator.Status)]),
-		}
+		statuses[pubkey] = validatorStatusOf(validator)
 
 	}
 
@@ -272,6 +262,42 @@ func (c *StandardHttpClient) GetValidatorStatuses(ctx context.Context, pubkeys [
 
 }
 
+// Get multiple validators' statuses by index, validators not found are not in the result
+func (c *StandardHttpClient) GetValidatorStatusesByIndices(ctx context.Context, indices []uint64, opts *beacon.ValidatorStatusOptions) (map[uint64]beacon.ValidatorStatus, error) {
+	ids := make([]string, len(indices))
+	for i, index := range indices {
+		ids[i] = strconv.FormatUint(index, 10)
+	}
+
+	validators, err := c.getValidatorsByOpts(ctx, ids, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make(map[uint64]beacon.ValidatorStatus, len(validators.Data))
+	for _, validator := range validators.Data {
+		statuses[uint64(validator.Index)] = validatorStatusOf(validator)
+	}
+	return statuses, nil
+}
+
+func validatorStatusOf(validator Validator) beacon.ValidatorStatus {
+	return beacon.ValidatorStatus{
+		Pubkey:                     types.BytesToValidatorPubkey(validator.Validator.Pubkey),
+		Index:                      uint64(validator.Index),
+		WithdrawalCredentials:      common.BytesToHash(validator.Validator.WithdrawalCredentials),
+		Balance:                    uint64(validator.Balance),
+		EffectiveBalance:           uint64(validator.Validator.EffectiveBalance),
+		Slashed:                    validator.Validator.Slashed,
+		ActivationEligibilityEpoch: uint64(validator.Validator.ActivationEligibilityEpoch),
+		ActivationEpoch:            uint64(validator.Validator.ActivationEpoch),
+		ExitEpoch:                  uint64(validator.Validator.ExitEpoch),
+		WithdrawableEpoch:          uint64(validator.Validator.WithdrawableEpoch),
+		Exists:                     true,
+		Status:                     ethpb.ValidatorStatus(ethpb.ValidatorStatus_value[strings.ToUpper(validator.Status)]),
+	}
+}
+
 // Perform a voluntary exit on a validator
 func (c *StandardHttpClient) ExitValidator(validatorIndex, epoch uint64, signature types.ValidatorSignature) error {
 	return c.postVoluntaryExit(VoluntaryExitRequest{
@@ -305,6 +331,20 @@ func (c *StandardHttpClient) GetEth1DataForEth2Block(blockId uint64) (beacon.Eth
 }
 
 func (c *StandardHttpClient) GetBeaconBlock(blockId uint64) (beacon.BeaconBlock, bool, error) {
+	if !c.sszUnsupported.Load() {
+		signedBlock, exists, err := c.getBeaconBlockSsz(blockId)
+		switch {
+		case err != nil:
+			return beacon.BeaconBlock{}, false, err
+		case !exists:
+			return beacon.BeaconBlock{}, false, nil
+		case signedBlock != nil:
+			return beaconBlockFromSsz(signedBlock), true, nil
+		}
+		// the node answered json or the fork is unknown to the ssz decoder
+		c.sszUnsupported.Store(true)
+	}
+
 	block, exists, err := c.getBeaconBlock(blockId)
 	if err != nil {
 		return beacon.BeaconBlock{}, false, err
@@ -532,6 +572,51 @@ func (c *StandardHttpClient) getValidators(ctx context.Context, stateId string,
 	return validators, nil
 }
 
+// Get validators with the ids in the request body, see getValidators
+func (c *StandardHttpClient) postValidators(ctx context.Context, stateId string, ids []string) (ValidatorsResponse, int, error) {
+	responseBody, status, err := c.postRequest(fmt.Sprintf(RequestValidatorsPath, stateId), ValidatorsRequest{Ids: ids}, ctx)
+	if err != nil {
+		return ValidatorsResponse{}, 0, fmt.Errorf("could not post validators: %w", err)
+	}
+	if status != http.StatusOK {
+		return ValidatorsResponse{}, status, fmt.Errorf("could not post validators: HTTP status %d; response body: '%s'", status, string(responseBody))
+	}
+
+	var validators ValidatorsResponse
+	if err := json.Unmarshal(responseBody, &validators); err != nil {
+		return ValidatorsResponse{}, status, fmt.Errorf("could not decode validators: %w", err)
+	}
+	return validators, status, nil
+}
+
+// getValidatorsBatch posts the batch of ids, and falls back to GET requests if the node does not support
+// POST. A not found state is not told apart from a missing route, so GET is only remembered once it succeeds
+func (c *StandardHttpClient) getValidatorsBatch(ctx context.Context, stateId string, ids []string) (ValidatorsResponse, error) {
+	if !c.postValidatorsUnsupported.Load() {
+		validators, status, err := c.postValidators(ctx, stateId, ids)
+		switch status {
+		case http.StatusNotFound, http.StatusMethodNotAllowed, http.StatusUnsupportedMediaType:
+		default:
+			return validators, err
+		}
+	}
+
+	data := make([]Validator, 0, len(ids))
+	for bsi := 0; bsi < len(ids); bsi += MaxRequestValidatorsCount {
+		bei := bsi + MaxRequestValidatorsCount
+		if bei > len(ids) {
+			bei = len(ids)
+		}
+		validators, err := c.getValidators(ctx, stateId, ids[bsi:bei])
+		if err != nil {
+			return ValidatorsResponse{}, err
+		}
+		data = append(data, validators.Data...)
+	}
+	c.postValidatorsUnsupported.Store(true)
+	return ValidatorsResponse{Data: data}, nil
+}
+
 // Get validators by pubkeys and status options
 func (c *StandardHttpClient) getValidatorsByOpts(ctx context.Context, pubkeysOrIndices []string, opts *beacon.ValidatorStatusOptions) (ValidatorsResponse, error) {
 
@@ -552,11 +637,11 @@ func (c *StandardHttpClient) getValidatorsByOpts(ctx context.Context, pubkeysOrI
 
 	// Load validator data in batches & return
 	data := make([]Validator, 0, len(pubkeysOrIndices))
-	for bsi := 0; bsi < len(pubkeysOrIndices); bsi += MaxRequestValidatorsCount {
+	for bsi := 0; bsi < len(pubkeysOrIndices); bsi += MaxPostValidatorsCount {
 
 		// Get batch start & end index
 		vsi := bsi
-		vei := bsi + MaxRequestValidatorsCount
+		vei := bsi + MaxPostValidatorsCount
 		if vei > len(pubkeysOrIndices) {
 			vei = len(pubkeysOrIndices)
 		}
@@ -566,7 +651,7 @@ func (c *StandardHttpClient) getValidatorsByOpts(ctx context.Context, pubkeysOrI
 		for vi := vsi; vi < vei; vi++ {
 			batch[vi-vsi] = pubkeysOrIndices[vi]
 		}
-		validators, err := c.getValidators(ctx, stateId, batch)
+		validators, err := c.getValidatorsBatch(ctx, stateId, batch)
 		if err != nil {
 			return ValidatorsResponse{}, err
 		}
@@ -611,6 +696,38 @@ func (c *StandardHttpClient) getBeaconBlock(blockId uint64) (BeaconBlockResponse
 	return beaconBlock, true, nil
 }
 
+// Get the target beacon block in ssz, the block is nil if the node answered json or its fork is not supported by the ssz decoder
+func (c *StandardHttpClient) getBeaconBlockSsz(blockId uint64) (interfaces.ReadOnlySignedBeaconBlock, bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*20)
+	defer cancel()
+	header := http.Header{}
+	header.Set("Accept", RequestContentTypeSsz)
+	responseBody, status, responseHeader, err := c.getRequestWithHeader(ctx, fmt.Sprintf(RequestBeaconBlockPath, blockId), header)
+	if err != nil {
+		return nil, false, fmt.Errorf("could not get beacon block data: %w", err)
+	}
+	if status == http.StatusNotFound {
+		return nil, false, nil
+	}
+	if status == http.StatusNotAcceptable {
+		return nil, true, nil
+	}
+	if status != http.StatusOK {
+		return nil, false, fmt.Errorf("could not get beacon block data: HTTP status %d; response body: '%s'", status, string(responseBody))
+	}
+	if !strings.HasPrefix(responseHeader.Get("Content-Type"), RequestContentTypeSsz) {
+		return nil, true, nil
+	}
+	block, err := decodeSszBlock(responseHeader.Get(ConsensusVersionHeader), responseBody)
+	if errors.Is(err, errUnsupportedSszVersion) {
+		return nil, true, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return block, true, nil
+}
+
 // Make a GET request to the beacon node
 func (c *StandardHttpClient) getRequest(requestPath string, optionalCtx ...context.Context) ([]byte, int, error) {
 	var ctx context.Context
@@ -624,30 +741,52 @@ func (c *StandardHttpClient) getRequest(requestPath string, optionalCtx ...conte
 		return nil, 0, fmt.Errorf("you can pass only one context")
 	}
 
+	body, status, _, err := c.getRequestWithHeader(ctx, requestPath, nil)
+	return body, status, err
+}
+
+// Make a GET request with header to the beacon node, the header of the response is returned too
+func (c *StandardHttpClient) getRequestWithHeader(ctx context.Context, requestPath string, header http.Header) ([]byte, int, http.Header, error) {
 	url := fmt.Sprintf(RequestUrlFormat, c.providerAddress, requestPath)
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
-		return nil, 0, err
+		return nil, 0, nil, err
+	}
+	for key, values := range header {
+		req.Header[key] = values
 	}
 
-	response, err := http.DefaultClient.Do(req)
+	startAt := time.Now()
+	response, err := c.client().Do(req)
 	if err != nil {
-		return []byte{}, 0, err
+		c.observe(requestPath, startAt, 0, err)
+		return []byte{}, 0, nil, err
 	}
 	defer func() {
 		_ = response.Body.Close()
 	}()
 
 	body, err := io.ReadAll(response.Body)
+	c.observe(requestPath, startAt, response.StatusCode, err)
 	if err != nil {
-		return []byte{}, 0, err
+		return []byte{}, 0, nil, err
 	}
 
-	return body, response.StatusCode, nil
+	return body, response.StatusCode, response.Header, nil
 }
 
 // Make a POST request to the beacon node
-func (c *StandardHttpClient) postRequest(requestPath string, requestBody interface{}) ([]byte, int, error) {
+func (c *StandardHttpClient) postRequest(requestPath string, requestBody interface{}, optionalCtx ...context.Context) ([]byte, int, error) {
+	var ctx context.Context
+	if len(optionalCtx) == 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(context.Background(), 60*time.Second)
+		defer cancel()
+	} else if len(optionalCtx) == 1 {
+		ctx = optionalCtx[0]
+	} else {
+		return nil, 0, fmt.Errorf("you can pass only one context")
+	}
 
 	// Get request body
 	requestBodyBytes, err := json.Marshal(requestBody)
@@ -657,17 +796,24 @@ func (c *StandardHttpClient) postRequest(requestPath string, requestBody interfa
 	requestBodyReader := bytes.NewReader(requestBodyBytes)
 
 	// Send request
-	client := http.Client{Timeout: 60 * time.Second}
-	response, err := client.Post(fmt.Sprintf(RequestUrlFormat, c.providerAddress, requestPath), RequestContentType, requestBodyReader)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, fmt.Sprintf(RequestUrlFormat, c.providerAddress, requestPath), requestBodyReader)
 	if err != nil {
 		return []byte{}, 0, err
 	}
+	req.Header.Set("Content-Type", RequestContentType)
+	startAt := time.Now()
+	response, err := c.client().Do(req)
+	if err != nil {
+		c.observe(requestPath, startAt, 0, err)
+		return []byte{}, 0, err
+	}
 	defer func() {
 		_ = response.Body.Close()
 	}()
 
 	// Get response
 	body, err := io.ReadAll(response.Body)
+	c.observe(requestPath, startAt, response.StatusCode, err)
 	if err != nil {
 		return []byte{}, 0, err
 	}
@@ -676,3 +822,31 @@ func (c *StandardHttpClient) postRequest(requestPath string, requestBody interfa
 	return body, response.StatusCode, nil
 
 }
+
+// client falls back to http.DefaultClient for clients not built by the constructors
+func (c *StandardHttpClient) client() *http.Client {
+	if c.httpClient == nil {
+		return http.DefaultClient
+	}
+	return c.httpClient
+}
+
+// observe records the request in metrics, not found responses are expected for missed slots
+func (c *StandardHttpClient) observe(requestPath string, startAt time.Time, status int, err error) {
+	failed := err != nil || (status >= http.StatusBadRequest && status != http.StatusNotFound)
+	metrics.ObserveRpc(metrics.Eth2, c.providerAddress, requestRoute(requestPath), startAt, failed)
+}
+
+// requestRoute replaces ids in the request path with placeholders to keep metric labels bounded,
+// e.g. /eth/v2/beacon/blocks/123 => /eth/v2/beacon/blocks/{id}
+func requestRoute(requestPath string) string {
+	requestPath, _, _ = strings.Cut(requestPath, "?")
+	segments := strings.Split(requestPath, "/")
+	for i := 1; i < len(segments); i++ {
+		switch segments[i-1] {
+		case "states", "blocks", "headers":
+			segments[i] = "{id}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
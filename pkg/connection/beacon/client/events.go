@@ -0,0 +1,148 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/stafiprotocol/eth-lsd-relay/pkg/connection/beacon"
+)
+
+const (
+	RequestEventsPath = "/eth/v1/events?topics=%s"
+
+	maxEventSize = 1 << 20
+)
+
+// SubscribeEvents reads the server-sent event stream of topics and calls handler for each event,
+// it blocks until ctx is done or the stream fails and always returns a non nil error
+func (c *StandardHttpClient) SubscribeEvents(ctx context.Context, topics []string, handler func(beacon.Event)) error {
+	requestPath := fmt.Sprintf(RequestEventsPath, strings.Join(topics, ","))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf(RequestUrlFormat, c.providerAddress, requestPath), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set("Cache-Control", "no-cache")
+
+	// no client timeout, the stream is long-lived and ends with ctx
+	streamClient := *c.client()
+	streamClient.Timeout = 0
+	startAt := time.Now()
+	response, err := streamClient.Do(req)
+	if err != nil {
+		c.observe(requestPath, startAt, 0, err)
+		return err
+	}
+	defer func() {
+		_ = response.Body.Close()
+	}()
+	c.observe(requestPath, startAt, response.StatusCode, nil)
+	if response.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(response.Body, 512))
+		return fmt.Errorf("could not subscribe events: HTTP status %d; response body: '%s'", response.StatusCode, string(body))
+	}
+
+	err = readEventStream(response.Body, func(topic string, data []byte) error {
+		event, err := decodeEvent(topic, data)
+		if err != nil {
+			return err
+		}
+		if event != nil {
+			handler(*event)
+		}
+		return nil
+	})
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if err == nil {
+		err = io.ErrUnexpectedEOF
+	}
+	return fmt.Errorf("event stream closed: %w", err)
+}
+
+// readEventStream parses the text/event-stream format, comments used as heartbeats are skipped
+func readEventStream(r io.Reader, dispatch func(topic string, data []byte) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxEventSize)
+
+	topic := ""
+	data := make([]string, 0, 1)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			if len(data) > 0 {
+				if err := dispatch(topic, []byte(strings.Join(data, "\n"))); err != nil {
+					return err
+				}
+			}
+			topic = ""
+			data = data[:0]
+			continue
+		}
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "event":
+			topic = value
+		case "data":
+			data = append(data, value)
+		}
+	}
+	return scanner.Err()
+}
+
+// decodeEvent returns nil for topics not subscribed by the relay
+func decodeEvent(topic string, data []byte) (*beacon.Event, error) {
+	event := beacon.Event{Topic: topic}
+	switch topic {
+	case beacon.EventTopicHead:
+		var head HeadEventResponse
+		if err := json.Unmarshal(data, &head); err != nil {
+			return nil, fmt.Errorf("could not decode %s event: %w", topic, err)
+		}
+		event.Head = &beacon.HeadEvent{
+			Slot:                uint64(head.Slot),
+			Block:               head.Block,
+			State:               head.State,
+			EpochTransition:     head.EpochTransition,
+			ExecutionOptimistic: head.ExecutionOptimistic,
+		}
+	case beacon.EventTopicFinalizedCheckpoint:
+		var checkpoint FinalizedCheckpointEventResponse
+		if err := json.Unmarshal(data, &checkpoint); err != nil {
+			return nil, fmt.Errorf("could not decode %s event: %w", topic, err)
+		}
+		event.FinalizedCheckpoint = &beacon.FinalizedCheckpointEvent{
+			Epoch:               uint64(checkpoint.Epoch),
+			Block:               checkpoint.Block,
+			State:               checkpoint.State,
+			ExecutionOptimistic: checkpoint.ExecutionOptimistic,
+		}
+	case beacon.EventTopicChainReorg:
+		var reorg ChainReorgEventResponse
+		if err := json.Unmarshal(data, &reorg); err != nil {
+			return nil, fmt.Errorf("could not decode %s event: %w", topic, err)
+		}
+		event.ChainReorg = &beacon.ChainReorgEvent{
+			Slot:                uint64(reorg.Slot),
+			Depth:               uint64(reorg.Depth),
+			OldHeadBlock:        reorg.OldHeadBlock,
+			NewHeadBlock:        reorg.NewHeadBlock,
+			Epoch:               uint64(reorg.Epoch),
+			ExecutionOptimistic: reorg.ExecutionOptimistic,
+		}
+	default:
+		return nil, nil
+	}
+	return &event, nil
+}
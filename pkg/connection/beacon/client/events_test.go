@@ -0,0 +1,57 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stafiprotocol/eth-lsd-relay/pkg/connection/beacon"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSubscribeEvents(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/eth/v1/events", r.URL.Path)
+		assert.Equal(t, "head,finalized_checkpoint,chain_reorg", r.URL.Query().Get("topics"))
+		assert.Equal(t, "text/event-stream", r.Header.Get("Accept"))
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, ": heartbeat\n\n")
+		fmt.Fprint(w, "event: head\ndata: {\"slot\":\"10\",\"block\":\"0x9a2fefd2fdb57f74993c7780ea5b9030d2897b615b89f808011ca5aebed54eaf\",\"epoch_transition\":false}\n\n")
+		fmt.Fprint(w, "event: voluntary_exit\ndata: {}\n\n")
+		fmt.Fprint(w, "event: finalized_checkpoint\ndata: {\"block\":\"0x9a2fefd2fdb57f74993c7780ea5b9030d2897b615b89f808011ca5aebed54eaf\",\n")
+		fmt.Fprint(w, "data: \"state\":\"0x600e852a08c1200654ddf11025f1ceacb3c2e74bdd5c630cde0838b2591b69f9\",\"epoch\":\"2\"}\n\n")
+		fmt.Fprint(w, "event: chain_reorg\ndata: {\"slot\":\"200\",\"depth\":\"2\",\"old_head_block\":\"0x0000000000000000000000000000000000000000000000000000000000000001\",\"new_head_block\":\"0x0000000000000000000000000000000000000000000000000000000000000002\",\"epoch\":\"6\"}\n\n")
+	}))
+	defer server.Close()
+
+	c := &StandardHttpClient{providerAddress: server.URL}
+	events := make([]beacon.Event, 0)
+	err := c.SubscribeEvents(context.Background(), []string{beacon.EventTopicHead, beacon.EventTopicFinalizedCheckpoint, beacon.EventTopicChainReorg}, func(e beacon.Event) {
+		events = append(events, e)
+	})
+	assert.ErrorContains(t, err, "event stream closed")
+
+	assert.Len(t, events, 3)
+	assert.Equal(t, uint64(10), events[0].Head.Slot)
+	assert.Equal(t, common.HexToHash("0x9a2fefd2fdb57f74993c7780ea5b9030d2897b615b89f808011ca5aebed54eaf"), events[0].Head.Block)
+	assert.Equal(t, uint64(2), events[1].FinalizedCheckpoint.Epoch)
+	assert.Equal(t, common.HexToHash("0x600e852a08c1200654ddf11025f1ceacb3c2e74bdd5c630cde0838b2591b69f9"), events[1].FinalizedCheckpoint.State)
+	assert.Equal(t, uint64(2), events[2].ChainReorg.Depth)
+	assert.Equal(t, uint64(200), events[2].ChainReorg.Slot)
+	assert.Equal(t, common.HexToHash("0x02"), events[2].ChainReorg.NewHeadBlock)
+}
+
+func TestSubscribeEventsStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "topic not supported", http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	c := &StandardHttpClient{providerAddress: server.URL}
+	err := c.SubscribeEvents(context.Background(), []string{beacon.EventTopicHead}, func(beacon.Event) {})
+	assert.ErrorContains(t, err, "HTTP status 400")
+}
@@ -0,0 +1,15 @@
+package client
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestRoute(t *testing.T) {
+	assert.Equal(t, "/eth/v2/beacon/blocks/{id}", requestRoute(fmt.Sprintf(RequestBeaconBlockPath, 123)))
+	assert.Equal(t, "/eth/v1/beacon/states/{id}/validators", requestRoute(fmt.Sprintf(RequestValidatorsPath, "head")+"?id=0x01,0x02"))
+	assert.Equal(t, "/eth/v1/beacon/states/{id}/finality_checkpoints", requestRoute(fmt.Sprintf(RequestFinalityCheckpointsPath, "finalized")))
+	assert.Equal(t, RequestGenesisPath, requestRoute(RequestGenesisPath))
+}
@@ -60,6 +60,27 @@ type FinalityCheckpointsResponse struct {
 		} `json:"finalized"`
 	} `json:"data"`
 }
+type HeadEventResponse struct {
+	Slot                uinteger    `json:"slot"`
+	Block               common.Hash `json:"block"`
+	State               common.Hash `json:"state"`
+	EpochTransition     bool        `json:"epoch_transition"`
+	ExecutionOptimistic bool        `json:"execution_optimistic"`
+}
+type FinalizedCheckpointEventResponse struct {
+	Block               common.Hash `json:"block"`
+	State               common.Hash `json:"state"`
+	Epoch               uinteger    `json:"epoch"`
+	ExecutionOptimistic bool        `json:"execution_optimistic"`
+}
+type ChainReorgEventResponse struct {
+	Slot                uinteger    `json:"slot"`
+	Depth               uinteger    `json:"depth"`
+	OldHeadBlock        common.Hash `json:"old_head_block"`
+	NewHeadBlock        common.Hash `json:"new_head_block"`
+	Epoch               uinteger    `json:"epoch"`
+	ExecutionOptimistic bool        `json:"execution_optimistic"`
+}
 type ForkResponse struct {
 	Data struct {
 		PreviousVersion byteArray `json:"previous_version"`
@@ -214,6 +235,9 @@ type WithdrawalPayload struct {
 	Amount         uinteger `json:"amount"`
 }
 
+type ValidatorsRequest struct {
+	Ids []string `json:"ids"`
+}
 type ValidatorsResponse struct {
 	Data []Validator `json:"data"`
 }
@@ -0,0 +1,73 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stafiprotocol/eth-lsd-relay/pkg/connection/beacon"
+	"github.com/stretchr/testify/assert"
+)
+
+func writeValidators(w http.ResponseWriter, ids []string) {
+	validators := make([]string, len(ids))
+	for i, id := range ids {
+		validators[i] = fmt.Sprintf(`{"index":"%s","balance":"32000000000","status":"active_ongoing","validator":{"pubkey":"0x%096s","withdrawal_credentials":"0x%064d","effective_balance":"32000000000","slashed":false,"activation_eligibility_epoch":"0","activation_epoch":"0","exit_epoch":"18446744073709551615","withdrawable_epoch":"18446744073709551615"}}`, id, id, 0)
+	}
+	fmt.Fprintf(w, `{"data":[%s]}`, strings.Join(validators, ","))
+}
+
+func TestGetValidatorStatusesByIndices(t *testing.T) {
+	posts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/eth/v1/beacon/states/320/validators", r.URL.Path)
+		assert.Equal(t, http.MethodPost, r.Method)
+		posts++
+		var request ValidatorsRequest
+		assert.Nil(t, json.NewDecoder(r.Body).Decode(&request))
+		writeValidators(w, request.Ids)
+	}))
+	defer server.Close()
+
+	c := &StandardHttpClient{providerAddress: server.URL, eth2Config: beacon.Eth2Config{SlotsPerEpoch: 32}}
+	indices := make([]uint64, MaxPostValidatorsCount+1)
+	for i := range indices {
+		indices[i] = uint64(i)
+	}
+	epoch := uint64(10)
+	statuses, err := c.GetValidatorStatusesByIndices(context.Background(), indices, &beacon.ValidatorStatusOptions{Epoch: &epoch})
+	assert.Nil(t, err)
+	assert.Len(t, statuses, len(indices))
+	assert.Equal(t, uint64(32e9), statuses[1000].Balance)
+	assert.True(t, statuses[0].Exists)
+	assert.Equal(t, 2, posts)
+}
+
+func TestGetValidatorsPostFallback(t *testing.T) {
+	posts, gets := 0, 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			posts++
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		gets++
+		writeValidators(w, strings.Split(r.URL.Query().Get("id"), ","))
+	}))
+	defer server.Close()
+
+	c := &StandardHttpClient{providerAddress: server.URL, eth2Config: beacon.Eth2Config{SlotsPerEpoch: 32}}
+	epoch := uint64(10)
+	for i := 0; i < 2; i++ {
+		statuses, err := c.GetValidatorStatusesByIndices(context.Background(), []uint64{1, 2, 3}, &beacon.ValidatorStatusOptions{Epoch: &epoch})
+		assert.Nil(t, err)
+		assert.Len(t, statuses, 3)
+	}
+	// POST is not tried again once GET succeeded
+	assert.Equal(t, 1, posts)
+	assert.Equal(t, 2, gets)
+}
@@ -139,6 +139,45 @@ type AttestationInfo struct {
 	CommitteeIndex  uint64
 }
 
+// Event stream topics
+const (
+	EventTopicHead                = "head"
+	EventTopicFinalizedCheckpoint = "finalized_checkpoint"
+	EventTopicChainReorg          = "chain_reorg"
+)
+
+// Event is one event of the beacon event stream, only the field of its topic is set
+type Event struct {
+	Topic               string
+	Head                *HeadEvent
+	FinalizedCheckpoint *FinalizedCheckpointEvent
+	ChainReorg          *ChainReorgEvent
+}
+
+type HeadEvent struct {
+	Slot                uint64
+	Block               common.Hash
+	State               common.Hash
+	EpochTransition     bool
+	ExecutionOptimistic bool
+}
+
+type FinalizedCheckpointEvent struct {
+	Epoch               uint64
+	Block               common.Hash
+	State               common.Hash
+	ExecutionOptimistic bool
+}
+
+type ChainReorgEvent struct {
+	Slot                uint64
+	Depth               uint64
+	OldHeadBlock        common.Hash
+	NewHeadBlock        common.Hash
+	Epoch               uint64
+	ExecutionOptimistic bool
+}
+
 // Beacon client type
 type BeaconClientType int
 
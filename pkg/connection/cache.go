@@ -5,28 +5,51 @@ import (
 	"fmt"
 	"math/big"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/avast/retry-go/v4"
 	"github.com/sirupsen/logrus"
 	"github.com/stafiprotocol/eth-lsd-relay/pkg/connection/beacon"
 	"github.com/stafiprotocol/eth-lsd-relay/pkg/connection/types"
+	"github.com/stafiprotocol/eth-lsd-relay/pkg/metrics"
+	"github.com/stafiprotocol/eth-lsd-relay/pkg/notify"
 	"github.com/stafiprotocol/eth-lsd-relay/pkg/utils"
 )
 
+const (
+	beaconHeadPollInterval = 12 * time.Second
+	// the beacon head is still polled at this interval while the event stream is up
+	beaconHeadRefreshInterval = 5 * time.Minute
+	// heads arrive every slot, a stream silent this long is treated as down
+	eventStreamIdleTimeout = time.Minute
+	eventStreamRetryDelay  = 10 * time.Second
+	// reorgs at least this deep are alerted, shallower ones are common and only logged
+	deepReorgAlertDepth = 3
+)
+
+var beaconEventTopics = []string{beacon.EventTopicHead, beacon.EventTopicFinalizedCheckpoint, beacon.EventTopicChainReorg}
+
 type CachedConnection struct {
 	*Connection
 	stop chan struct{}
 
 	// cache data
+	headMutex                sync.RWMutex
 	beaconHead               beacon.BeaconHead
 	beaconHeadErr            error
+	beaconHeadSyncedAt       time.Time
 	eth1LatestBlockNumber    uint64
 	eth1LatestBlockNumberErr error
 
 	chainId              *big.Int
 	eth2Config           *beacon.Eth2Config
-	validatorStatusCache sync.Map
+	validatorStatusCache sync.Map // statuses at a slot
+	statusCache          *validatorStatusCache
+
+	lastEventAt   atomic.Int64 // unix nano of the latest event of the current stream, zero if the stream is down
+	subsMutex     sync.Mutex
+	finalizedSubs []chan struct{}
 }
 
 func NewCachedConnection(conn *Connection) (*CachedConnection, error) {
@@ -34,6 +57,7 @@ func NewCachedConnection(conn *Connection) (*CachedConnection, error) {
 		Connection:           conn,
 		stop:                 make(chan struct{}),
 		validatorStatusCache: sync.Map{},
+		statusCache:          newValidatorStatusCache(),
 	}
 	return &cc, nil
 }
@@ -53,18 +77,52 @@ func (c *CachedConnection) Start() error {
 
 	utils.SafeGoWithRestart(c.syncBeaconHeadService)
 	utils.SafeGoWithRestart(c.syncEth1LatestBlockService)
+	utils.SafeGoWithRestart(c.beaconEventService)
 
 	return nil
 }
 
 func (c *CachedConnection) Stop() {
 	close(c.stop)
+	if c.txManager != nil {
+		c.txManager.Stop()
+	}
 }
 
 func (c *CachedConnection) BeaconHead() (beacon.BeaconHead, error) {
+	c.headMutex.RLock()
+	defer c.headMutex.RUnlock()
 	return c.beaconHead, c.beaconHeadErr
 }
 
+// SubscribeFinalizedCheckpoint returns a channel signaled after the beacon head is refreshed on a new finalized checkpoint,
+// signals are coalesced while the receiver is busy and none is sent while the event stream is down.
+// The returned func unsubscribes, it must be called once the channel is not read anymore
+func (c *CachedConnection) SubscribeFinalizedCheckpoint() (<-chan struct{}, func()) {
+	ch := make(chan struct{}, 1)
+	c.subsMutex.Lock()
+	c.finalizedSubs = append(c.finalizedSubs, ch)
+	c.subsMutex.Unlock()
+
+	unsubscribe := func() {
+		c.subsMutex.Lock()
+		defer c.subsMutex.Unlock()
+		for i, sub := range c.finalizedSubs {
+			if sub == ch {
+				c.finalizedSubs = append(c.finalizedSubs[:i], c.finalizedSubs[i+1:]...)
+				return
+			}
+		}
+	}
+	return ch, unsubscribe
+}
+
+// EventStreamUp reports whether beacon events are being received
+func (c *CachedConnection) EventStreamUp() bool {
+	lastEventAt := c.lastEventAt.Load()
+	return lastEventAt != 0 && time.Since(time.Unix(0, lastEventAt)) < eventStreamIdleTimeout
+}
+
 func (c *CachedConnection) ChainID() (*big.Int, error) {
 	return c.chainId, nil
 }
@@ -83,24 +141,133 @@ func (c *CachedConnection) Eth2Config() (beacon.Eth2Config, error) {
 
 // internal jobs
 
+// syncBeaconHeadService polls the beacon head, only as a fallback while the event stream is up
 func (c *CachedConnection) syncBeaconHeadService() {
 	for {
 		select {
 		case <-c.stop:
 			return
 		default:
-			if err := c.syncBeaconHead(); err != nil {
-				logrus.Errorf("connection cache: fail to sync beacon head: %s", utils.ErrToLogStr(err))
+			c.headMutex.RLock()
+			syncedAt := c.beaconHeadSyncedAt
+			c.headMutex.RUnlock()
+			if !c.EventStreamUp() || time.Since(syncedAt) >= beaconHeadRefreshInterval {
+				if err := c.syncBeaconHead(); err != nil {
+					logrus.Errorf("connection cache: fail to sync beacon head: %s", utils.ErrToLogStr(err))
+				}
 			}
 		}
-		time.Sleep(12 * time.Second)
+		time.Sleep(beaconHeadPollInterval)
 	}
 }
 
 func (c *CachedConnection) syncBeaconHead() error {
-	c.beaconHead, c.beaconHeadErr = retry.DoWithData(c.GetBeaconHead,
+	head, err := retry.DoWithData(c.GetBeaconHead,
 		retry.Delay(time.Second*2), retry.Attempts(5))
-	return c.beaconHeadErr
+
+	c.headMutex.Lock()
+	defer c.headMutex.Unlock()
+	c.beaconHead, c.beaconHeadErr = head, err
+	c.beaconHeadSyncedAt = time.Now()
+	return err
+}
+
+func (c *CachedConnection) beaconEventService() {
+	for {
+		select {
+		case <-c.stop:
+			return
+		default:
+		}
+
+		err := c.subscribeBeaconEvents()
+		c.lastEventAt.Store(0)
+		metrics.SetBeaconEventStreamUp(false)
+		select {
+		case <-c.stop:
+			return
+		default:
+		}
+		logrus.Warnf("connection cache: beacon event stream down, polling beacon head until it is back: %s", utils.ErrToLogStr(err))
+		utils.Sleep(c.stop, eventStreamRetryDelay)
+	}
+}
+
+// subscribeBeaconEvents consumes the event stream until it fails, is silent for eventStreamIdleTimeout or the connection stops
+func (c *CachedConnection) subscribeBeaconEvents() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	subscribedAt := time.Now().UnixNano()
+	utils.SafeGo(func() {
+		ticker := time.NewTicker(eventStreamIdleTimeout / 4)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-c.stop:
+				cancel()
+				return
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				lastEventAt := max(c.lastEventAt.Load(), subscribedAt)
+				if time.Since(time.Unix(0, lastEventAt)) >= eventStreamIdleTimeout {
+					logrus.Warn("connection cache: no beacon event received in time, resubscribe")
+					cancel()
+					return
+				}
+			}
+		}
+	})
+
+	return c.SubscribeBeaconEvents(ctx, beaconEventTopics, c.handleBeaconEvent)
+}
+
+func (c *CachedConnection) handleBeaconEvent(event beacon.Event) {
+	if c.lastEventAt.Swap(time.Now().UnixNano()) == 0 {
+		metrics.SetBeaconEventStreamUp(true)
+		logrus.Info("connection cache: beacon event stream connected")
+	}
+	metrics.IncBeaconEvent(event.Topic)
+
+	switch {
+	case event.FinalizedCheckpoint != nil:
+		logrus.Debugf("connection cache: finalized checkpoint epoch %d", event.FinalizedCheckpoint.Epoch)
+		if err := c.syncBeaconHead(); err != nil {
+			logrus.Errorf("connection cache: fail to sync beacon head: %s", utils.ErrToLogStr(err))
+			return
+		}
+		c.subsMutex.Lock()
+		for _, ch := range c.finalizedSubs {
+			select {
+			case ch <- struct{}{}:
+			default:
+			}
+		}
+		c.subsMutex.Unlock()
+
+	case event.ChainReorg != nil:
+		reorg := event.ChainReorg
+		metrics.ObserveBeaconReorg(reorg.Depth)
+		log := logrus.WithFields(logrus.Fields{
+			"slot":         reorg.Slot,
+			"depth":        reorg.Depth,
+			"oldHeadBlock": reorg.OldHeadBlock.String(),
+			"newHeadBlock": reorg.NewHeadBlock.String(),
+		})
+		log.Warn("connection cache: beacon chain reorg")
+		if reorg.Depth >= deepReorgAlertDepth {
+			notify.Notify(notify.Alert{
+				Key:      "beacon-chain-reorg",
+				Severity: notify.SeverityWarning,
+				Title:    fmt.Sprintf("beacon chain reorg of %d slots at slot %d", reorg.Depth, reorg.Slot),
+				Fields: map[string]string{
+					"oldHeadBlock": reorg.OldHeadBlock.String(),
+					"newHeadBlock": reorg.NewHeadBlock.String(),
+				},
+			})
+		}
+	}
 }
 
 func (c *CachedConnection) Eth1LatestBlock() (uint64, error) {
@@ -135,6 +302,15 @@ func (c *CachedConnection) cacheChainID() (err error) {
 }
 
 func (c *CachedConnection) GetValidatorStatus(ctx context.Context, pubkey types.ValidatorPubkey, opts *beacon.ValidatorStatusOptions) (validatorStatus beacon.ValidatorStatus, err error) {
+	// shares the cache of GetValidatorStatuses, a status not found is the zero status as of Connection.GetValidatorStatus
+	if _, ok := cachedEpochOf(opts); ok {
+		statuses, err := c.GetValidatorStatuses(ctx, []types.ValidatorPubkey{pubkey}, opts)
+		if err != nil {
+			return beacon.ValidatorStatus{}, err
+		}
+		return statuses[pubkey], nil
+	}
+
 	cacheKey := ""
 	if opts != nil {
 		if opts.Epoch != nil {
@@ -0,0 +1,23 @@
+package connection
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSubscribeFinalizedCheckpoint(t *testing.T) {
+	c := &CachedConnection{}
+	first, unsubscribeFirst := c.SubscribeFinalizedCheckpoint()
+	second, unsubscribeSecond := c.SubscribeFinalizedCheckpoint()
+	assert.Len(t, c.finalizedSubs, 2)
+
+	unsubscribeFirst()
+	unsubscribeFirst()
+	assert.Len(t, c.finalizedSubs, 1)
+	assert.True(t, c.finalizedSubs[0] == second)
+	assert.False(t, c.finalizedSubs[0] == first)
+
+	unsubscribeSecond()
+	assert.Empty(t, c.finalizedSubs)
+}
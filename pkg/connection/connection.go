@@ -8,21 +8,22 @@ import (
 	"fmt"
 	"math/big"
 	"strings"
-	"sync"
 	"time"
 
 	"github.com/avast/retry-go/v4"
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
-	ethcrypto "github.com/ethereum/go-ethereum/crypto"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
 	"github.com/forta-network/go-multicall"
 	"github.com/samber/lo"
-	"github.com/stafiprotocol/chainbridge/utils/crypto/secp256k1"
+	"github.com/shopspring/decimal"
 	"github.com/stafiprotocol/eth-lsd-relay/pkg/config"
 	"github.com/stafiprotocol/eth-lsd-relay/pkg/connection/beacon"
 	"github.com/stafiprotocol/eth-lsd-relay/pkg/connection/beacon/client"
 	"github.com/stafiprotocol/eth-lsd-relay/pkg/connection/types"
 	"github.com/stafiprotocol/eth-lsd-relay/pkg/gomicrobee"
+	"github.com/stafiprotocol/eth-lsd-relay/pkg/metrics"
+	"github.com/stafiprotocol/eth-lsd-relay/pkg/notify"
 	"github.com/stafiprotocol/eth-lsd-relay/pkg/utils"
 )
 
@@ -38,6 +39,19 @@ func (e *GasPriceError) Error() string {
 	)
 }
 
+// InsufficientBalanceError is returned instead of sending a tx the signer can not pay for
+type InsufficientBalanceError struct {
+	Balance  *big.Int
+	Required *big.Int
+}
+
+func (e *InsufficientBalanceError) Error() string {
+	return fmt.Sprintf("insufficient relayer balance(ether), balance: %s, required: %s",
+		decimal.NewFromBigInt(e.Balance, -18).String(),
+		decimal.NewFromBigInt(e.Required, -18).String(),
+	)
+}
+
 var Gwei5 = big.NewInt(5e9)
 var Gwei10 = big.NewInt(10e9)
 var Gwei20 = big.NewInt(20e9)
@@ -45,6 +59,7 @@ var Gwei20 = big.NewInt(20e9)
 type eth2Client struct {
 	*client.StandardHttpClient
 	endpoint string
+	index    int // in the endpoint pool
 
 	config           beacon.Eth2Config
 	latestBeaconHead *beacon.BeaconHead
@@ -55,25 +70,29 @@ type eth2Client struct {
 
 type Connection struct {
 	endpoints          []config.Endpoint
-	kp                 *secp256k1.Keypair
+	signer             Signer
 	gasLimit           *big.Int
 	maxGasPrice        *big.Int
 	gasPriceMultiplier *big.Float
 
 	eth1Client  ContractBackend
 	eth2Clients []*eth2Client
+	eth2Pool    *EndpointPool
 
-	txOpts      *bind.TransactOpts
+	txManager   *TxManager
 	callOpts    bind.CallOpts
-	optsLock    sync.Mutex
 	multiCaller *multicall.Caller
+	quorum      Quorum
+
+	verifiedMultiCaller *multicall.Caller // nil if the quorum is not enabled
 
 	latestMultiCallMicrobeeSystem gomicrobee.System[*multicall.Call, *MultiCall]
 }
 
 // NewConnection returns an uninitialized connection, must call Connection.Connect() before using.
-func NewConnection(endpoints []config.Endpoint, kp *secp256k1.Keypair, gasLimit, maxGasPrice *big.Int, gasPriceMultiplier *big.Float) (*Connection, error) {
-	if kp != nil {
+// signer can be nil for a read-only connection
+func NewConnection(endpoints []config.Endpoint, signer Signer, gasLimit, maxGasPrice *big.Int, gasPriceMultiplier *big.Float) (*Connection, error) {
+	if signer != nil {
 		if maxGasPrice.Cmp(big.NewInt(0)) <= 0 {
 			return nil, fmt.Errorf("max gas price empty")
 		}
@@ -83,7 +102,7 @@ func NewConnection(endpoints []config.Endpoint, kp *secp256k1.Keypair, gasLimit,
 	}
 	c := &Connection{
 		endpoints:          endpoints,
-		kp:                 kp,
+		signer:             signer,
 		gasLimit:           gasLimit,
 		maxGasPrice:        maxGasPrice,
 		gasPriceMultiplier: gasPriceMultiplier,
@@ -116,14 +135,8 @@ func (c *Connection) connect() error {
 		return err
 	}
 
-	if c.kp != nil {
-		// Construct tx opts, call opts, and nonce mechanism
-		opts, err := c.newTransactOpts(big.NewInt(0), c.gasLimit)
-		if err != nil {
-			return err
-		}
-		c.txOpts = opts
-		c.callOpts = bind.CallOpts{Pending: false, From: c.kp.CommonAddress(), BlockNumber: nil, Context: context.Background()}
+	if c.signer != nil {
+		c.callOpts = bind.CallOpts{Pending: false, From: c.signer.Address(), BlockNumber: nil, Context: context.Background()}
 	} else {
 		c.callOpts = bind.CallOpts{Pending: false, From: common.Address{}, BlockNumber: nil, Context: context.Background()}
 	}
@@ -131,14 +144,25 @@ func (c *Connection) connect() error {
 }
 
 func (c *Connection) connectEth1() (err error) {
-	c.eth1Client, err = NewEth1Client(lo.Map(c.endpoints, func(e config.Endpoint, i int) string { return e.Eth1 }))
+	endpoints := lo.Map(c.endpoints, func(e config.Endpoint, i int) string { return e.Eth1 })
+	pool := NewEndpointPool(metrics.Eth1, endpoints)
+	for i, e := range c.endpoints {
+		pool.SetRateLimit(i, e.Eth1RateLimit)
+	}
+	c.eth1Client, err = newEth1Client(endpoints, lo.Map(c.endpoints, func(e config.Endpoint, i int) config.EndpointOptions { return e.Eth1Options }), pool)
 	return
 }
 
 func (c *Connection) connectEth2(chainId *big.Int) error {
+	c.eth2Pool = NewEndpointPool(metrics.Eth2, lo.Map(c.endpoints, func(e config.Endpoint, i int) string { return e.Eth2 }))
 	c.eth2Clients = make([]*eth2Client, 0, len(c.endpoints))
-	for _, e := range c.endpoints {
-		stdClient, err := client.NewStandardHttpClient(e.Eth2, chainId)
+	for i, e := range c.endpoints {
+		c.eth2Pool.SetRateLimit(i, e.Eth2RateLimit)
+		httpClient, err := newEndpointHttpClient(e.Eth2Options, c.eth2Pool, i)
+		if err != nil {
+			return err
+		}
+		stdClient, err := client.NewStandardHttpClientWithHttpClient(e.Eth2, chainId, httpClient)
 		if err != nil {
 			return err
 		}
@@ -150,11 +174,13 @@ func (c *Connection) connectEth2(chainId *big.Int) error {
 		client := eth2Client{
 			StandardHttpClient: stdClient,
 			endpoint:           e.Eth2,
+			index:              i,
 			config:             config,
 		}
 		checkEth2Health(&client)
 		c.eth2Clients = append(c.eth2Clients, &client)
 	}
+	c.updateEth2HeadLags()
 
 	utils.SafeGoWithRestart(func() {
 		for {
@@ -162,12 +188,41 @@ func (c *Connection) connectEth2(chainId *big.Int) error {
 			for i := range c.eth2Clients {
 				checkEth2Health(c.eth2Clients[i])
 			}
+			c.updateEth2HeadLags()
 		}
 	})
+	utils.SafeGoWithRestart(c.eth2Pool.logScores)
 
 	return nil
 }
 
+// updateEth2HeadLags sets the slots each endpoint lags behind the highest head slot
+func (c *Connection) updateEth2HeadLags() {
+	highest := uint64(0)
+	for _, client := range c.eth2Clients {
+		if client.healthCheckError == nil && client.latestBeaconHead.Slot > highest {
+			highest = client.latestBeaconHead.Slot
+		}
+	}
+	for _, client := range c.eth2Clients {
+		if client.healthCheckError == nil {
+			c.eth2Pool.SetHeadLag(client.index, highest-client.latestBeaconHead.Slot)
+		}
+	}
+}
+
+// ConfigureEndpointPools sets the circuit breaker of both the eth1 and eth2 endpoint pools
+func (c *Connection) ConfigureEndpointPools(cfg EndpointPoolConfig) error {
+	if cfg.FailureThreshold < 1 {
+		return fmt.Errorf("endpoint pool failure threshold must be positive")
+	}
+	if eth1Client, ok := c.eth1Client.(*Eth1Client); ok {
+		eth1Client.Pool().SetConfig(cfg)
+	}
+	c.eth2Pool.SetConfig(cfg)
+	return nil
+}
+
 func (c *Connection) getHealthyEth2Clients() ([]*eth2Client, error) {
 	clients := make([]*eth2Client, 0, len(c.eth2Clients))
 	errMsgs := make([]string, 0, len(c.eth2Clients))
@@ -184,9 +239,16 @@ func (c *Connection) getHealthyEth2Clients() ([]*eth2Client, error) {
 		clients = append(clients, client)
 	}
 	if len(clients) == 0 {
-		return nil, fmt.Errorf("all eth2 endpoints are out of sync: %s", strings.Join(errMsgs, ";"))
+		err := fmt.Errorf("all eth2 endpoints are out of sync: %s", strings.Join(errMsgs, ";"))
+		notify.Notify(notify.Alert{
+			Key:      "eth2-out-of-sync",
+			Severity: notify.SeverityCritical,
+			Title:    "all eth2 endpoints are out of sync",
+			Message:  err.Error(),
+		})
+		return nil, err
 	}
-	return clients, nil
+	return order(c.eth2Pool, clients, eth2Index), nil
 }
 
 func checkEth2Health(client *eth2Client) {
@@ -207,45 +269,92 @@ func checkEth2Health(client *eth2Client) {
 	client.lastCheckedAt = time.Now()
 }
 
-// newTransactOpts builds the TransactOpts for the connection's keypair.
-func (c *Connection) newTransactOpts(value, gasLimit *big.Int) (*bind.TransactOpts, error) {
-	privateKey := c.kp.PrivateKey()
-	address := ethcrypto.PubkeyToAddress(privateKey.PublicKey)
+// newSignerTransactor is bind.NewKeyedTransactorWithChainID with the signing delegated to signer
+func newSignerTransactor(signer Signer, chainId *big.Int) *bind.TransactOpts {
+	return &bind.TransactOpts{
+		From: signer.Address(),
+		Signer: func(address common.Address, tx *ethtypes.Transaction) (*ethtypes.Transaction, error) {
+			if address != signer.Address() {
+				return nil, bind.ErrNotAuthorized
+			}
+			return signer.SignTx(context.Background(), tx, chainId)
+		},
+		Context: context.Background(),
+	}
+}
 
-	nonce, err := c.eth1Client.PendingNonceAt(context.Background(), address)
-	if err != nil {
-		return nil, err
+// EnableTxManager makes the txs of the signer go through a TxManager, the max gas price of the connection
+// is used if cfg.MaxGasPrice is nil
+func (c *Connection) EnableTxManager(cfg TxManagerConfig) error {
+	if c.signer == nil {
+		return fmt.Errorf("tx manager needs a signer")
 	}
 	chainId, err := c.eth1Client.ChainID(context.Background())
 	if err != nil {
-		return nil, err
+		return err
+	}
+	if cfg.MaxGasPrice == nil {
+		cfg.MaxGasPrice = c.maxGasPrice
+	}
+	txManager, err := NewTxManager(c.eth1Client, c.signer, chainId, c.SafeEstimateFee, cfg)
+	if err != nil {
+		return err
 	}
+	txManager.Start()
+	c.txManager = txManager
+	return nil
+}
 
-	auth, err := bind.NewKeyedTransactorWithChainID(privateKey, chainId)
+// EnableQuorum verifies the critical reads by q: validator statuses at an epoch or slot, beacon blocks,
+// BatchBalancesAtBlocks and the multicalls of MultiCaller at a fixed block
+func (c *Connection) EnableQuorum(q Quorum) error {
+	if !q.Enabled() {
+		return nil
+	}
+	if q.Size > len(c.endpoints) {
+		return fmt.Errorf("quorum size %d is greater than the number of endpoints %d", q.Size, len(c.endpoints))
+	}
+	if q.Threshold <= q.Size/2 || q.Threshold > q.Size {
+		return fmt.Errorf("quorum threshold %d must be a majority of size %d", q.Threshold, q.Size)
+	}
+	eth1Client, ok := c.eth1Client.(*Eth1Client)
+	if !ok {
+		return fmt.Errorf("quorum is not supported by the eth1 client")
+	}
+	verifiedMultiCaller, err := multicall.New(eth1Client.VerifiedCaller())
 	if err != nil {
-		return nil, err
+		return err
 	}
-	auth.Nonce = big.NewInt(int64(nonce))
-	auth.Value = value
-	auth.GasLimit = uint64(gasLimit.Int64())
-	auth.Context = context.Background()
+	eth1Client.SetQuorum(q)
+	c.quorum = q
+	c.verifiedMultiCaller = verifiedMultiCaller
+	return nil
+}
 
-	return auth, nil
+// TxManager returns nil if not enabled
+func (c *Connection) TxManager() *TxManager {
+	return c.txManager
 }
 
-func (c *Connection) Keypair() *secp256k1.Keypair {
-	return c.kp
+// GasLimit of the txs sent by the signer
+func (c *Connection) GasLimit() uint64 {
+	return c.gasLimit.Uint64()
 }
 
-func (c *Connection) Eth1Client() ContractBackend {
-	return c.eth1Client
+// Signer returns nil for a read-only connection
+func (c *Connection) Signer() Signer {
+	return c.signer
 }
 
-func (c *Connection) TxOpts() *bind.TransactOpts {
-	return c.txOpts
+func (c *Connection) Eth1Client() ContractBackend {
+	return c.eth1Client
 }
 
+// MultiCaller is verified by the quorum if enabled
 func (c *Connection) MultiCaller() *multicall.Caller {
+	if c.verifiedMultiCaller != nil {
+		return c.verifiedMultiCaller
+	}
 	return c.multiCaller
 }
 
@@ -280,6 +389,7 @@ func (c *Connection) SafeEstimateFee(ctx context.Context) (*big.Int, *big.Int, e
 
 	gasFeeCap, _ := new(big.Float).Mul(new(big.Float).SetInt(marketGasFeeCap), c.gasPriceMultiplier).Int(nil)
 	gasTipCap, _ := new(big.Float).Mul(new(big.Float).SetInt(marketGasTipCap), c.gasPriceMultiplier).Int(nil)
+	metrics.SetGasPrice(gasTipCap, gasFeeCap)
 
 	if gasFeeCap.Cmp(c.maxGasPrice) > 0 {
 		return nil, nil, &GasPriceError{Current: gasFeeCap, Max: c.maxGasPrice}
@@ -288,37 +398,6 @@ func (c *Connection) SafeEstimateFee(ctx context.Context) (*big.Int, *big.Int, e
 	return gasTipCap, gasFeeCap, nil
 }
 
-// LockAndUpdateOpts acquires a lock on the opts before updating the nonce
-// and gas price.
-func (c *Connection) LockAndUpdateTxOpts() error {
-	c.optsLock.Lock()
-
-	var err error
-	defer func() {
-		if err != nil {
-			c.optsLock.Unlock()
-		}
-	}()
-
-	gasTipCap, gasFeeCap, err := c.SafeEstimateFee(context.Background())
-	if err != nil {
-		return err
-	}
-	c.txOpts.GasTipCap = gasTipCap
-	c.txOpts.GasFeeCap = gasFeeCap
-
-	nonce, err := c.eth1Client.NonceAt(context.Background(), c.txOpts.From, nil)
-	if err != nil {
-		return err
-	}
-	c.txOpts.Nonce.SetUint64(nonce)
-	return nil
-}
-
-func (c *Connection) UnlockTxOpts() {
-	c.optsLock.Unlock()
-}
-
 // LatestBlock returns the latest block from the current chain
 func (c *Connection) Eth1LatestBlock() (uint64, error) {
 	header, err := c.eth1Client.BlockNumber(context.Background())
@@ -334,6 +413,13 @@ func (c *Connection) GetValidatorStatus(ctx context.Context, pubkey types.Valida
 	if err != nil {
 		return
 	}
+	if c.quorum.Enabled() && pinnedStatusOptions(opts) {
+		return quorumRead(c.quorum, "GetValidatorStatus", clients, eth2Endpoint,
+			func(client *eth2Client) (beacon.ValidatorStatus, error) {
+				return client.GetValidatorStatus(ctx, pubkey, opts)
+			},
+			func(status beacon.ValidatorStatus) any { return status })
+	}
 
 	for _, client := range clients {
 		validatorStatus, err = client.GetValidatorStatus(ctx, pubkey, opts)
@@ -350,6 +436,20 @@ func (c *Connection) GetValidatorStatuses(ctx context.Context, pubkeys []types.V
 	if err != nil {
 		return
 	}
+	if c.quorum.Enabled() && pinnedStatusOptions(opts) {
+		return quorumRead(c.quorum, "GetValidatorStatuses", clients, eth2Endpoint,
+			func(client *eth2Client) (map[types.ValidatorPubkey]beacon.ValidatorStatus, error) {
+				return client.GetValidatorStatuses(ctx, pubkeys, opts)
+			},
+			func(statuses map[types.ValidatorPubkey]beacon.ValidatorStatus) any {
+				// json needs string keys
+				byHex := make(map[string]beacon.ValidatorStatus, len(statuses))
+				for pubkey, status := range statuses {
+					byHex[pubkey.Hex()] = status
+				}
+				return byHex
+			})
+	}
 
 	for _, client := range clients {
 		validatorStatus, err = client.GetValidatorStatuses(ctx, pubkeys, opts)
@@ -360,12 +460,52 @@ func (c *Connection) GetValidatorStatuses(ctx context.Context, pubkeys []types.V
 	return
 }
 
+func (c *Connection) GetValidatorStatusesByIndices(ctx context.Context, indices []uint64, opts *beacon.ValidatorStatusOptions) (validatorStatus map[uint64]beacon.ValidatorStatus, err error) {
+	var clients []*eth2Client
+	clients, err = c.getHealthyEth2Clients()
+	if err != nil {
+		return
+	}
+	if c.quorum.Enabled() && pinnedStatusOptions(opts) {
+		return quorumRead(c.quorum, "GetValidatorStatusesByIndices", clients, eth2Endpoint,
+			func(client *eth2Client) (map[uint64]beacon.ValidatorStatus, error) {
+				return client.GetValidatorStatusesByIndices(ctx, indices, opts)
+			},
+			func(statuses map[uint64]beacon.ValidatorStatus) any { return statuses })
+	}
+
+	for _, client := range clients {
+		validatorStatus, err = client.GetValidatorStatusesByIndices(ctx, indices, opts)
+		if err == nil {
+			return
+		}
+	}
+	return
+}
+
 func (c *Connection) GetBeaconBlock(blockId uint64) (block beacon.BeaconBlock, exist bool, err error) {
 	var clients []*eth2Client
 	clients, err = c.getHealthyEth2Clients()
 	if err != nil {
 		return
 	}
+	if c.quorum.Enabled() {
+		type blockOrMissing struct {
+			block beacon.BeaconBlock
+			exist bool
+		}
+		var answer blockOrMissing
+		answer, err = quorumRead(c.quorum, "GetBeaconBlock", clients, eth2Endpoint,
+			func(client *eth2Client) (blockOrMissing, error) {
+				block, exist, err := client.GetBeaconBlock(blockId)
+				return blockOrMissing{block: block, exist: exist}, err
+			},
+			// only the fields the relay relies on are compared
+			func(answer blockOrMissing) any {
+				return []any{answer.exist, answer.block.ExecutionBlockNumber, answer.block.ProposerIndex, answer.block.Withdrawals}
+			})
+		return answer.block, answer.exist, err
+	}
 
 	for _, client := range clients {
 		block, exist, err = client.GetBeaconBlock(blockId)
@@ -376,6 +516,19 @@ func (c *Connection) GetBeaconBlock(blockId uint64) (block beacon.BeaconBlock, e
 	return
 }
 
+// pinnedStatusOptions reports whether the statuses are read at a fixed epoch or slot, reads of the head may differ across endpoints
+func pinnedStatusOptions(opts *beacon.ValidatorStatusOptions) bool {
+	return opts != nil && (opts.Epoch != nil || opts.Slot != nil)
+}
+
+func eth2Endpoint(client *eth2Client) string {
+	return client.endpoint
+}
+
+func eth2Index(client *eth2Client) int {
+	return client.index
+}
+
 func (c *Connection) GetEth2Config() (cfg beacon.Eth2Config, err error) {
 	var clients []*eth2Client
 	clients, err = c.getHealthyEth2Clients()
@@ -392,6 +545,15 @@ func (c *Connection) GetEth2Config() (cfg beacon.Eth2Config, err error) {
 	return
 }
 
+// SubscribeBeaconEvents streams events of topics from the first healthy eth2 endpoint, see StandardHttpClient.SubscribeEvents
+func (c *Connection) SubscribeBeaconEvents(ctx context.Context, topics []string, handler func(beacon.Event)) error {
+	clients, err := c.getHealthyEth2Clients()
+	if err != nil {
+		return err
+	}
+	return clients[0].SubscribeEvents(ctx, topics, handler)
+}
+
 func (c *Connection) GetBeaconHead() (head beacon.BeaconHead, err error) {
 	var clients []*eth2Client
 	clients, err = c.getHealthyEth2Clients()
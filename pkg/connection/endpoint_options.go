@@ -0,0 +1,229 @@
+package connection
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/gorilla/websocket"
+	"github.com/stafiprotocol/eth-lsd-relay/pkg/config"
+)
+
+// execution clients accept tokens issued within 60 seconds
+const jwtTokenRefreshInterval = 30 * time.Second
+
+// endpointAuth returns the func setting the headers, basic auth and jwt token of opts on a request, nil if none is configured
+func endpointAuth(opts config.EndpointOptions) (rpc.HTTPAuth, error) {
+	var jwtToken *jwtAuth
+	if opts.JwtSecretFile != "" {
+		var err error
+		if jwtToken, err = newJwtAuth(opts.JwtSecretFile); err != nil {
+			return nil, err
+		}
+	}
+	basicAuth := ""
+	if opts.BasicAuthUser != "" || opts.BasicAuthPassword != "" {
+		basicAuth = "Basic " + base64.StdEncoding.EncodeToString([]byte(opts.BasicAuthUser+":"+opts.BasicAuthPassword))
+	}
+	if len(opts.Headers) == 0 && jwtToken == nil && basicAuth == "" {
+		return nil, nil
+	}
+
+	return func(h http.Header) error {
+		for key, value := range opts.Headers {
+			h.Set(key, value)
+		}
+		if basicAuth != "" {
+			h.Set("Authorization", basicAuth)
+		}
+		if jwtToken != nil {
+			token, err := jwtToken.token()
+			if err != nil {
+				return err
+			}
+			h.Set("Authorization", "Bearer "+token)
+		}
+		return nil
+	}, nil
+}
+
+// endpointTLSConfig returns nil if opts uses neither a custom CA nor a client certificate
+func endpointTLSConfig(opts config.EndpointOptions) (*tls.Config, error) {
+	if opts.CaFile == "" && opts.CertFile == "" {
+		return nil, nil
+	}
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if opts.CaFile != "" {
+		pem, err := os.ReadFile(opts.CaFile)
+		if err != nil {
+			return nil, fmt.Errorf("read ca file: %w", err)
+		}
+		rootCAs, err := x509.SystemCertPool()
+		if err != nil {
+			rootCAs = x509.NewCertPool()
+		}
+		if !rootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificate found in ca file %s", opts.CaFile)
+		}
+		tlsConfig.RootCAs = rootCAs
+	}
+	if opts.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(opts.CertFile, opts.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
+
+func endpointProxy(opts config.EndpointOptions) (func(*http.Request) (*url.URL, error), error) {
+	if opts.Proxy == "" {
+		return http.ProxyFromEnvironment, nil
+	}
+	proxyUrl, err := url.Parse(opts.Proxy)
+	if err != nil {
+		return nil, fmt.Errorf("parse proxy: %w", err)
+	}
+	return http.ProxyURL(proxyUrl), nil
+}
+
+// newEndpointHttpClient returns the client of pool endpoint i, sending requests as configured by opts
+func newEndpointHttpClient(opts config.EndpointOptions, pool *EndpointPool, i int) (*http.Client, error) {
+	tlsConfig, err := endpointTLSConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+	proxy, err := endpointProxy(opts)
+	if err != nil {
+		return nil, err
+	}
+	auth, err := endpointAuth(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = proxy
+	if tlsConfig != nil {
+		transport.TLSClientConfig = tlsConfig
+	}
+	var roundTripper http.RoundTripper = pool.Transport(i, transport)
+	if auth != nil {
+		roundTripper = &authTransport{auth: auth, base: roundTripper}
+	}
+	return &http.Client{
+		Transport: roundTripper,
+		Timeout:   time.Duration(opts.TimeoutSeconds) * time.Second,
+	}, nil
+}
+
+// dialEth1Websocket dials a ws endpoint as configured by opts, the auth headers are sent on handshakes
+func dialEth1Websocket(endpoint, scheme string, opts config.EndpointOptions) (*rpc.Client, error) {
+	tlsConfig, err := endpointTLSConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+	proxy, err := endpointProxy(opts)
+	if err != nil {
+		return nil, err
+	}
+	auth, err := endpointAuth(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	options := []rpc.ClientOption{
+		rpc.WithHeader("Origin", fmt.Sprintf("/%s", scheme)),
+		rpc.WithWebsocketDialer(websocket.Dialer{
+			Proxy:            proxy,
+			TLSClientConfig:  tlsConfig,
+			HandshakeTimeout: time.Duration(opts.TimeoutSeconds) * time.Second,
+		}),
+	}
+	if auth != nil {
+		options = append(options, rpc.WithHTTPAuth(auth))
+	}
+	return rpc.DialOptions(context.Background(), endpoint, options...)
+}
+
+type authTransport struct {
+	auth rpc.HTTPAuth
+	base http.RoundTripper
+}
+
+func (t *authTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// a RoundTripper must not modify the request
+	req = req.Clone(req.Context())
+	if err := t.auth(req.Header); err != nil {
+		return nil, err
+	}
+	return t.base.RoundTrip(req)
+}
+
+// jwtAuth signs HS256 tokens with the secret of an engine api style jwt secret file, the token is reused until
+// jwtTokenRefreshInterval passes and the file is read again on refresh so a rotated secret is picked up
+type jwtAuth struct {
+	secretFile string
+
+	mutex    sync.Mutex
+	signed   string
+	issuedAt time.Time
+}
+
+func newJwtAuth(secretFile string) (*jwtAuth, error) {
+	a := &jwtAuth{secretFile: secretFile}
+	if _, err := a.token(); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+func (a *jwtAuth) token() (string, error) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	now := time.Now()
+	if a.signed != "" && now.Sub(a.issuedAt) < jwtTokenRefreshInterval {
+		return a.signed, nil
+	}
+	secret, err := readJwtSecret(a.secretFile)
+	if err != nil {
+		return "", err
+	}
+	signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"iat": &jwt.NumericDate{Time: now},
+	}).SignedString(secret)
+	if err != nil {
+		return "", fmt.Errorf("sign jwt token: %w", err)
+	}
+	a.signed = signed
+	a.issuedAt = now
+	return signed, nil
+}
+
+func readJwtSecret(secretFile string) ([]byte, error) {
+	content, err := os.ReadFile(secretFile)
+	if err != nil {
+		return nil, fmt.Errorf("read jwt secret file: %w", err)
+	}
+	secret, err := hex.DecodeString(strings.TrimPrefix(strings.TrimSpace(string(content)), "0x"))
+	if err != nil {
+		return nil, fmt.Errorf("jwt secret file %s is not hex encoded: %w", secretFile, err)
+	}
+	if len(secret) != 32 {
+		return nil, fmt.Errorf("jwt secret of file %s must be 32 bytes, got %d", secretFile, len(secret))
+	}
+	return secret, nil
+}
@@ -0,0 +1,90 @@
+package connection
+
+import (
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stafiprotocol/eth-lsd-relay/pkg/config"
+	"github.com/stretchr/testify/assert"
+)
+
+const testJwtSecret = "0x7365637265747365637265747365637265747365637265747365637265747365"
+
+func TestNewEndpointHttpClient(t *testing.T) {
+	requests := make(chan *http.Request, 1)
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests <- r
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	caFile := filepath.Join(dir, "ca.pem")
+	err := os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}), 0600)
+	assert.Nil(t, err)
+	jwtSecretFile := filepath.Join(dir, "jwt.hex")
+	assert.Nil(t, os.WriteFile(jwtSecretFile, []byte(testJwtSecret+"\n"), 0600))
+
+	pool := NewEndpointPool("eth2", []string{server.URL})
+
+	// the server certificate is not trusted without the ca file
+	client, err := newEndpointHttpClient(config.EndpointOptions{}, pool, 0)
+	assert.Nil(t, err)
+	_, err = client.Get(server.URL)
+	assert.ErrorContains(t, err, "certificate")
+
+	client, err = newEndpointHttpClient(config.EndpointOptions{
+		Headers:       map[string]string{"X-Api-Key": "key"},
+		JwtSecretFile: jwtSecretFile,
+		CaFile:        caFile,
+	}, pool, 0)
+	assert.Nil(t, err)
+	response, err := client.Get(server.URL)
+	assert.Nil(t, err)
+	response.Body.Close()
+	r := <-requests
+	assert.Equal(t, "key", r.Header.Get("X-Api-Key"))
+	token, err := jwt.Parse(r.Header.Get("Authorization")[len("Bearer "):], func(*jwt.Token) (interface{}, error) {
+		return readJwtSecret(jwtSecretFile)
+	})
+	assert.Nil(t, err)
+	assert.True(t, token.Valid)
+	assert.Equal(t, uint64(2), pool.Scores()[0].Requests)
+
+	client, err = newEndpointHttpClient(config.EndpointOptions{BasicAuthUser: "user", BasicAuthPassword: "password", CaFile: caFile}, pool, 0)
+	assert.Nil(t, err)
+	response, err = client.Get(server.URL)
+	assert.Nil(t, err)
+	response.Body.Close()
+	user, password, ok := (<-requests).BasicAuth()
+	assert.True(t, ok)
+	assert.Equal(t, "user", user)
+	assert.Equal(t, "password", password)
+
+	_, err = newEndpointHttpClient(config.EndpointOptions{JwtSecretFile: caFile}, pool, 0)
+	assert.ErrorContains(t, err, "not hex encoded")
+}
+
+func TestJwtAuthRefresh(t *testing.T) {
+	jwtSecretFile := filepath.Join(t.TempDir(), "jwt.hex")
+	assert.Nil(t, os.WriteFile(jwtSecretFile, []byte(testJwtSecret), 0600))
+
+	auth, err := newJwtAuth(jwtSecretFile)
+	assert.Nil(t, err)
+	first, err := auth.token()
+	assert.Nil(t, err)
+	second, err := auth.token()
+	assert.Nil(t, err)
+	assert.Equal(t, first, second)
+
+	// an expired token is signed again
+	auth.issuedAt = time.Now().Add(-jwtTokenRefreshInterval - time.Second)
+	_, err = auth.token()
+	assert.Nil(t, err)
+	assert.WithinDuration(t, time.Now(), auth.issuedAt, time.Second)
+}
@@ -0,0 +1,313 @@
+package connection
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stafiprotocol/eth-lsd-relay/pkg/metrics"
+	"github.com/stafiprotocol/eth-lsd-relay/pkg/utils"
+	"golang.org/x/time/rate"
+)
+
+const (
+	// weight of the latest sample in the latency and error rate averages
+	scoreDecay = 0.2
+	// a failing endpoint is scored as if it were this many times slower
+	errorRatePenalty = 10
+	// each block or slot an endpoint lags behind the best one costs as much as this latency
+	headLagPenalty = time.Second
+
+	scoresLogInterval = 10 * time.Minute
+)
+
+// circuit states of an endpoint
+const (
+	CircuitClosed   = "closed"
+	CircuitOpen     = "open"
+	CircuitHalfOpen = "half-open"
+)
+
+// EndpointPoolConfig configs the circuit breaker of an EndpointPool
+type EndpointPoolConfig struct {
+	FailureThreshold int           // consecutive failures that open the circuit of an endpoint
+	OpenTimeout      time.Duration // an open circuit turns half-open after it
+}
+
+func DefaultEndpointPoolConfig() EndpointPoolConfig {
+	return EndpointPoolConfig{
+		FailureThreshold: 5,
+		OpenTimeout:      30 * time.Second,
+	}
+}
+
+// EndpointPool scores the endpoints of one type from the requests sent to them, requests are tried
+// in the order of score instead of config order. The circuit of an endpoint opens after consecutive
+// failures and it is not used until half-open, then it is tried after the others until a request,
+// usually the periodic health check, succeeds again
+type EndpointPool struct {
+	endpointType string
+	endpoints    []*pooledEndpoint
+
+	cfgMutex sync.RWMutex
+	cfg      EndpointPoolConfig
+}
+
+type pooledEndpoint struct {
+	endpoint string
+	limiter  *rate.Limiter // nil if unlimited
+
+	mutex               sync.Mutex
+	latency             time.Duration
+	errorRate           float64
+	headLag             uint64
+	requests            uint64
+	consecutiveFailures int
+	openedAt            time.Time // zero if the circuit is closed
+}
+
+// EndpointScore is a snapshot of the stats of a pooled endpoint, a lower score is better
+type EndpointScore struct {
+	Latency             time.Duration `json:"-"`
+	LatencyMs           int64         `json:"latencyMs"`
+	ErrorRate           float64       `json:"errorRate"`
+	HeadLag             uint64        `json:"headLag"` // blocks for eth1, slots for eth2
+	Requests            uint64        `json:"requests"`
+	ConsecutiveFailures int           `json:"consecutiveFailures"`
+	Circuit             string        `json:"circuit"`
+	Score               float64       `json:"score"`
+}
+
+func NewEndpointPool(endpointType string, endpoints []string) *EndpointPool {
+	pool := &EndpointPool{
+		endpointType: endpointType,
+		endpoints:    make([]*pooledEndpoint, len(endpoints)),
+		cfg:          DefaultEndpointPoolConfig(),
+	}
+	for i, endpoint := range endpoints {
+		pool.endpoints[i] = &pooledEndpoint{endpoint: endpoint}
+	}
+	return pool
+}
+
+func (p *EndpointPool) SetConfig(cfg EndpointPoolConfig) {
+	p.cfgMutex.Lock()
+	defer p.cfgMutex.Unlock()
+	p.cfg = cfg
+}
+
+func (p *EndpointPool) config() EndpointPoolConfig {
+	p.cfgMutex.RLock()
+	defer p.cfgMutex.RUnlock()
+	return p.cfg
+}
+
+// SetRateLimit limits the requests sent through Transport to endpoint i, unlimited if requestsPerSecond is not positive.
+// Bursts up to one second of requests are allowed
+func (p *EndpointPool) SetRateLimit(i int, requestsPerSecond float64) {
+	if requestsPerSecond <= 0 {
+		p.endpoints[i].limiter = nil
+		return
+	}
+	burst := int(requestsPerSecond)
+	if burst < 1 {
+		burst = 1
+	}
+	p.endpoints[i].limiter = rate.NewLimiter(rate.Limit(requestsPerSecond), burst)
+}
+
+// Report records the result of a request to endpoint i
+func (p *EndpointPool) Report(i int, latency time.Duration, failed bool) {
+	e := p.endpoints[i]
+	cfg := p.config()
+
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	failure := 0.0
+	if failed {
+		failure = 1
+	}
+	if e.requests == 0 {
+		e.latency = latency
+		e.errorRate = failure
+	} else {
+		e.latency = time.Duration((1-scoreDecay)*float64(e.latency) + scoreDecay*float64(latency))
+		e.errorRate = (1-scoreDecay)*e.errorRate + scoreDecay*failure
+	}
+	e.requests++
+
+	if !failed {
+		if !e.openedAt.IsZero() {
+			logrus.WithFields(logrus.Fields{
+				"type":     p.endpointType,
+				"endpoint": utils.RedactUrl(e.endpoint),
+			}).Info("endpoint circuit closed")
+		}
+		e.consecutiveFailures = 0
+		e.openedAt = time.Time{}
+		return
+	}
+
+	e.consecutiveFailures++
+	now := time.Now()
+	switch e.circuit(now, cfg) {
+	case CircuitClosed:
+		if e.consecutiveFailures >= cfg.FailureThreshold {
+			e.openedAt = now
+			logrus.WithFields(logrus.Fields{
+				"type":     p.endpointType,
+				"endpoint": utils.RedactUrl(e.endpoint),
+				"failures": e.consecutiveFailures,
+			}).Warn("endpoint circuit opened")
+		}
+	case CircuitHalfOpen:
+		// the probe failed
+		e.openedAt = now
+	}
+}
+
+// SetHeadLag records how far endpoint i lags behind the best endpoint, set by the periodic health checks
+func (p *EndpointPool) SetHeadLag(i int, lag uint64) {
+	e := p.endpoints[i]
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.headLag = lag
+}
+
+// Transport wraps base to report every request to endpoint i and to wait for its rate limit
+func (p *EndpointPool) Transport(i int, base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &pooledTransport{pool: p, index: i, base: base}
+}
+
+// Scores returns the stats of the endpoints in config order
+func (p *EndpointPool) Scores() []EndpointScore {
+	cfg := p.config()
+	now := time.Now()
+	scores := make([]EndpointScore, len(p.endpoints))
+	for i, e := range p.endpoints {
+		scores[i] = e.score(now, cfg)
+	}
+	return scores
+}
+
+// order sorts clients by circuit state then score, clients of open circuits are dropped unless all of them are open.
+// The sort is stable so clients of equal scores keep the config order
+func order[C any](p *EndpointPool, clients []C, index func(C) int) []C {
+	if p == nil || len(clients) < 2 {
+		return clients
+	}
+	cfg := p.config()
+	now := time.Now()
+
+	type ranked struct {
+		client C
+		rank   int
+		score  float64
+	}
+	ranks := map[string]int{CircuitClosed: 0, CircuitHalfOpen: 1, CircuitOpen: 2}
+	rankeds := make([]ranked, len(clients))
+	allOpen := true
+	for i, client := range clients {
+		score := p.endpoints[index(client)].score(now, cfg)
+		rankeds[i] = ranked{client: client, rank: ranks[score.Circuit], score: score.Score}
+		if score.Circuit != CircuitOpen {
+			allOpen = false
+		}
+	}
+	sort.SliceStable(rankeds, func(i, j int) bool {
+		if rankeds[i].rank != rankeds[j].rank {
+			return rankeds[i].rank < rankeds[j].rank
+		}
+		return rankeds[i].score < rankeds[j].score
+	})
+
+	ordered := make([]C, 0, len(clients))
+	for _, r := range rankeds {
+		if r.rank == ranks[CircuitOpen] && !allOpen {
+			continue
+		}
+		ordered = append(ordered, r.client)
+	}
+	return ordered
+}
+
+// logScores logs and exports the scores of the endpoints periodically
+func (p *EndpointPool) logScores() {
+	for {
+		for i, score := range p.Scores() {
+			endpoint := p.endpoints[i].endpoint
+			metrics.SetEndpointScore(p.endpointType, endpoint, score.Score, score.Circuit != CircuitClosed)
+			logrus.WithFields(logrus.Fields{
+				"type":      p.endpointType,
+				"endpoint":  utils.RedactUrl(endpoint),
+				"latencyMs": score.LatencyMs,
+				"errorRate": fmt.Sprintf("%.3f", score.ErrorRate),
+				"headLag":   score.HeadLag,
+				"requests":  score.Requests,
+				"circuit":   score.Circuit,
+				"score":     fmt.Sprintf("%.1f", score.Score),
+			}).Info("endpoint score")
+		}
+		time.Sleep(scoresLogInterval)
+	}
+}
+
+// circuit must be called with the mutex held
+func (e *pooledEndpoint) circuit(now time.Time, cfg EndpointPoolConfig) string {
+	switch {
+	case e.openedAt.IsZero():
+		return CircuitClosed
+	case now.Sub(e.openedAt) >= cfg.OpenTimeout:
+		return CircuitHalfOpen
+	default:
+		return CircuitOpen
+	}
+}
+
+// score is the latency in milliseconds weighted by the error rate, plus the penalty of head lag
+func (e *pooledEndpoint) score(now time.Time, cfg EndpointPoolConfig) EndpointScore {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	latencyMs := float64(e.latency) / float64(time.Millisecond)
+	lagMs := float64(e.headLag) * float64(headLagPenalty) / float64(time.Millisecond)
+	return EndpointScore{
+		Latency:             e.latency,
+		LatencyMs:           e.latency.Milliseconds(),
+		ErrorRate:           e.errorRate,
+		HeadLag:             e.headLag,
+		Requests:            e.requests,
+		ConsecutiveFailures: e.consecutiveFailures,
+		Circuit:             e.circuit(now, cfg),
+		Score:               latencyMs*(1+errorRatePenalty*e.errorRate) + lagMs,
+	}
+}
+
+type pooledTransport struct {
+	pool  *EndpointPool
+	index int
+	base  http.RoundTripper
+}
+
+// RoundTrip counts transport errors, server errors and throttling as failures of the endpoint,
+// other statuses are answers of a working endpoint
+func (t *pooledTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if limiter := t.pool.endpoints[t.index].limiter; limiter != nil {
+		if err := limiter.Wait(req.Context()); err != nil {
+			return nil, fmt.Errorf("rate limit of endpoint: %w", err)
+		}
+	}
+
+	startAt := time.Now()
+	response, err := t.base.RoundTrip(req)
+	failed := err != nil || response.StatusCode >= http.StatusInternalServerError || response.StatusCode == http.StatusTooManyRequests
+	t.pool.Report(t.index, time.Since(startAt), failed)
+	return response, err
+}
@@ -0,0 +1,110 @@
+package connection
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func orderedEndpoints(p *EndpointPool) []string {
+	indexes := make([]int, len(p.endpoints))
+	for i := range indexes {
+		indexes[i] = i
+	}
+	endpoints := make([]string, 0, len(indexes))
+	for _, i := range order(p, indexes, func(i int) int { return i }) {
+		endpoints = append(endpoints, p.endpoints[i].endpoint)
+	}
+	return endpoints
+}
+
+func TestEndpointPoolOrder(t *testing.T) {
+	p := NewEndpointPool("eth1", []string{"http://a", "http://b", "http://c"})
+
+	// no requests yet, config order is kept
+	assert.Equal(t, []string{"http://a", "http://b", "http://c"}, orderedEndpoints(p))
+
+	p.Report(0, 300*time.Millisecond, false)
+	p.Report(1, 100*time.Millisecond, false)
+	p.Report(2, 200*time.Millisecond, false)
+	assert.Equal(t, []string{"http://b", "http://c", "http://a"}, orderedEndpoints(p))
+
+	// errors and head lag cost more than latency
+	p.Report(1, 100*time.Millisecond, true)
+	assert.Equal(t, []string{"http://c", "http://a", "http://b"}, orderedEndpoints(p))
+	p.SetHeadLag(2, 3)
+	assert.Equal(t, []string{"http://a", "http://b", "http://c"}, orderedEndpoints(p))
+
+	scores := p.Scores()
+	assert.Equal(t, int64(300), scores[0].LatencyMs)
+	assert.InDelta(t, 0.2, scores[1].ErrorRate, 1e-9)
+	assert.Equal(t, uint64(3), scores[2].HeadLag)
+	assert.Equal(t, uint64(2), scores[1].Requests)
+}
+
+func TestEndpointPoolCircuit(t *testing.T) {
+	p := NewEndpointPool("eth2", []string{"http://a", "http://b"})
+	p.SetConfig(EndpointPoolConfig{FailureThreshold: 3, OpenTimeout: 50 * time.Millisecond})
+
+	p.Report(1, 500*time.Millisecond, false)
+	for i := 0; i < 2; i++ {
+		p.Report(0, time.Millisecond, true)
+	}
+	assert.Equal(t, CircuitClosed, p.Scores()[0].Circuit)
+
+	p.Report(0, time.Millisecond, true)
+	assert.Equal(t, CircuitOpen, p.Scores()[0].Circuit)
+	assert.Equal(t, []string{"http://b"}, orderedEndpoints(p))
+
+	// all open, every endpoint is still tried
+	for i := 0; i < 3; i++ {
+		p.Report(1, time.Millisecond, true)
+	}
+	assert.Equal(t, []string{"http://a", "http://b"}, orderedEndpoints(p))
+	p.Report(1, 500*time.Millisecond, false)
+
+	// half-open is tried after closed ones, a failed probe opens it again
+	time.Sleep(60 * time.Millisecond)
+	assert.Equal(t, CircuitHalfOpen, p.Scores()[0].Circuit)
+	assert.Equal(t, []string{"http://b", "http://a"}, orderedEndpoints(p))
+	p.Report(0, time.Millisecond, true)
+	assert.Equal(t, CircuitOpen, p.Scores()[0].Circuit)
+
+	time.Sleep(60 * time.Millisecond)
+	p.Report(0, time.Millisecond, false)
+	assert.Equal(t, CircuitClosed, p.Scores()[0].Circuit)
+	assert.Equal(t, 0, p.Scores()[0].ConsecutiveFailures)
+}
+
+func TestEndpointPoolTransport(t *testing.T) {
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	p := NewEndpointPool("eth2", []string{server.URL})
+	p.SetRateLimit(0, 20)
+	client := &http.Client{Transport: p.Transport(0, nil)}
+
+	for _, status = range []int{http.StatusOK, http.StatusNotFound, http.StatusTooManyRequests, http.StatusBadGateway} {
+		response, err := client.Get(server.URL)
+		assert.Nil(t, err)
+		response.Body.Close()
+	}
+	score := p.Scores()[0]
+	assert.Equal(t, uint64(4), score.Requests)
+	assert.Equal(t, 2, score.ConsecutiveFailures)
+
+	// a burst of 20 is allowed, then requests wait for tokens
+	startAt := time.Now()
+	for i := 0; i < 25; i++ {
+		response, err := client.Get(server.URL)
+		assert.Nil(t, err)
+		response.Body.Close()
+	}
+	assert.Greater(t, time.Since(startAt), 100*time.Millisecond)
+}
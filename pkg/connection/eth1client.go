@@ -2,8 +2,10 @@ package connection
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"math/big"
+	"net/http"
 	"net/url"
 	"strings"
 	"time"
@@ -17,6 +19,9 @@ import (
 	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/ethereum/go-ethereum/rpc"
 	"github.com/sirupsen/logrus"
+	"github.com/stafiprotocol/eth-lsd-relay/pkg/config"
+	"github.com/stafiprotocol/eth-lsd-relay/pkg/metrics"
+	"github.com/stafiprotocol/eth-lsd-relay/pkg/notify"
 	"github.com/stafiprotocol/eth-lsd-relay/pkg/utils"
 )
 
@@ -27,6 +32,8 @@ type ContractBackend interface {
 	ChainID(ctx context.Context) (*big.Int, error)
 	BlockByNumber(ctx context.Context, number *big.Int) (*types.Block, error)
 	BalanceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (*big.Int, error)
+	TransactionByHash(ctx context.Context, hash common.Hash) (tx *types.Transaction, isPending bool, err error)
+	TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error)
 	WaitTxOkCommon(txHash common.Hash) (blockNumber uint64, err error)
 	Debug_TraceBlockByNumber(ctx context.Context, number *big.Int, tracer Tracer) ([]TxResult, error)
 }
@@ -36,6 +43,9 @@ var _ ContractBackend = &Eth1Client{}
 type underlyingEth1Client struct {
 	*ethclient.Client
 	endpoint string
+	index    int  // in the endpoint pool
+	pooled   bool // requests are reported to the pool by the http transport
+	pool     *EndpointPool
 
 	latestBlock      *types.Block
 	outOfSync        bool
@@ -45,9 +55,21 @@ type underlyingEth1Client struct {
 
 type Eth1Client struct {
 	clients []*underlyingEth1Client
+	pool    *EndpointPool
+	quorum  Quorum
+}
+
+// SetQuorum enables the verify mode of BatchBalancesAtBlocks and VerifiedCaller
+func (c *Eth1Client) SetQuorum(q Quorum) {
+	c.quorum = q
 }
 
 func NewEth1Client(endpoints []string) (*Eth1Client, error) {
+	return newEth1Client(endpoints, make([]config.EndpointOptions, len(endpoints)), NewEndpointPool(metrics.Eth1, endpoints))
+}
+
+// newEth1Client sends the requests of endpoints[i] as configured by options[i] through pool endpoint i
+func newEth1Client(endpoints []string, options []config.EndpointOptions, pool *EndpointPool) (*Eth1Client, error) {
 	if len(endpoints) == 0 {
 		return nil, fmt.Errorf("endpoints can not be empty")
 	}
@@ -56,6 +78,7 @@ func NewEth1Client(endpoints []string) (*Eth1Client, error) {
 	for i, e := range endpoints {
 		var rpcClient *rpc.Client
 		var err error
+		pooled := false
 		// Start http or ws client
 		u, err := url.Parse(e)
 		if err != nil {
@@ -63,9 +86,13 @@ func NewEth1Client(endpoints []string) (*Eth1Client, error) {
 		}
 		switch u.Scheme {
 		case "http", "https":
-			rpcClient, err = rpc.DialHTTP(e)
+			var httpClient *http.Client
+			if httpClient, err = newEndpointHttpClient(options[i], pool, i); err == nil {
+				rpcClient, err = rpc.DialHTTPWithClient(e, httpClient)
+			}
+			pooled = true
 		case "ws", "wss":
-			rpcClient, err = rpc.DialWebsocket(context.Background(), e, fmt.Sprintf("/%s", u.Scheme))
+			rpcClient, err = dialEth1Websocket(e, u.Scheme, options[i])
 		default:
 			err = fmt.Errorf("unsupported scheme: %s", u.Scheme)
 		}
@@ -76,23 +103,52 @@ func NewEth1Client(endpoints []string) (*Eth1Client, error) {
 		client := &underlyingEth1Client{
 			Client:   ethclient.NewClient(rpcClient),
 			endpoint: e,
+			index:    i,
+			pooled:   pooled,
+			pool:     pool,
 		}
 		checkHealth(client)
 		clients[i] = client
 	}
 
+	c := &Eth1Client{
+		clients: clients,
+		pool:    pool,
+	}
+	c.updateHeadLags()
+
 	utils.SafeGoWithRestart(func() {
 		for {
 			time.Sleep(time.Minute)
 			for i := range clients {
 				checkHealth(clients[i])
 			}
+			c.updateHeadLags()
 		}
 	})
+	utils.SafeGoWithRestart(pool.logScores)
+
+	return c, nil
+}
 
-	return &Eth1Client{
-		clients,
-	}, nil
+// Pool scores the endpoints of the client
+func (c *Eth1Client) Pool() *EndpointPool {
+	return c.pool
+}
+
+// updateHeadLags sets the blocks each endpoint lags behind the highest latest block
+func (c *Eth1Client) updateHeadLags() {
+	highest := uint64(0)
+	for _, client := range c.clients {
+		if client.healthCheckError == nil && client.latestBlock.NumberU64() > highest {
+			highest = client.latestBlock.NumberU64()
+		}
+	}
+	for _, client := range c.clients {
+		if client.healthCheckError == nil {
+			c.pool.SetHeadLag(client.index, highest-client.latestBlock.NumberU64())
+		}
+	}
 }
 
 func (c *Eth1Client) getHealthyClients() ([]*underlyingEth1Client, error) {
@@ -111,9 +167,33 @@ func (c *Eth1Client) getHealthyClients() ([]*underlyingEth1Client, error) {
 		clients = append(clients, client)
 	}
 	if len(clients) == 0 {
-		return nil, fmt.Errorf("all eth1 endpoints are out of sync: %s", strings.Join(errMsgs, ";"))
+		err := fmt.Errorf("all eth1 endpoints are out of sync: %s", strings.Join(errMsgs, ";"))
+		notify.Notify(notify.Alert{
+			Key:      "eth1-out-of-sync",
+			Severity: notify.SeverityCritical,
+			Title:    "all eth1 endpoints are out of sync",
+			Message:  err.Error(),
+		})
+		return nil, err
+	}
+	return order(c.pool, clients, func(client *underlyingEth1Client) int { return client.index }), nil
+}
+
+func (c *underlyingEth1Client) BatchCallContext(ctx context.Context, calls []rpc.BatchElem) error {
+	startAt := time.Now()
+	err := c.Client.Client().BatchCallContext(ctx, calls)
+	c.observe("BatchCallContext", startAt, err)
+	return err
+}
+
+// observe records the request in metrics, and in the pool if it is not reported by the transport.
+// Json-rpc errors such as reverts are answers of a working endpoint
+func (c *underlyingEth1Client) observe(method string, startAt time.Time, err error) {
+	metrics.ObserveRpc(metrics.Eth1, c.endpoint, method, startAt, err != nil)
+	if !c.pooled && c.pool != nil {
+		var rpcErr rpc.Error
+		c.pool.Report(c.index, time.Since(startAt), err != nil && !errors.As(err, &rpcErr))
 	}
-	return clients, nil
 }
 
 func checkHealth(client *underlyingEth1Client) {
@@ -146,7 +226,9 @@ func (c *Eth1Client) BalanceAt(ctx context.Context, account common.Address, bloc
 	}
 
 	for _, client := range clients {
+		startAt := time.Now()
 		balance, err = client.BalanceAt(ctx, account, blockNumber)
+		client.observe("BalanceAt", startAt, err)
 		if err == nil {
 			return
 		}
@@ -162,7 +244,9 @@ func (c *Eth1Client) BatchCallContext(ctx context.Context, calls []rpc.BatchElem
 	}
 
 	for _, client := range clients {
+		startAt := time.Now()
 		err = client.Client.Client().BatchCallContext(ctx, calls)
+		client.observe("BatchCallContext", startAt, err)
 		if err == nil {
 			return
 		}
@@ -170,7 +254,57 @@ func (c *Eth1Client) BatchCallContext(ctx context.Context, calls []rpc.BatchElem
 	return
 }
 
+// BatchBalancesAtBlocks is a critical read, it is verified by the quorum if enabled
 func (c *Eth1Client) BatchBalancesAtBlocks(ctx context.Context, address common.Address, blocks []uint64) (map[uint64]*big.Int, error) {
+	if !c.quorum.Enabled() {
+		return batchBalancesAtBlocks(ctx, c, address, blocks)
+	}
+	clients, err := c.getHealthyClients()
+	if err != nil {
+		return nil, err
+	}
+	return quorumRead(c.quorum, "BatchBalancesAtBlocks", clients,
+		func(client *underlyingEth1Client) string { return client.endpoint },
+		func(client *underlyingEth1Client) (map[uint64]*big.Int, error) {
+			return batchBalancesAtBlocks(ctx, client, address, blocks)
+		},
+		func(balances map[uint64]*big.Int) any { return balances })
+}
+
+// VerifiedCaller returns a contract caller whose calls at a fixed block are verified by the quorum if enabled,
+// calls at the latest block may differ across endpoints and fail over as usual
+func (c *Eth1Client) VerifiedCaller() bind.ContractCaller {
+	return &verifiedCaller{Eth1Client: c}
+}
+
+type verifiedCaller struct {
+	*Eth1Client
+}
+
+func (v *verifiedCaller) CallContract(ctx context.Context, call ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	if !v.quorum.Enabled() || blockNumber == nil || blockNumber.Sign() <= 0 {
+		return v.Eth1Client.CallContract(ctx, call, blockNumber)
+	}
+	clients, err := v.getHealthyClients()
+	if err != nil {
+		return nil, err
+	}
+	return quorumRead(v.quorum, "CallContract", clients,
+		func(client *underlyingEth1Client) string { return client.endpoint },
+		func(client *underlyingEth1Client) ([]byte, error) {
+			startAt := time.Now()
+			bytes, err := client.CallContract(ctx, call, blockNumber)
+			client.observe("CallContract", startAt, err)
+			return bytes, err
+		},
+		func(bytes []byte) any { return hexutil.Bytes(bytes) })
+}
+
+type batchCaller interface {
+	BatchCallContext(ctx context.Context, calls []rpc.BatchElem) error
+}
+
+func batchBalancesAtBlocks(ctx context.Context, caller batchCaller, address common.Address, blocks []uint64) (map[uint64]*big.Int, error) {
 	calls := make([]rpc.BatchElem, len(blocks))
 	results := make([]*hexutil.Big, len(blocks))
 
@@ -182,7 +316,7 @@ func (c *Eth1Client) BatchBalancesAtBlocks(ctx context.Context, address common.A
 		}
 	}
 
-	if err := c.BatchCallContext(ctx, calls); err != nil {
+	if err := caller.BatchCallContext(ctx, calls); err != nil {
 		return nil, err
 	}
 
@@ -228,7 +362,10 @@ func (c *Eth1Client) Debug_TraceBlockByNumber(ctx context.Context, number *big.I
 
 	for _, client := range clients {
 		rpcClient := client.Client.Client()
-		if err = rpcClient.CallContext(ctx, &result, "debug_traceBlockByNumber", number, tracer); err == nil {
+		startAt := time.Now()
+		err = rpcClient.CallContext(ctx, &result, "debug_traceBlockByNumber", number, tracer)
+		client.observe("Debug_TraceBlockByNumber", startAt, err)
+		if err == nil {
 			return
 		}
 	}
@@ -244,7 +381,9 @@ func (c *Eth1Client) BlockByNumber(ctx context.Context, number *big.Int) (block
 	}
 
 	for _, client := range clients {
+		startAt := time.Now()
 		block, err = client.BlockByNumber(ctx, number)
+		client.observe("BlockByNumber", startAt, err)
 		if err == nil {
 			return
 		}
@@ -260,7 +399,9 @@ func (c *Eth1Client) ChainID(ctx context.Context) (id *big.Int, err error) {
 	}
 
 	for _, client := range clients {
+		startAt := time.Now()
 		id, err = client.ChainID(ctx)
+		client.observe("ChainID", startAt, err)
 		if err == nil {
 			return
 		}
@@ -276,7 +417,9 @@ func (c *Eth1Client) NonceAt(ctx context.Context, account common.Address, blockN
 	}
 
 	for _, client := range clients {
+		startAt := time.Now()
 		nonce, err = client.NonceAt(ctx, account, blockNumber)
+		client.observe("NonceAt", startAt, err)
 		if err == nil {
 			return
 		}
@@ -292,7 +435,9 @@ func (c *Eth1Client) BlockNumber(ctx context.Context) (number uint64, err error)
 	}
 
 	for _, client := range clients {
+		startAt := time.Now()
 		number, err = client.BlockNumber(ctx)
+		client.observe("BlockNumber", startAt, err)
 		if err == nil {
 			return
 		}
@@ -308,7 +453,9 @@ func (c *Eth1Client) CallContract(ctx context.Context, call ethereum.CallMsg, bl
 	}
 
 	for _, client := range clients {
+		startAt := time.Now()
 		bytes, err = client.CallContract(ctx, call, blockNumber)
+		client.observe("CallContract", startAt, err)
 		if err == nil {
 			return
 		}
@@ -324,7 +471,9 @@ func (c *Eth1Client) CodeAt(ctx context.Context, contract common.Address, blockN
 	}
 
 	for _, client := range clients {
+		startAt := time.Now()
 		bytes, err = client.CodeAt(ctx, contract, blockNumber)
+		client.observe("CodeAt", startAt, err)
 		if err == nil {
 			return
 		}
@@ -340,7 +489,9 @@ func (c *Eth1Client) EstimateGas(ctx context.Context, call ethereum.CallMsg) (ga
 	}
 
 	for _, client := range clients {
+		startAt := time.Now()
 		gas, err = client.EstimateGas(ctx, call)
+		client.observe("EstimateGas", startAt, err)
 		if err == nil {
 			return
 		}
@@ -356,7 +507,9 @@ func (c *Eth1Client) FilterLogs(ctx context.Context, query ethereum.FilterQuery)
 	}
 
 	for _, client := range clients {
+		startAt := time.Now()
 		logs, err = client.FilterLogs(ctx, query)
+		client.observe("FilterLogs", startAt, err)
 		if err == nil {
 			return
 		}
@@ -372,7 +525,9 @@ func (c *Eth1Client) HeaderByNumber(ctx context.Context, number *big.Int) (heade
 	}
 
 	for _, client := range clients {
+		startAt := time.Now()
 		header, err = client.HeaderByNumber(ctx, number)
+		client.observe("HeaderByNumber", startAt, err)
 		if err == nil {
 			return
 		}
@@ -388,7 +543,9 @@ func (c *Eth1Client) PendingCodeAt(ctx context.Context, account common.Address)
 	}
 
 	for _, client := range clients {
+		startAt := time.Now()
 		bytes, err = client.PendingCodeAt(ctx, account)
+		client.observe("PendingCodeAt", startAt, err)
 		if err == nil {
 			return
 		}
@@ -404,7 +561,9 @@ func (c *Eth1Client) PendingNonceAt(ctx context.Context, account common.Address)
 	}
 
 	for _, client := range clients {
+		startAt := time.Now()
 		nonce, err = client.PendingNonceAt(ctx, account)
+		client.observe("PendingNonceAt", startAt, err)
 		if err == nil {
 			return
 		}
@@ -420,7 +579,9 @@ func (c *Eth1Client) SendTransaction(ctx context.Context, tx *types.Transaction)
 	}
 
 	for _, client := range clients {
+		startAt := time.Now()
 		err = client.SendTransaction(ctx, tx)
+		client.observe("SendTransaction", startAt, err)
 		if err == nil {
 			return
 		}
@@ -436,7 +597,9 @@ func (c *Eth1Client) SubscribeFilterLogs(ctx context.Context, query ethereum.Fil
 	}
 
 	for _, client := range clients {
+		startAt := time.Now()
 		sub, err = client.SubscribeFilterLogs(ctx, query, ch)
+		client.observe("SubscribeFilterLogs", startAt, err)
 		if err == nil {
 			return
 		}
@@ -452,7 +615,9 @@ func (c *Eth1Client) SuggestGasPrice(ctx context.Context) (price *big.Int, err e
 	}
 
 	for _, client := range clients {
+		startAt := time.Now()
 		price, err = client.SuggestGasPrice(ctx)
+		client.observe("SuggestGasPrice", startAt, err)
 		if err == nil {
 			return
 		}
@@ -468,7 +633,45 @@ func (c *Eth1Client) SuggestGasTipCap(ctx context.Context) (cap *big.Int, err er
 	}
 
 	for _, client := range clients {
+		startAt := time.Now()
 		cap, err = client.SuggestGasTipCap(ctx)
+		client.observe("SuggestGasTipCap", startAt, err)
+		if err == nil {
+			return
+		}
+	}
+	return
+}
+
+func (c *Eth1Client) TransactionByHash(ctx context.Context, hash common.Hash) (tx *types.Transaction, isPending bool, err error) {
+	var clients []*underlyingEth1Client
+	clients, err = c.getHealthyClients()
+	if err != nil {
+		return
+	}
+
+	for _, client := range clients {
+		startAt := time.Now()
+		tx, isPending, err = client.TransactionByHash(ctx, hash)
+		client.observe("TransactionByHash", startAt, err)
+		if err == nil {
+			return
+		}
+	}
+	return
+}
+
+func (c *Eth1Client) TransactionReceipt(ctx context.Context, txHash common.Hash) (receipt *types.Receipt, err error) {
+	var clients []*underlyingEth1Client
+	clients, err = c.getHealthyClients()
+	if err != nil {
+		return
+	}
+
+	for _, client := range clients {
+		startAt := time.Now()
+		receipt, err = client.TransactionReceipt(ctx, txHash)
+		client.observe("TransactionReceipt", startAt, err)
 		if err == nil {
 			return
 		}
@@ -0,0 +1,71 @@
+package connection
+
+import (
+	"time"
+
+	"github.com/stafiprotocol/eth-lsd-relay/pkg/utils"
+)
+
+// EndpointHealth is the result of the latest periodic health check of an endpoint, along with its score in the endpoint pool
+type EndpointHealth struct {
+	Endpoint      string        `json:"endpoint"` // scheme and host only
+	Healthy       bool          `json:"healthy"`
+	OutOfSync     bool          `json:"outOfSync"`
+	LatestBlock   uint64        `json:"latestBlock,omitempty"`   // eth1 latest block number
+	FinalizedSlot uint64        `json:"finalizedSlot,omitempty"` // eth2 finalized slot
+	Error         string        `json:"error,omitempty"`
+	LastCheckedAt time.Time     `json:"lastCheckedAt"`
+	Score         EndpointScore `json:"score"`
+}
+
+func (c *Eth1Client) EndpointsHealth() []EndpointHealth {
+	scores := c.pool.Scores()
+	healths := make([]EndpointHealth, 0, len(c.clients))
+	for _, client := range c.clients {
+		health := EndpointHealth{
+			Endpoint:      utils.RedactUrl(client.endpoint),
+			OutOfSync:     client.outOfSync,
+			LastCheckedAt: client.lastCheckedAt,
+			Score:         scores[client.index],
+		}
+		if err := client.healthCheckError; err != nil {
+			health.Error = err.Error()
+		}
+		if block := client.latestBlock; block != nil {
+			health.LatestBlock = block.NumberU64()
+		}
+		health.Healthy = health.Error == "" && !health.OutOfSync
+		healths = append(healths, health)
+	}
+	return healths
+}
+
+func (c *Connection) Eth1EndpointsHealth() []EndpointHealth {
+	eth1Client, ok := c.eth1Client.(*Eth1Client)
+	if !ok {
+		return nil
+	}
+	return eth1Client.EndpointsHealth()
+}
+
+func (c *Connection) Eth2EndpointsHealth() []EndpointHealth {
+	scores := c.eth2Pool.Scores()
+	healths := make([]EndpointHealth, 0, len(c.eth2Clients))
+	for _, client := range c.eth2Clients {
+		health := EndpointHealth{
+			Endpoint:      utils.RedactUrl(client.endpoint),
+			OutOfSync:     client.outOfSync,
+			LastCheckedAt: client.lastCheckedAt,
+			Score:         scores[client.index],
+		}
+		if err := client.healthCheckError; err != nil {
+			health.Error = err.Error()
+		}
+		if head := client.latestBeaconHead; head != nil {
+			health.FinalizedSlot = head.FinalizedSlot
+		}
+		health.Healthy = health.Error == "" && !health.OutOfSync
+		healths = append(healths, health)
+	}
+	return healths
+}
@@ -0,0 +1,133 @@
+package connection
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stafiprotocol/eth-lsd-relay/pkg/notify"
+	"github.com/stafiprotocol/eth-lsd-relay/pkg/utils"
+)
+
+// Quorum configs the verify mode of critical reads: Size endpoints are queried and Threshold of them must return the same answer.
+// Reads fail over to the next healthy endpoint as usual if Size is less than 2
+type Quorum struct {
+	Size      int
+	Threshold int
+}
+
+func (q Quorum) Enabled() bool {
+	return q.Size > 1
+}
+
+// QuorumError is returned by a verified read if not enough endpoints agree, the answer is not used
+type QuorumError struct {
+	Method   string
+	Agreed   int
+	Required int
+	Answers  map[string]string // index and redacted url of endpoint => answer fingerprint or error
+}
+
+func (e *QuorumError) Error() string {
+	endpoints := make([]string, 0, len(e.Answers))
+	for endpoint := range e.Answers {
+		endpoints = append(endpoints, endpoint)
+	}
+	sort.Strings(endpoints)
+	answers := make([]string, 0, len(endpoints))
+	for _, endpoint := range endpoints {
+		answers = append(answers, fmt.Sprintf("%s: %s", endpoint, e.Answers[endpoint]))
+	}
+	return fmt.Sprintf("quorum of %s not reached, %d endpoints agree but %d required, answers: %s",
+		e.Method, e.Agreed, e.Required, strings.Join(answers, "; "))
+}
+
+// Disagreed reports whether endpoints returned different answers, rather than just failed
+func (e *QuorumError) Disagreed() bool {
+	fingerprints := make(map[string]bool)
+	for _, answer := range e.Answers {
+		if strings.HasPrefix(answer, "0x") {
+			fingerprints[answer] = true
+		}
+	}
+	return len(fingerprints) > 1
+}
+
+// quorumRead calls read on the first q.Size clients concurrently and returns the answer shared by at least q.Threshold of them.
+// Answers are compared by the json encoding of fingerprint(answer), which should hold only the fields the relay relies on
+func quorumRead[C any, T any](q Quorum, method string, clients []C, endpoint func(C) string, read func(C) (T, error), fingerprint func(T) any) (T, error) {
+	var zero T
+	if len(clients) > q.Size {
+		clients = clients[:q.Size]
+	}
+
+	type answer struct {
+		value       T
+		fingerprint string
+		err         error
+	}
+	answers := make([]answer, len(clients))
+	wg := sync.WaitGroup{}
+	for i := range clients {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			value, err := read(clients[i])
+			if err != nil {
+				answers[i].err = err
+				return
+			}
+			encoded, err := json.Marshal(fingerprint(value))
+			if err != nil {
+				answers[i].err = fmt.Errorf("encode answer: %w", err)
+				return
+			}
+			answers[i] = answer{value: value, fingerprint: crypto.Keccak256Hash(encoded).Hex()}
+		}(i)
+	}
+	wg.Wait()
+
+	counts := make(map[string]int)
+	best := -1
+	for i, a := range answers {
+		if a.err != nil {
+			continue
+		}
+		counts[a.fingerprint]++
+		if best < 0 || counts[a.fingerprint] > counts[answers[best].fingerprint] {
+			best = i
+		}
+	}
+	if best >= 0 && counts[answers[best].fingerprint] >= q.Threshold {
+		return answers[best].value, nil
+	}
+
+	quorumErr := &QuorumError{
+		Method:   method,
+		Required: q.Threshold,
+		Answers:  make(map[string]string, len(clients)),
+	}
+	if best >= 0 {
+		quorumErr.Agreed = counts[answers[best].fingerprint]
+	}
+	for i, a := range answers {
+		key := fmt.Sprintf("#%d %s", i, utils.RedactUrl(endpoint(clients[i])))
+		if a.err != nil {
+			quorumErr.Answers[key] = "err: " + a.err.Error()
+		} else {
+			quorumErr.Answers[key] = a.fingerprint
+		}
+	}
+	if quorumErr.Disagreed() {
+		notify.Notify(notify.Alert{
+			Key:      "quorum/" + method,
+			Severity: notify.SeverityCritical,
+			Title:    fmt.Sprintf("endpoints disagree on %s, voting is paused", method),
+			Message:  quorumErr.Error(),
+		})
+	}
+	return zero, quorumErr
+}
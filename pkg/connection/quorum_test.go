@@ -0,0 +1,83 @@
+package connection
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeEndpoint struct {
+	url     string
+	balance int64
+	err     error
+}
+
+func readFakeBalance(e *fakeEndpoint) (map[uint64]*big.Int, error) {
+	if e.err != nil {
+		return nil, e.err
+	}
+	return map[uint64]*big.Int{100: big.NewInt(e.balance)}, nil
+}
+
+func readQuorum(q Quorum, endpoints ...*fakeEndpoint) (map[uint64]*big.Int, error) {
+	return quorumRead(q, "BatchBalancesAtBlocks", endpoints,
+		func(e *fakeEndpoint) string { return e.url },
+		readFakeBalance,
+		func(balances map[uint64]*big.Int) any { return balances })
+}
+
+func TestQuorumRead(t *testing.T) {
+	q := Quorum{Size: 3, Threshold: 2}
+
+	// a lying endpoint is outvoted
+	balances, err := readQuorum(q,
+		&fakeEndpoint{url: "http://a", balance: 32},
+		&fakeEndpoint{url: "http://b", balance: 31},
+		&fakeEndpoint{url: "http://c", balance: 32})
+	assert.Nil(t, err)
+	assert.Equal(t, big.NewInt(32), balances[100])
+
+	// a failed endpoint is tolerated
+	balances, err = readQuorum(q,
+		&fakeEndpoint{url: "http://a", err: errors.New("timeout")},
+		&fakeEndpoint{url: "http://b", balance: 32},
+		&fakeEndpoint{url: "http://c", balance: 32})
+	assert.Nil(t, err)
+	assert.Equal(t, big.NewInt(32), balances[100])
+
+	// endpoints beyond size are not queried
+	balances, err = readQuorum(q,
+		&fakeEndpoint{url: "http://a", balance: 32},
+		&fakeEndpoint{url: "http://b", balance: 32},
+		&fakeEndpoint{url: "http://c", balance: 32},
+		&fakeEndpoint{url: "http://d", err: errors.New("must not be queried")})
+	assert.Nil(t, err)
+	assert.Equal(t, big.NewInt(32), balances[100])
+}
+
+func TestQuorumReadDisagree(t *testing.T) {
+	q := Quorum{Size: 3, Threshold: 2}
+
+	_, err := readQuorum(q,
+		&fakeEndpoint{url: "http://a", balance: 32},
+		&fakeEndpoint{url: "http://b", balance: 31},
+		&fakeEndpoint{url: "http://c", err: errors.New("timeout")})
+	var quorumErr *QuorumError
+	assert.ErrorAs(t, err, &quorumErr)
+	assert.True(t, quorumErr.Disagreed())
+	assert.Equal(t, 1, quorumErr.Agreed)
+	assert.Equal(t, 2, quorumErr.Required)
+	assert.Len(t, quorumErr.Answers, 3)
+	assert.ErrorContains(t, err, "quorum of BatchBalancesAtBlocks not reached")
+	assert.ErrorContains(t, err, "#2 http://c: err: timeout")
+
+	// failures only are not a disagreement
+	_, err = readQuorum(q,
+		&fakeEndpoint{url: "http://a", balance: 32},
+		&fakeEndpoint{url: "http://b", err: errors.New("timeout")},
+		&fakeEndpoint{url: "http://c", err: errors.New("timeout")})
+	assert.ErrorAs(t, err, &quorumErr)
+	assert.False(t, quorumErr.Disagreed())
+}
@@ -0,0 +1,226 @@
+package connection
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	ethcrypto "github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/stafiprotocol/chainbridge/utils/crypto/secp256k1"
+	"github.com/stafiprotocol/eth-lsd-relay/pkg/utils"
+)
+
+// Signer signs the txs of the voter account
+type Signer interface {
+	Address() common.Address
+	SignTx(ctx context.Context, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error)
+}
+
+var (
+	_ Signer = &KeystoreSigner{}
+	_ Signer = &RemoteSigner{}
+	_ Signer = &MockSigner{}
+)
+
+// KeystoreSigner signs with a keypair loaded from the local keystore
+type KeystoreSigner struct {
+	kp *secp256k1.Keypair
+}
+
+func NewKeystoreSigner(kp *secp256k1.Keypair) (*KeystoreSigner, error) {
+	if kp == nil {
+		return nil, fmt.Errorf("keypair can not be nil")
+	}
+	return &KeystoreSigner{kp: kp}, nil
+}
+
+func (s *KeystoreSigner) Address() common.Address {
+	return s.kp.CommonAddress()
+}
+
+func (s *KeystoreSigner) SignTx(_ context.Context, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	return types.SignTx(tx, types.LatestSignerForChainID(chainID), s.kp.PrivateKey())
+}
+
+// RemoteSigner signs through the eth_signTransaction json-rpc method of a signing service such as Web3Signer or Clef,
+// the private key never enters the relay process
+type RemoteSigner struct {
+	endpoint string
+	address  common.Address
+	client   *rpc.Client
+	timeout  time.Duration
+}
+
+// signTxArgs is the transaction object of eth_signTransaction
+type signTxArgs struct {
+	From                 common.Address  `json:"from"`
+	To                   *common.Address `json:"to,omitempty"`
+	Gas                  hexutil.Uint64  `json:"gas"`
+	GasPrice             *hexutil.Big    `json:"gasPrice,omitempty"`
+	MaxFeePerGas         *hexutil.Big    `json:"maxFeePerGas,omitempty"`
+	MaxPriorityFeePerGas *hexutil.Big    `json:"maxPriorityFeePerGas,omitempty"`
+	Value                *hexutil.Big    `json:"value"`
+	Nonce                hexutil.Uint64  `json:"nonce"`
+	Data                 hexutil.Bytes   `json:"data"`
+	ChainID              *hexutil.Big    `json:"chainId"`
+}
+
+// NewRemoteSigner dials the signer and checks that it manages address
+func NewRemoteSigner(endpoint string, address common.Address) (*RemoteSigner, error) {
+	client, err := rpc.Dial(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("dial remote signer %s err: %w", utils.RedactUrl(endpoint), err)
+	}
+	s := &RemoteSigner{
+		endpoint: endpoint,
+		address:  address,
+		client:   client,
+		timeout:  30 * time.Second,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+	var accounts []common.Address
+	if err := client.CallContext(ctx, &accounts, "eth_accounts"); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("remote signer eth_accounts err: %w", err)
+	}
+	for _, account := range accounts {
+		if account == address {
+			return s, nil
+		}
+	}
+	client.Close()
+	return nil, fmt.Errorf("account %s is not managed by remote signer %s", address.String(), utils.RedactUrl(endpoint))
+}
+
+func (s *RemoteSigner) Address() common.Address {
+	return s.address
+}
+
+func (s *RemoteSigner) SignTx(ctx context.Context, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	args := signTxArgs{
+		From:    s.address,
+		To:      tx.To(),
+		Gas:     hexutil.Uint64(tx.Gas()),
+		Value:   (*hexutil.Big)(tx.Value()),
+		Nonce:   hexutil.Uint64(tx.Nonce()),
+		Data:    tx.Data(),
+		ChainID: (*hexutil.Big)(chainID),
+	}
+	if tx.Type() == types.LegacyTxType {
+		args.GasPrice = (*hexutil.Big)(tx.GasPrice())
+	} else {
+		args.MaxFeePerGas = (*hexutil.Big)(tx.GasFeeCap())
+		args.MaxPriorityFeePerGas = (*hexutil.Big)(tx.GasTipCap())
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+	var result json.RawMessage
+	if err := s.client.CallContext(ctx, &result, "eth_signTransaction", args); err != nil {
+		return nil, fmt.Errorf("remote signer eth_signTransaction err: %w", err)
+	}
+	raw, err := decodeSignTxResult(result)
+	if err != nil {
+		return nil, err
+	}
+	signed := new(types.Transaction)
+	if err := signed.UnmarshalBinary(raw); err != nil {
+		return nil, fmt.Errorf("decode signed tx err: %w", err)
+	}
+	if err := checkSignedTx(tx, signed, s.address, chainID); err != nil {
+		return nil, err
+	}
+	return signed, nil
+}
+
+func (s *RemoteSigner) Close() {
+	s.client.Close()
+}
+
+// decodeSignTxResult accepts the raw tx hex returned by Web3Signer and the {raw, tx} object returned by Clef
+func decodeSignTxResult(result json.RawMessage) ([]byte, error) {
+	var rawHex string
+	if err := json.Unmarshal(result, &rawHex); err != nil {
+		var obj struct {
+			Raw string `json:"raw"`
+		}
+		if err := json.Unmarshal(result, &obj); err != nil || obj.Raw == "" {
+			return nil, fmt.Errorf("unexpected eth_signTransaction result: %s", strings.TrimSpace(string(result)))
+		}
+		rawHex = obj.Raw
+	}
+	raw, err := hexutil.Decode(rawHex)
+	if err != nil {
+		return nil, fmt.Errorf("decode eth_signTransaction result err: %w", err)
+	}
+	return raw, nil
+}
+
+// checkSignedTx makes sure the signer signed what was asked, by the expected account
+func checkSignedTx(tx, signed *types.Transaction, from common.Address, chainID *big.Int) error {
+	sender, err := types.Sender(types.LatestSignerForChainID(chainID), signed)
+	if err != nil {
+		return fmt.Errorf("recover sender of signed tx err: %w", err)
+	}
+	if sender != from {
+		return fmt.Errorf("signed tx sender %s is not %s", sender.String(), from.String())
+	}
+	sameTo := (tx.To() == nil && signed.To() == nil) || (tx.To() != nil && signed.To() != nil && *tx.To() == *signed.To())
+	if !sameTo || tx.Nonce() != signed.Nonce() || tx.Gas() != signed.Gas() ||
+		tx.Value().Cmp(signed.Value()) != 0 || string(tx.Data()) != string(signed.Data()) ||
+		tx.GasFeeCap().Cmp(signed.GasFeeCap()) != 0 || tx.GasTipCap().Cmp(signed.GasTipCap()) != 0 {
+		return fmt.Errorf("signed tx %s differs from the requested one", signed.Hash().String())
+	}
+	return nil
+}
+
+// MockSigner signs with an in-memory key and records the signed txs, for tests
+type MockSigner struct {
+	key    *ecdsa.PrivateKey
+	mutex  sync.Mutex
+	signed []*types.Transaction
+}
+
+// NewMockSigner uses key if not nil, otherwise a new key is generated
+func NewMockSigner(key *ecdsa.PrivateKey) (*MockSigner, error) {
+	if key == nil {
+		var err error
+		if key, err = ethcrypto.GenerateKey(); err != nil {
+			return nil, err
+		}
+	}
+	return &MockSigner{key: key}, nil
+}
+
+func (s *MockSigner) Address() common.Address {
+	return ethcrypto.PubkeyToAddress(s.key.PublicKey)
+}
+
+func (s *MockSigner) SignTx(_ context.Context, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	signed, err := types.SignTx(tx, types.LatestSignerForChainID(chainID), s.key)
+	if err != nil {
+		return nil, err
+	}
+	s.mutex.Lock()
+	s.signed = append(s.signed, signed)
+	s.mutex.Unlock()
+	return signed, nil
+}
+
+// Signed returns the txs signed so far
+func (s *MockSigner) Signed() []*types.Transaction {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([]*types.Transaction{}, s.signed...)
+}
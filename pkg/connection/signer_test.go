@@ -0,0 +1,143 @@
+package connection_test
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/stafiprotocol/eth-lsd-relay/pkg/connection"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeRemoteSigner answers eth_accounts and eth_signTransaction like Web3Signer, or like Clef if clef is set
+type fakeRemoteSigner struct {
+	signer *connection.MockSigner
+	clef   bool
+	tamper bool // sign with a changed nonce
+}
+
+func (f *fakeRemoteSigner) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ID     json.RawMessage   `json:"id"`
+		Method string            `json:"method"`
+		Params []json.RawMessage `json:"params"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var result interface{}
+	switch req.Method {
+	case "eth_accounts":
+		result = []common.Address{f.signer.Address()}
+	case "eth_signTransaction":
+		var args struct {
+			To                   *common.Address `json:"to"`
+			Gas                  hexutil.Uint64  `json:"gas"`
+			MaxFeePerGas         *hexutil.Big    `json:"maxFeePerGas"`
+			MaxPriorityFeePerGas *hexutil.Big    `json:"maxPriorityFeePerGas"`
+			Value                *hexutil.Big    `json:"value"`
+			Nonce                hexutil.Uint64  `json:"nonce"`
+			Data                 hexutil.Bytes   `json:"data"`
+			ChainID              *hexutil.Big    `json:"chainId"`
+		}
+		if err := json.Unmarshal(req.Params[0], &args); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		nonce := uint64(args.Nonce)
+		if f.tamper {
+			nonce++
+		}
+		tx := types.NewTx(&types.DynamicFeeTx{
+			ChainID:   args.ChainID.ToInt(),
+			Nonce:     nonce,
+			GasTipCap: args.MaxPriorityFeePerGas.ToInt(),
+			GasFeeCap: args.MaxFeePerGas.ToInt(),
+			Gas:       uint64(args.Gas),
+			To:        args.To,
+			Value:     args.Value.ToInt(),
+			Data:      args.Data,
+		})
+		signed, err := f.signer.SignTx(context.Background(), tx, args.ChainID.ToInt())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		raw, _ := signed.MarshalBinary()
+		if f.clef {
+			result = map[string]interface{}{"raw": hexutil.Bytes(raw), "tx": signed}
+		} else {
+			result = hexutil.Bytes(raw)
+		}
+	default:
+		http.Error(w, "method not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"jsonrpc": "2.0", "id": req.ID, "result": result})
+}
+
+func newTestTx() *types.Transaction {
+	to := common.HexToAddress("0x61135C59A4Eb452b89963188eD6B6a7487049764")
+	return types.NewTx(&types.DynamicFeeTx{
+		ChainID:   big.NewInt(17000),
+		Nonce:     7,
+		GasTipCap: big.NewInt(1e9),
+		GasFeeCap: big.NewInt(30e9),
+		Gas:       500000,
+		To:        &to,
+		Value:     big.NewInt(0),
+		Data:      []byte{0x01, 0x02},
+	})
+}
+
+func TestRemoteSigner(t *testing.T) {
+	mock, err := connection.NewMockSigner(nil)
+	assert.Nil(t, err)
+	chainID := big.NewInt(17000)
+
+	for _, clef := range []bool{false, true} {
+		fake := &fakeRemoteSigner{signer: mock, clef: clef}
+		server := httptest.NewServer(fake)
+
+		signer, err := connection.NewRemoteSigner(server.URL, mock.Address())
+		assert.Nil(t, err)
+		assert.Equal(t, mock.Address(), signer.Address())
+
+		tx := newTestTx()
+		signed, err := signer.SignTx(context.Background(), tx, chainID)
+		assert.Nil(t, err)
+		sender, err := types.Sender(types.LatestSignerForChainID(chainID), signed)
+		assert.Nil(t, err)
+		assert.Equal(t, mock.Address(), sender)
+		assert.Equal(t, tx.Nonce(), signed.Nonce())
+		assert.Equal(t, tx.Data(), signed.Data())
+
+		// a signer signing something else is rejected
+		fake.tamper = true
+		_, err = signer.SignTx(context.Background(), tx, chainID)
+		assert.ErrorContains(t, err, "differs from the requested one")
+
+		signer.Close()
+		server.Close()
+	}
+}
+
+func TestRemoteSignerUnknownAccount(t *testing.T) {
+	mock, err := connection.NewMockSigner(nil)
+	assert.Nil(t, err)
+	server := httptest.NewServer(&fakeRemoteSigner{signer: mock})
+	defer server.Close()
+
+	_, err = connection.NewRemoteSigner(server.URL, common.HexToAddress("0x61135C59A4Eb452b89963188eD6B6a7487049764"))
+	assert.ErrorContains(t, err, "is not managed by remote signer")
+}
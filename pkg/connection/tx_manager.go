@@ -0,0 +1,551 @@
+package connection
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/sirupsen/logrus"
+	"github.com/stafiprotocol/eth-lsd-relay/pkg/utils"
+)
+
+var ErrTxCancelled = errors.New("tx cancelled")
+
+// nodes only accept a replacement raising both fee caps by this percent
+const minReplacementBumpPercent = 10
+
+// a nonce used while none of our txs has a receipt is only treated as taken by another tx after this many checks,
+// as the node answering the receipt may lag behind the one answering the nonce
+const maxMissingReceiptChecks = 3
+
+// TxBackend is the part of the eth1 client used by TxManager
+type TxBackend interface {
+	balanceBackend
+	NonceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (uint64, error)
+	SendTransaction(ctx context.Context, tx *types.Transaction) error
+	TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error)
+}
+
+type balanceBackend interface {
+	BalanceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (*big.Int, error)
+}
+
+// checkBalance guards every send and replacement of TxManager, it makes sure account can pay cost, the max a tx may spend: gasLimit * gasFeeCap + value
+func checkBalance(ctx context.Context, backend balanceBackend, account common.Address, cost *big.Int) error {
+	balance, err := backend.BalanceAt(ctx, account, nil)
+	if err != nil {
+		return err
+	}
+	if balance.Cmp(cost) < 0 {
+		return &InsufficientBalanceError{Balance: balance, Required: cost}
+	}
+	return nil
+}
+
+// FeeFunc returns the gas tip cap and gas fee cap of a new tx
+type FeeFunc func(ctx context.Context) (*big.Int, *big.Int, error)
+
+// ObsoleteChecker tells whether a pending tx is not needed anymore, e.g. its proposals were executed by other voters
+type ObsoleteChecker func(ptx *PendingTx) (bool, error)
+
+type TxManagerConfig struct {
+	BumpAfter    time.Duration // a tx not mined after this is re-broadcast with bumped fees
+	BumpPercent  uint64        // at least 10, the minimum accepted by nodes for a replacement
+	MaxGasPrice  *big.Int
+	PollInterval time.Duration
+	StoreFile    string // pending txs are persisted in it if not empty
+}
+
+// PendingTx is a relay tx not mined yet, every broadcast version of it shares Nonce
+type PendingTx struct {
+	Key         string         `json:"key"`
+	Nonce       uint64         `json:"nonce"`
+	To          common.Address `json:"to"`
+	Data        hexutil.Bytes  `json:"data"`
+	Value       *big.Int       `json:"value"`
+	Gas         uint64         `json:"gas"`
+	GasTipCap   *big.Int       `json:"gasTipCap"`
+	GasFeeCap   *big.Int       `json:"gasFeeCap"`
+	Raw         hexutil.Bytes  `json:"raw"`    // latest signed version
+	Hashes      []common.Hash  `json:"hashes"` // hashes of all broadcast versions, latest last
+	ProposalIds []common.Hash  `json:"proposalIds"`
+	Cancelled   bool           `json:"cancelled"`
+	BroadcastAt time.Time      `json:"broadcastAt"`
+
+	mutex                sync.Mutex // guards Hashes read by Hash outside of the manager
+	missingReceiptChecks int
+	done                 chan struct{}
+	receipt              *types.Receipt
+	err                  error
+}
+
+// Hash returns the hash of the latest broadcast version
+func (p *PendingTx) Hash() common.Hash {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return p.Hashes[len(p.Hashes)-1]
+}
+
+// TxManager sends the txs of signer, tracks them by nonce until mined, re-broadcasts them with bumped fees
+// when stuck and cancels the ones made obsolete
+type TxManager struct {
+	backend TxBackend
+	signer  Signer
+	chainID *big.Int
+	fees    FeeFunc
+	cfg     TxManagerConfig
+	log     *logrus.Entry
+
+	mutex            sync.Mutex
+	pending          map[uint64]*PendingTx
+	obsoleteCheckers map[common.Address]ObsoleteChecker
+	stop             chan struct{}
+}
+
+func NewTxManager(backend TxBackend, signer Signer, chainID *big.Int, fees FeeFunc, cfg TxManagerConfig) (*TxManager, error) {
+	if signer == nil {
+		return nil, fmt.Errorf("signer can not be nil")
+	}
+	if cfg.BumpPercent < minReplacementBumpPercent {
+		return nil, fmt.Errorf("bump percent can not be less than %d", minReplacementBumpPercent)
+	}
+	if cfg.MaxGasPrice == nil || cfg.MaxGasPrice.Sign() <= 0 {
+		return nil, fmt.Errorf("max gas price empty")
+	}
+	if cfg.PollInterval == 0 {
+		cfg.PollInterval = utils.RetryInterval
+	}
+	m := &TxManager{
+		backend:          backend,
+		signer:           signer,
+		chainID:          chainID,
+		fees:             fees,
+		cfg:              cfg,
+		log:              logrus.WithField("module", "txManager"),
+		pending:          make(map[uint64]*PendingTx),
+		obsoleteCheckers: make(map[common.Address]ObsoleteChecker),
+		stop:             make(chan struct{}),
+	}
+	if err := m.load(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (m *TxManager) Start() {
+	utils.SafeGoWithRestart(m.checkPendingService)
+}
+
+func (m *TxManager) Stop() {
+	close(m.stop)
+}
+
+func (m *TxManager) checkPendingService() {
+	ticker := time.NewTicker(m.cfg.PollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-m.stop:
+			return
+		case <-ticker.C:
+			if err := m.CheckPending(context.Background()); err != nil {
+				m.log.WithError(err).Warn("check pending txs failed")
+			}
+		}
+	}
+}
+
+// SetObsoleteChecker sets the checker of the pending txs sent to contract to
+func (m *TxManager) SetObsoleteChecker(to common.Address, checker ObsoleteChecker) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.obsoleteCheckers[to] = checker
+}
+
+// Send builds a tx with build and broadcasts it with the next free nonce. If a tx with the same key is still
+// pending it is returned instead, so a proposal is never voted twice while its tx is stuck
+func (m *TxManager) Send(ctx context.Context, key string, proposalIds []common.Hash, gasLimit uint64,
+	build func(opts *bind.TransactOpts) (*types.Transaction, error)) (*PendingTx, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if ptx := m.pendingOf(key); ptx != nil {
+		m.log.WithFields(logrus.Fields{
+			"key":    key,
+			"nonce":  ptx.Nonce,
+			"txHash": ptx.Hash().String(),
+		}).Info("tx already pending")
+		return ptx, nil
+	}
+
+	nonce, err := m.nextNonce(ctx)
+	if err != nil {
+		return nil, err
+	}
+	gasTipCap, gasFeeCap, err := m.fees(ctx)
+	if err != nil {
+		return nil, err
+	}
+	opts := newSignerTransactor(m.signer, m.chainID)
+	opts.Context = ctx
+	opts.Nonce = new(big.Int).SetUint64(nonce)
+	opts.GasLimit = gasLimit
+	opts.GasTipCap = gasTipCap
+	opts.GasFeeCap = gasFeeCap
+	opts.NoSend = true
+	tx, err := build(opts)
+	if err != nil {
+		return nil, err
+	}
+	if tx.To() == nil {
+		return nil, fmt.Errorf("contract creation tx is not supported")
+	}
+	if err = checkBalance(ctx, m.backend, m.signer.Address(), tx.Cost()); err != nil {
+		return nil, err
+	}
+
+	ptx := &PendingTx{
+		Key:         key,
+		Nonce:       nonce,
+		To:          *tx.To(),
+		Data:        tx.Data(),
+		Value:       tx.Value(),
+		Gas:         tx.Gas(),
+		ProposalIds: proposalIds,
+		done:        make(chan struct{}),
+	}
+	if err = ptx.update(tx); err != nil {
+		return nil, err
+	}
+	// persisted before broadcast so a tx sent right before a crash is still tracked after restart
+	m.pending[nonce] = ptx
+	if err = m.save(); err != nil {
+		delete(m.pending, nonce)
+		return nil, err
+	}
+	if err = m.backend.SendTransaction(ctx, tx); err != nil {
+		delete(m.pending, nonce)
+		if saveErr := m.save(); saveErr != nil {
+			m.log.WithError(saveErr).Warn("save pending txs failed")
+		}
+		return nil, err
+	}
+	return ptx, nil
+}
+
+// Wait blocks until ptx is mined, ErrTxCancelled is returned if it was replaced by a cancellation
+func (m *TxManager) Wait(ctx context.Context, ptx *PendingTx) (*types.Receipt, error) {
+	select {
+	case <-ptx.done:
+		return ptx.receipt, ptx.err
+	case <-ctx.Done():
+		return nil, fmt.Errorf("wait tx with nonce %d: %w", ptx.Nonce, ctx.Err())
+	}
+}
+
+// PendingTxs returns the pending txs ordered by nonce
+func (m *TxManager) PendingTxs() []*PendingTx {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.sortedPending()
+}
+
+// PendingOf returns the pending tx sent with key, nil if there is none
+func (m *TxManager) PendingOf(key string) *PendingTx {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.pendingOf(key)
+}
+
+func (m *TxManager) pendingOf(key string) *PendingTx {
+	for _, ptx := range m.pending {
+		if ptx.Key == key && !ptx.Cancelled {
+			return ptx
+		}
+	}
+	return nil
+}
+
+// NextNonce returns the confirmed nonce of the signer, skipping the nonces of pending txs
+func (m *TxManager) NextNonce(ctx context.Context) (uint64, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.nextNonce(ctx)
+}
+
+func (m *TxManager) nextNonce(ctx context.Context) (uint64, error) {
+	nonce, err := m.backend.NonceAt(ctx, m.signer.Address(), nil)
+	if err != nil {
+		return 0, err
+	}
+	for n := range m.pending {
+		if n >= nonce {
+			nonce = n + 1
+		}
+	}
+	return nonce, nil
+}
+
+// CheckPending resolves the mined txs and bumps or cancels the ones stuck for longer than BumpAfter
+func (m *TxManager) CheckPending(ctx context.Context) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if len(m.pending) == 0 {
+		return nil
+	}
+
+	confirmedNonce, err := m.backend.NonceAt(ctx, m.signer.Address(), nil)
+	if err != nil {
+		return err
+	}
+	changed := false
+	for _, ptx := range m.sortedPending() {
+		if ptx.Nonce < confirmedNonce {
+			if m.resolve(ctx, ptx) {
+				delete(m.pending, ptx.Nonce)
+				changed = true
+			}
+			continue
+		}
+		if time.Since(ptx.BroadcastAt) < m.cfg.BumpAfter {
+			continue
+		}
+		if err := m.replace(ctx, ptx); err != nil {
+			m.log.WithFields(logrus.Fields{
+				"nonce":  ptx.Nonce,
+				"txHash": ptx.Hash().String(),
+			}).WithError(err).Warn("replace stuck tx failed")
+			continue
+		}
+		changed = true
+	}
+	if changed {
+		return m.save()
+	}
+	return nil
+}
+
+// resolve looks for the mined version of ptx whose nonce is confirmed, it returns false if not decided yet
+func (m *TxManager) resolve(ctx context.Context, ptx *PendingTx) bool {
+	for i := len(ptx.Hashes) - 1; i >= 0; i-- {
+		receipt, err := m.backend.TransactionReceipt(ctx, ptx.Hashes[i])
+		if err != nil || receipt == nil {
+			continue
+		}
+		ptx.receipt = receipt
+		switch {
+		case ptx.Cancelled:
+			ptx.err = ErrTxCancelled
+		case receipt.Status != types.ReceiptStatusSuccessful:
+			ptx.err = fmt.Errorf("tx %s failed", receipt.TxHash.String())
+		}
+		m.log.WithFields(logrus.Fields{
+			"nonce":     ptx.Nonce,
+			"txHash":    receipt.TxHash.String(),
+			"status":    receipt.Status,
+			"cancelled": ptx.Cancelled,
+		}).Info("tx mined")
+		close(ptx.done)
+		return true
+	}
+
+	ptx.missingReceiptChecks++
+	if ptx.missingReceiptChecks < maxMissingReceiptChecks {
+		return false
+	}
+	ptx.err = fmt.Errorf("nonce %d of tx %s was used by another tx", ptx.Nonce, ptx.Hash().String())
+	m.log.WithField("nonce", ptx.Nonce).Warn(ptx.err.Error())
+	close(ptx.done)
+	return true
+}
+
+// replace cancels ptx if obsolete, otherwise re-broadcasts it with bumped fees. The latest version is
+// re-broadcast as is if fees can not be bumped under the max gas price, in case it was dropped by the mempool
+func (m *TxManager) replace(ctx context.Context, ptx *PendingTx) error {
+	gasTipCap, gasFeeCap := m.bumpedFees(ctx, ptx)
+	if gasFeeCap.Cmp(raisePercent(ptx.GasFeeCap, minReplacementBumpPercent)) < 0 ||
+		gasTipCap.Cmp(raisePercent(ptx.GasTipCap, minReplacementBumpPercent)) < 0 {
+		m.log.WithFields(logrus.Fields{
+			"nonce":     ptx.Nonce,
+			"gasFeeCap": ptx.GasFeeCap.String(),
+		}).Warn("can not bump fees over max gas price, re-broadcast tx")
+		ptx.BroadcastAt = time.Now()
+		tx := new(types.Transaction)
+		if err := tx.UnmarshalBinary(ptx.Raw); err != nil {
+			return err
+		}
+		return m.sendIgnoreKnown(ctx, tx)
+	}
+
+	to, data, value, gas := ptx.To, ptx.Data, ptx.Value, ptx.Gas
+	cancel := false
+	if checker, exist := m.obsoleteCheckers[ptx.To]; exist && !ptx.Cancelled {
+		obsolete, err := checker(ptx)
+		if err != nil {
+			return err
+		}
+		if obsolete {
+			// a zero value self transfer takes the nonce
+			cancel = true
+			to, data, value, gas = m.signer.Address(), nil, big.NewInt(0), 21000
+		}
+	}
+
+	tx, err := m.signer.SignTx(ctx, types.NewTx(&types.DynamicFeeTx{
+		ChainID:   m.chainID,
+		Nonce:     ptx.Nonce,
+		GasTipCap: gasTipCap,
+		GasFeeCap: gasFeeCap,
+		Gas:       gas,
+		To:        &to,
+		Value:     value,
+		Data:      data,
+	}), m.chainID)
+	if err != nil {
+		return err
+	}
+	if err = checkBalance(ctx, m.backend, m.signer.Address(), tx.Cost()); err != nil {
+		return err
+	}
+	if err = m.sendIgnoreKnown(ctx, tx); err != nil {
+		return err
+	}
+	if cancel {
+		ptx.Cancelled = true
+		ptx.To, ptx.Data, ptx.Value, ptx.Gas = to, data, value, gas
+	}
+	if err = ptx.update(tx); err != nil {
+		return err
+	}
+	m.log.WithFields(logrus.Fields{
+		"key":       ptx.Key,
+		"nonce":     ptx.Nonce,
+		"txHash":    tx.Hash().String(),
+		"gasTipCap": gasTipCap.String(),
+		"gasFeeCap": gasFeeCap.String(),
+		"cancelled": ptx.Cancelled,
+	}).Info("replace stuck tx")
+	return nil
+}
+
+// bumpedFees raises both fees of ptx by BumpPercent, or to the market fees if higher, capped at MaxGasPrice
+func (m *TxManager) bumpedFees(ctx context.Context, ptx *PendingTx) (*big.Int, *big.Int) {
+	gasTipCap := raisePercent(ptx.GasTipCap, m.cfg.BumpPercent)
+	gasFeeCap := raisePercent(ptx.GasFeeCap, m.cfg.BumpPercent)
+	if marketTipCap, marketFeeCap, err := m.fees(ctx); err == nil {
+		if marketTipCap.Cmp(gasTipCap) > 0 {
+			gasTipCap = marketTipCap
+		}
+		if marketFeeCap.Cmp(gasFeeCap) > 0 {
+			gasFeeCap = marketFeeCap
+		}
+	}
+	if gasFeeCap.Cmp(m.cfg.MaxGasPrice) > 0 {
+		gasFeeCap = new(big.Int).Set(m.cfg.MaxGasPrice)
+	}
+	if gasTipCap.Cmp(gasFeeCap) > 0 {
+		gasTipCap = new(big.Int).Set(gasFeeCap)
+	}
+	return gasTipCap, gasFeeCap
+}
+
+func raisePercent(v *big.Int, percent uint64) *big.Int {
+	raised := new(big.Int).Mul(v, new(big.Int).SetUint64(100+percent))
+	return raised.Div(raised, big.NewInt(100))
+}
+
+func (m *TxManager) sendIgnoreKnown(ctx context.Context, tx *types.Transaction) error {
+	err := m.backend.SendTransaction(ctx, tx)
+	if err != nil && isKnownTxErr(err) {
+		return nil
+	}
+	return err
+}
+
+func isKnownTxErr(err error) bool {
+	msg := err.Error()
+	return strings.Contains(msg, "already known") || strings.Contains(msg, "known transaction")
+}
+
+func (p *PendingTx) update(tx *types.Transaction) error {
+	raw, err := tx.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.Raw = raw
+	p.GasTipCap = tx.GasTipCap()
+	p.GasFeeCap = tx.GasFeeCap()
+	p.Hashes = append(p.Hashes, tx.Hash())
+	p.BroadcastAt = time.Now()
+	return nil
+}
+
+func (m *TxManager) sortedPending() []*PendingTx {
+	list := make([]*PendingTx, 0, len(m.pending))
+	for _, ptx := range m.pending {
+		list = append(list, ptx)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Nonce < list[j].Nonce })
+	return list
+}
+
+func (m *TxManager) load() error {
+	if m.cfg.StoreFile == "" {
+		return nil
+	}
+	content, err := os.ReadFile(m.cfg.StoreFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	list := make([]*PendingTx, 0)
+	if err = json.Unmarshal(content, &list); err != nil {
+		return fmt.Errorf("decode pending txs file %s err: %w", m.cfg.StoreFile, err)
+	}
+	for _, ptx := range list {
+		if len(ptx.Hashes) == 0 {
+			continue
+		}
+		ptx.done = make(chan struct{})
+		m.pending[ptx.Nonce] = ptx
+	}
+	if len(m.pending) > 0 {
+		m.log.WithField("txs", len(m.pending)).Info("restore pending txs")
+	}
+	return nil
+}
+
+// save writes the pending txs to a temp file first, so the store is never left half written
+func (m *TxManager) save() error {
+	if m.cfg.StoreFile == "" {
+		return nil
+	}
+	content, err := json.MarshalIndent(m.sortedPending(), "", "  ")
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(m.cfg.StoreFile), 0700); err != nil {
+		return err
+	}
+	tmp := m.cfg.StoreFile + ".tmp"
+	if err = os.WriteFile(tmp, content, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, m.cfg.StoreFile)
+}
@@ -0,0 +1,242 @@
+package connection_test
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/stafiprotocol/eth-lsd-relay/pkg/connection"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeChain keeps the broadcast txs in a mempool keyed by nonce, mine includes the latest one
+type fakeChain struct {
+	mutex    sync.Mutex
+	nonce    uint64
+	balance  *big.Int
+	mempool  map[uint64]*types.Transaction
+	receipts map[common.Hash]*types.Receipt
+	sent     []*types.Transaction
+}
+
+func newFakeChain() *fakeChain {
+	return &fakeChain{
+		balance:  big.NewInt(1e18),
+		mempool:  make(map[uint64]*types.Transaction),
+		receipts: make(map[common.Hash]*types.Receipt),
+	}
+}
+
+func (c *fakeChain) NonceAt(context.Context, common.Address, *big.Int) (uint64, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.nonce, nil
+}
+
+func (c *fakeChain) BalanceAt(context.Context, common.Address, *big.Int) (*big.Int, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.balance, nil
+}
+
+func (c *fakeChain) SendTransaction(_ context.Context, tx *types.Transaction) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if tx.Nonce() < c.nonce {
+		return errors.New("nonce too low")
+	}
+	if old, exist := c.mempool[tx.Nonce()]; exist && old.Hash() != tx.Hash() &&
+		tx.GasFeeCap().Cmp(new(big.Int).Div(new(big.Int).Mul(old.GasFeeCap(), big.NewInt(110)), big.NewInt(100))) < 0 {
+		return errors.New("replacement transaction underpriced")
+	}
+	c.mempool[tx.Nonce()] = tx
+	c.sent = append(c.sent, tx)
+	return nil
+}
+
+func (c *fakeChain) TransactionReceipt(_ context.Context, hash common.Hash) (*types.Receipt, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	receipt, exist := c.receipts[hash]
+	if !exist {
+		return nil, errors.New("not found")
+	}
+	return receipt, nil
+}
+
+func (c *fakeChain) mine() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for {
+		tx, exist := c.mempool[c.nonce]
+		if !exist {
+			return
+		}
+		c.receipts[tx.Hash()] = &types.Receipt{TxHash: tx.Hash(), Status: types.ReceiptStatusSuccessful}
+		delete(c.mempool, c.nonce)
+		c.nonce++
+	}
+}
+
+func fixedFees(tip, feeCap int64) connection.FeeFunc {
+	return func(context.Context) (*big.Int, *big.Int, error) {
+		return big.NewInt(tip), big.NewInt(feeCap), nil
+	}
+}
+
+func newTestTxManager(t *testing.T, chain *fakeChain, signer connection.Signer, storeFile string) *connection.TxManager {
+	m, err := connection.NewTxManager(chain, signer, big.NewInt(17000), fixedFees(1e9, 10e9), connection.TxManagerConfig{
+		BumpAfter:   time.Millisecond,
+		BumpPercent: 15,
+		MaxGasPrice: big.NewInt(12e9),
+		StoreFile:   storeFile,
+	})
+	assert.Nil(t, err)
+	return m
+}
+
+// buildTx mimics an abigen contract call with the given opts
+func buildTx(to common.Address, data []byte) func(opts *bind.TransactOpts) (*types.Transaction, error) {
+	return func(opts *bind.TransactOpts) (*types.Transaction, error) {
+		return opts.Signer(opts.From, types.NewTx(&types.DynamicFeeTx{
+			ChainID:   big.NewInt(17000),
+			Nonce:     opts.Nonce.Uint64(),
+			GasTipCap: opts.GasTipCap,
+			GasFeeCap: opts.GasFeeCap,
+			Gas:       opts.GasLimit,
+			To:        &to,
+			Value:     big.NewInt(0),
+			Data:      data,
+		}))
+	}
+}
+
+func TestTxManagerSendAndBump(t *testing.T) {
+	chain := newFakeChain()
+	chain.nonce = 5
+	signer, err := connection.NewMockSigner(nil)
+	assert.Nil(t, err)
+	m := newTestTxManager(t, chain, signer, "")
+	to := common.HexToAddress("0x61135C59A4Eb452b89963188eD6B6a7487049764")
+
+	ptx, err := m.Send(context.Background(), "a", nil, 500000, buildTx(to, []byte{1}))
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(5), ptx.Nonce)
+
+	// same key is not sent twice, another key takes the next nonce
+	same, err := m.Send(context.Background(), "a", nil, 500000, buildTx(to, []byte{1}))
+	assert.Nil(t, err)
+	assert.Equal(t, ptx, same)
+	other, err := m.Send(context.Background(), "b", nil, 500000, buildTx(to, []byte{2}))
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(6), other.Nonce)
+	assert.Len(t, chain.sent, 2)
+
+	// stuck txs are bumped by 15%
+	time.Sleep(2 * time.Millisecond)
+	assert.Nil(t, m.CheckPending(context.Background()))
+	assert.Equal(t, big.NewInt(11.5e9), ptx.GasFeeCap)
+	assert.Equal(t, big.NewInt(1.15e9), ptx.GasTipCap)
+	assert.Len(t, ptx.Hashes, 2)
+
+	// capped at the max gas price the raise is below what nodes accept, the tx is only re-broadcast
+	time.Sleep(2 * time.Millisecond)
+	sent := len(chain.sent)
+	assert.Nil(t, m.CheckPending(context.Background()))
+	assert.Equal(t, big.NewInt(11.5e9), ptx.GasFeeCap)
+	assert.Len(t, ptx.Hashes, 2)
+	assert.Greater(t, len(chain.sent), sent)
+
+	chain.mine()
+	assert.Nil(t, m.CheckPending(context.Background()))
+	receipt, err := m.Wait(context.Background(), ptx)
+	assert.Nil(t, err)
+	assert.Equal(t, ptx.Hash(), receipt.TxHash)
+	_, err = m.Wait(context.Background(), other)
+	assert.Nil(t, err)
+	assert.Empty(t, m.PendingTxs())
+}
+
+func TestTxManagerInsufficientBalance(t *testing.T) {
+	chain := newFakeChain()
+	// 500000 gas at 10 gwei needs 0.005 ether
+	chain.balance = big.NewInt(4e15)
+	signer, err := connection.NewMockSigner(nil)
+	assert.Nil(t, err)
+	m := newTestTxManager(t, chain, signer, "")
+	to := common.HexToAddress("0x61135C59A4Eb452b89963188eD6B6a7487049764")
+
+	_, err = m.Send(context.Background(), "a", nil, 500000, buildTx(to, []byte{1}))
+	var balanceErr *connection.InsufficientBalanceError
+	assert.ErrorAs(t, err, &balanceErr)
+	assert.Equal(t, big.NewInt(5e15), balanceErr.Required)
+	assert.Empty(t, chain.sent)
+	assert.Empty(t, m.PendingTxs())
+}
+
+func TestTxManagerCancelObsolete(t *testing.T) {
+	chain := newFakeChain()
+	signer, err := connection.NewMockSigner(nil)
+	assert.Nil(t, err)
+	m := newTestTxManager(t, chain, signer, "")
+	to := common.HexToAddress("0x61135C59A4Eb452b89963188eD6B6a7487049764")
+	proposalId := common.HexToHash("0x01")
+
+	executed := false
+	m.SetObsoleteChecker(to, func(ptx *connection.PendingTx) (bool, error) {
+		assert.Equal(t, []common.Hash{proposalId}, ptx.ProposalIds)
+		return executed, nil
+	})
+	ptx, err := m.Send(context.Background(), "a", []common.Hash{proposalId}, 500000, buildTx(to, []byte{1}))
+	assert.Nil(t, err)
+
+	executed = true
+	time.Sleep(2 * time.Millisecond)
+	assert.Nil(t, m.CheckPending(context.Background()))
+	assert.True(t, ptx.Cancelled)
+	cancelTx := chain.sent[len(chain.sent)-1]
+	assert.Equal(t, signer.Address(), *cancelTx.To())
+	assert.Empty(t, cancelTx.Data())
+	assert.Equal(t, uint64(0), cancelTx.Nonce())
+
+	chain.mine()
+	assert.Nil(t, m.CheckPending(context.Background()))
+	_, err = m.Wait(context.Background(), ptx)
+	assert.ErrorIs(t, err, connection.ErrTxCancelled)
+}
+
+func TestTxManagerRestore(t *testing.T) {
+	chain := newFakeChain()
+	signer, err := connection.NewMockSigner(nil)
+	assert.Nil(t, err)
+	storeFile := filepath.Join(t.TempDir(), "pending_txs.json")
+	to := common.HexToAddress("0x61135C59A4Eb452b89963188eD6B6a7487049764")
+
+	ptx, err := newTestTxManager(t, chain, signer, storeFile).Send(context.Background(), "a", nil, 500000, buildTx(to, []byte{1}))
+	assert.Nil(t, err)
+
+	// after restart the pending tx still holds its nonce and its key
+	m := newTestTxManager(t, chain, signer, storeFile)
+	restored := m.PendingTxs()
+	assert.Len(t, restored, 1)
+	assert.Equal(t, ptx.Hash(), restored[0].Hash())
+	nonce, err := m.NextNonce(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(1), nonce)
+	same, err := m.Send(context.Background(), "a", nil, 500000, buildTx(to, []byte{1}))
+	assert.Nil(t, err)
+	assert.Equal(t, restored[0], same)
+
+	chain.mine()
+	assert.Nil(t, m.CheckPending(context.Background()))
+	_, err = m.Wait(context.Background(), same)
+	assert.Nil(t, err)
+	assert.Empty(t, newTestTxManager(t, chain, signer, storeFile).PendingTxs())
+}
@@ -0,0 +1,136 @@
+package connection
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/stafiprotocol/eth-lsd-relay/pkg/connection/beacon"
+	"github.com/stafiprotocol/eth-lsd-relay/pkg/connection/types"
+)
+
+// statuses of this many epochs are kept, the handlers of a round read a few epochs at most
+const validatorStatusCacheEpochs = 8
+
+// validatorStatusCache holds the statuses of validators at an epoch by (epoch, index), the index of a pubkey is
+// learned from its first status so later reads of it go by index
+type validatorStatusCache struct {
+	mutex    sync.RWMutex
+	statuses map[uint64]map[uint64]beacon.ValidatorStatus // epoch => index => status
+	indices  map[types.ValidatorPubkey]uint64
+}
+
+func newValidatorStatusCache() *validatorStatusCache {
+	return &validatorStatusCache{
+		statuses: make(map[uint64]map[uint64]beacon.ValidatorStatus),
+		indices:  make(map[types.ValidatorPubkey]uint64),
+	}
+}
+
+func (c *validatorStatusCache) get(epoch uint64, pubkey types.ValidatorPubkey) (beacon.ValidatorStatus, bool) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	index, ok := c.indices[pubkey]
+	if !ok {
+		return beacon.ValidatorStatus{}, false
+	}
+	status, ok := c.statuses[epoch][index]
+	return status, ok
+}
+
+func (c *validatorStatusCache) index(pubkey types.ValidatorPubkey) (uint64, bool) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	index, ok := c.indices[pubkey]
+	return index, ok
+}
+
+// put stores the statuses of existing validators, the oldest epochs are dropped beyond validatorStatusCacheEpochs
+func (c *validatorStatusCache) put(epoch uint64, statuses []beacon.ValidatorStatus) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	byIndex, ok := c.statuses[epoch]
+	if !ok {
+		byIndex = make(map[uint64]beacon.ValidatorStatus, len(statuses))
+		c.statuses[epoch] = byIndex
+	}
+	for _, status := range statuses {
+		if !status.Exists {
+			continue
+		}
+		byIndex[status.Index] = status
+		c.indices[status.Pubkey] = status.Index
+	}
+
+	if len(c.statuses) > validatorStatusCacheEpochs {
+		epochs := make([]uint64, 0, len(c.statuses))
+		for e := range c.statuses {
+			epochs = append(epochs, e)
+		}
+		sort.Slice(epochs, func(i, j int) bool { return epochs[i] < epochs[j] })
+		for _, e := range epochs[:len(epochs)-validatorStatusCacheEpochs] {
+			delete(c.statuses, e)
+		}
+	}
+}
+
+// cachedEpochOf returns the epoch of opts if the statuses are read at an epoch, reads of the head or a slot are not cached by epoch
+func cachedEpochOf(opts *beacon.ValidatorStatusOptions) (uint64, bool) {
+	if opts == nil || opts.Epoch == nil || opts.Slot != nil {
+		return 0, false
+	}
+	return *opts.Epoch, true
+}
+
+// GetValidatorStatuses reads statuses at an epoch from the cache, the validators missing from it are queried
+// by index if their index is known and by pubkey otherwise
+func (c *CachedConnection) GetValidatorStatuses(ctx context.Context, pubkeys []types.ValidatorPubkey, opts *beacon.ValidatorStatusOptions) (map[types.ValidatorPubkey]beacon.ValidatorStatus, error) {
+	epoch, ok := cachedEpochOf(opts)
+	if !ok {
+		return c.Connection.GetValidatorStatuses(ctx, pubkeys, opts)
+	}
+
+	statuses := make(map[types.ValidatorPubkey]beacon.ValidatorStatus, len(pubkeys))
+	missingIndices := make([]uint64, 0)
+	missingPubkeys := make([]types.ValidatorPubkey, 0)
+	for _, pubkey := range pubkeys {
+		if status, ok := c.statusCache.get(epoch, pubkey); ok {
+			statuses[pubkey] = status
+			continue
+		}
+		if index, ok := c.statusCache.index(pubkey); ok {
+			missingIndices = append(missingIndices, index)
+		} else {
+			missingPubkeys = append(missingPubkeys, pubkey)
+		}
+	}
+
+	if len(missingIndices) > 0 {
+		byIndex, err := c.Connection.GetValidatorStatusesByIndices(ctx, missingIndices, opts)
+		if err != nil {
+			return nil, err
+		}
+		fetched := make([]beacon.ValidatorStatus, 0, len(byIndex))
+		for _, status := range byIndex {
+			statuses[status.Pubkey] = status
+			fetched = append(fetched, status)
+		}
+		c.statusCache.put(epoch, fetched)
+	}
+
+	if len(missingPubkeys) > 0 {
+		byPubkey, err := c.Connection.GetValidatorStatuses(ctx, missingPubkeys, opts)
+		if err != nil {
+			return nil, err
+		}
+		fetched := make([]beacon.ValidatorStatus, 0, len(byPubkey))
+		for pubkey, status := range byPubkey {
+			statuses[pubkey] = status
+			fetched = append(fetched, status)
+		}
+		c.statusCache.put(epoch, fetched)
+	}
+
+	return statuses, nil
+}
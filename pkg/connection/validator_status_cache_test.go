@@ -0,0 +1,54 @@
+package connection
+
+import (
+	"testing"
+
+	"github.com/stafiprotocol/eth-lsd-relay/pkg/connection/beacon"
+	"github.com/stafiprotocol/eth-lsd-relay/pkg/connection/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidatorStatusCache(t *testing.T) {
+	pubkey := types.BytesToValidatorPubkey([]byte{1})
+	unknown := types.BytesToValidatorPubkey([]byte{2})
+	cache := newValidatorStatusCache()
+
+	cache.put(10, []beacon.ValidatorStatus{
+		{Pubkey: pubkey, Index: 5, Balance: 32e9, Exists: true},
+		{Pubkey: unknown},
+	})
+	status, ok := cache.get(10, pubkey)
+	assert.True(t, ok)
+	assert.Equal(t, uint64(32e9), status.Balance)
+	_, ok = cache.get(11, pubkey)
+	assert.False(t, ok)
+	index, ok := cache.index(pubkey)
+	assert.True(t, ok)
+	assert.Equal(t, uint64(5), index)
+
+	// statuses of validators not yet deposited are not cached
+	_, ok = cache.get(10, unknown)
+	assert.False(t, ok)
+	_, ok = cache.index(unknown)
+	assert.False(t, ok)
+
+	for epoch := uint64(11); epoch < 11+validatorStatusCacheEpochs; epoch++ {
+		cache.put(epoch, []beacon.ValidatorStatus{{Pubkey: pubkey, Index: 5, Exists: true}})
+	}
+	_, ok = cache.get(10, pubkey)
+	assert.False(t, ok)
+	_, ok = cache.get(11, pubkey)
+	assert.True(t, ok)
+	assert.Len(t, cache.statuses, validatorStatusCacheEpochs)
+}
+
+func TestCachedEpochOf(t *testing.T) {
+	epoch, slot := uint64(3), uint64(100)
+	_, ok := cachedEpochOf(nil)
+	assert.False(t, ok)
+	_, ok = cachedEpochOf(&beacon.ValidatorStatusOptions{Epoch: &epoch, Slot: &slot})
+	assert.False(t, ok)
+	cached, ok := cachedEpochOf(&beacon.ValidatorStatusOptions{Epoch: &epoch})
+	assert.True(t, ok)
+	assert.Equal(t, epoch, cached)
+}
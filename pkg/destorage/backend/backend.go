@@ -0,0 +1,82 @@
+package backend
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stafiprotocol/eth-lsd-relay/pkg/config"
+	"github.com/stafiprotocol/eth-lsd-relay/pkg/destorage"
+	"github.com/stafiprotocol/eth-lsd-relay/pkg/destorage/kubo"
+	"github.com/stafiprotocol/eth-lsd-relay/pkg/destorage/nftstorage"
+	"github.com/stafiprotocol/eth-lsd-relay/pkg/destorage/pinata"
+	"github.com/stafiprotocol/eth-lsd-relay/pkg/destorage/replicated"
+	"github.com/stafiprotocol/eth-lsd-relay/pkg/destorage/web3storage"
+	"github.com/stafiprotocol/eth-lsd-relay/pkg/utils"
+)
+
+const (
+	// kuboPinsFileName records files pinned to the kubo node, it lives in the log dir next to other reports
+	kuboPinsFileName = "kubo_pins.jsonl"
+	// archiveDirName keeps a copy of every file uploaded or downloaded by the replicated backend
+	archiveDirName = "destorage_archive"
+)
+
+// New builds the decentralized storage selected by cfg.Storage.Backend
+func New(cfg *config.Config, log *logrus.Entry) (destorage.DeStorage, error) {
+	if strings.ToLower(cfg.Storage.Backend) != config.StorageBackendReplicated {
+		return newBackend(cfg.Storage.Backend, cfg, log)
+	}
+
+	backends := make([]replicated.Backend, 0, len(cfg.Storage.Replicas))
+	for _, name := range cfg.Storage.Replicas {
+		if strings.ToLower(name) == config.StorageBackendReplicated {
+			return nil, fmt.Errorf("storage backend %s can not be a replica", name)
+		}
+		s, err := newBackend(name, cfg, log)
+		if err != nil {
+			return nil, err
+		}
+		backends = append(backends, replicated.Backend{Name: name, Storage: s})
+	}
+	s, err := replicated.NewStorage(backends, cfg.Storage.MinReplicas, filepath.Join(cfg.LogFilePath, archiveDirName), log)
+	if err != nil {
+		return nil, fmt.Errorf("fail to new replicated storage: %w", err)
+	}
+	return s, nil
+}
+
+func newBackend(backend string, cfg *config.Config, log *logrus.Entry) (destorage.DeStorage, error) {
+	switch strings.ToLower(backend) {
+	case "", config.StorageBackendPinata:
+		c, err := pinata.NewClient(cfg.Pinata.Endpoint, cfg.Pinata.Apikey)
+		if err != nil {
+			return nil, fmt.Errorf("fail to new pinata client: %w", err)
+		}
+		c.StartUnpinFiles(utils.Day * time.Duration(cfg.Pinata.PinDays))
+		return c, nil
+	case config.StorageBackendWeb3Storage:
+		s, err := web3storage.NewStorage(cfg.Web3Storage.ProofFile, cfg.Web3Storage.SpaceDid, cfg.Web3Storage.PrivateKey)
+		if err != nil {
+			return nil, fmt.Errorf("fail to new web3storage: %w", err)
+		}
+		return s, nil
+	case config.StorageBackendNftStorage:
+		s, err := nftstorage.NewNftStorage(cfg.NftStorage.Apikey, log)
+		if err != nil {
+			return nil, fmt.Errorf("fail to new nftstorage: %w", err)
+		}
+		return s, nil
+	case config.StorageBackendKubo:
+		c, err := kubo.NewClient(cfg.Kubo.Endpoint, filepath.Join(cfg.LogFilePath, kuboPinsFileName))
+		if err != nil {
+			return nil, fmt.Errorf("fail to new kubo client: %w", err)
+		}
+		c.StartUnpinFiles(utils.Day * time.Duration(cfg.Kubo.PinDays))
+		return c, nil
+	default:
+		return nil, fmt.Errorf("unsupported storage backend: %s", backend)
+	}
+}
@@ -0,0 +1,61 @@
+package backend_test
+
+import (
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stafiprotocol/eth-lsd-relay/pkg/config"
+	"github.com/stafiprotocol/eth-lsd-relay/pkg/destorage/backend"
+	"github.com/stafiprotocol/eth-lsd-relay/pkg/destorage/kubo"
+	"github.com/stafiprotocol/eth-lsd-relay/pkg/destorage/nftstorage"
+	"github.com/stafiprotocol/eth-lsd-relay/pkg/destorage/pinata"
+	"github.com/stafiprotocol/eth-lsd-relay/pkg/destorage/replicated"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNew(t *testing.T) {
+	log := logrus.NewEntry(logrus.StandardLogger())
+
+	dds, err := backend.New(&config.Config{Pinata: config.Pinata{Apikey: "key"}}, log)
+	assert.Nil(t, err)
+	assert.IsType(t, &pinata.Client{}, dds)
+
+	dds, err = backend.New(&config.Config{
+		Storage:    config.Storage{Backend: "NftStorage"},
+		NftStorage: config.NftStorage{Apikey: "key"},
+	}, log)
+	assert.Nil(t, err)
+	assert.IsType(t, &nftstorage.NftStorage{}, dds)
+
+	_, err = backend.New(&config.Config{Storage: config.Storage{Backend: config.StorageBackendNftStorage}}, log)
+	assert.NotNil(t, err)
+
+	_, err = backend.New(&config.Config{
+		Storage:     config.Storage{Backend: config.StorageBackendWeb3Storage},
+		Web3Storage: config.Web3Storage{PrivateKey: "bad", SpaceDid: "did:key:x", ProofFile: "missing"},
+	}, log)
+	assert.NotNil(t, err)
+
+	dds, err = backend.New(&config.Config{
+		LogFilePath: t.TempDir(),
+		Storage:     config.Storage{Backend: config.StorageBackendKubo},
+	}, log)
+	assert.Nil(t, err)
+	assert.IsType(t, &kubo.Client{}, dds)
+
+	dds, err = backend.New(&config.Config{
+		LogFilePath: t.TempDir(),
+		Storage:     config.Storage{Backend: config.StorageBackendReplicated, Replicas: []string{"pinata", "kubo"}},
+		Pinata:      config.Pinata{Apikey: "key"},
+	}, log)
+	assert.Nil(t, err)
+	assert.IsType(t, &replicated.Storage{}, dds)
+
+	_, err = backend.New(&config.Config{
+		Storage: config.Storage{Backend: config.StorageBackendReplicated, Replicas: []string{"replicated"}},
+	}, log)
+	assert.ErrorContains(t, err, "can not be a replica")
+
+	_, err = backend.New(&config.Config{Storage: config.Storage{Backend: "s3"}}, log)
+	assert.ErrorContains(t, err, "unsupported storage backend")
+}
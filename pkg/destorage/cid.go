@@ -0,0 +1,66 @@
+package destorage
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-unixfsnode/data/builder"
+	dagpb "github.com/ipld/go-codec-dagpb"
+	"github.com/ipld/go-ipld-prime"
+	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
+)
+
+// ComputeCid computes the CIDv1 of a directory wrapping a single file, the same way files are
+// added by the backends (default chunker, raw leaves), without storing any block
+func ComputeCid(fileName string, content []byte) (string, error) {
+	ls := discardLinkSystem()
+	fileLink, size, err := builder.BuildUnixFSFile(bytes.NewReader(content), "", &ls)
+	if err != nil {
+		return "", err
+	}
+	entry, err := builder.BuildUnixFSDirectoryEntry(fileName, int64(size), fileLink)
+	if err != nil {
+		return "", err
+	}
+	root, _, err := builder.BuildUnixFSDirectory([]dagpb.PBLink{entry}, &ls)
+	if err != nil {
+		return "", err
+	}
+	rcl, ok := root.(cidlink.Link)
+	if !ok {
+		return "", fmt.Errorf("could not interpret %s", root)
+	}
+	return rcl.Cid.String(), nil
+}
+
+// VerifyCid checks that content named fileName is what expectedCid points to, CIDv1 is recomputed
+// with ComputeCid and CIDv0 of files uploaded by older relays with ComputeCidV0
+func VerifyCid(expectedCid, fileName string, content []byte) error {
+	expected, err := cid.Decode(expectedCid)
+	if err != nil {
+		return fmt.Errorf("invalid cid %s: %w", expectedCid, err)
+	}
+	compute := ComputeCid
+	if expected.Version() == 0 {
+		compute = ComputeCidV0
+	}
+	computed, err := compute(fileName, content)
+	if err != nil {
+		return err
+	}
+	if computed != expected.String() {
+		return fmt.Errorf("cid mismatch, expected %s computed %s", expected.String(), computed)
+	}
+	return nil
+}
+
+func discardLinkSystem() ipld.LinkSystem {
+	ls := cidlink.DefaultLinkSystem()
+	ls.TrustedStorage = true
+	ls.StorageWriteOpener = func(_ ipld.LinkContext) (io.Writer, ipld.BlockWriteCommitter, error) {
+		return io.Discard, func(ipld.Link) error { return nil }, nil
+	}
+	return ls
+}
@@ -0,0 +1,101 @@
+package destorage
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-unixfsnode/data/builder"
+	"github.com/multiformats/go-multicodec"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestComputeCid(t *testing.T) {
+	// `ipfs add --cid-version=1` of "hello world"
+	ls := discardLinkSystem()
+	fileLink, _, err := builder.BuildUnixFSFile(bytes.NewReader([]byte("hello world")), "", &ls)
+	assert.Nil(t, err)
+	assert.Equal(t, "bafkreifzjut3te2nhyekklss27nh3k72ysco7y32koao5eei66wof36n5e", fileLink.String())
+
+	dirCid, err := ComputeCid("hello.txt", []byte("hello world"))
+	assert.Nil(t, err)
+	decoded, err := cid.Decode(dirCid)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(1), decoded.Version())
+	assert.Equal(t, uint64(multicodec.DagPb), decoded.Type())
+
+	otherName, err := ComputeCid("hello2.txt", []byte("hello world"))
+	assert.Nil(t, err)
+	assert.NotEqual(t, dirCid, otherName)
+
+	assert.Nil(t, VerifyCid(dirCid, "hello.txt", []byte("hello world")))
+	assert.ErrorContains(t, VerifyCid(dirCid, "hello.txt", []byte("hello world!")), "cid mismatch")
+	// cid v0 is recomputed too
+	assert.ErrorContains(t, VerifyCid("QmT78zSuBmuS4z925WZfrqQ1qHaJ56DQaTfyMUF7F8ff5o", "hello.txt", []byte("hello world")), "cid mismatch")
+	assert.NotNil(t, VerifyCid("not-a-cid", "hello.txt", nil))
+
+	// files larger than one chunk
+	big := bytes.Repeat([]byte{1}, 1<<20)
+	bigCid, err := ComputeCid("big.bin", big)
+	assert.Nil(t, err)
+	assert.Nil(t, VerifyCid(bigCid, "big.bin", big))
+}
+
+// patternBytes returns n bytes of a pattern that does not align with the 256 KiB chunks
+func patternBytes(n int) []byte {
+	content := make([]byte, n)
+	for i := range content {
+		content[i] = byte(i % 251)
+	}
+	return content
+}
+
+func TestComputeCidGolden(t *testing.T) {
+	// root cids of `ipfs add -w --cid-version 1 <file>`: raw leaves, size-262144 chunker, balanced
+	// layout with 174 links per node, the layout pinata pinFileToIPFS uses for folders with cidVersion 1
+	cases := []struct {
+		fileName string
+		content  []byte
+		cid      string
+	}{
+		{"hello.txt", []byte("hello world"), "bafybeic6svhkwl3y2wvkj33weshyjjs5cbvgijh7yo3kjasyglrdwe2l74"},
+		// two chunks, the last one partial
+		{"big.bin", patternBytes(300 * 1024), "bafybeiga22suwv42kyrwgk5i3nosb4egrdrtvysfsl6uwg4mgqgrhklvim"},
+		// twelve full chunks
+		{"big.bin", patternBytes(3 << 20), "bafybeiamcaul6mxbprcsq6s4qdqoxk3vp2gbcwjlp5tc2h7v3tl2sbpwcq"},
+	}
+	for _, c := range cases {
+		computed, err := ComputeCid(c.fileName, c.content)
+		assert.Nil(t, err)
+		assert.Equal(t, c.cid, computed, "%s of %d bytes", c.fileName, len(c.content))
+		assert.Nil(t, VerifyCid(c.cid, c.fileName, c.content))
+	}
+}
+
+func TestComputeCidV0Golden(t *testing.T) {
+	// root cids of `ipfs add -w <file>`: dag-pb leaves, size-262144 chunker, balanced layout with
+	// 174 links per node, the layout of files uploaded by older relays
+	cases := []struct {
+		fileName string
+		content  []byte
+		cid      string
+	}{
+		{"hello.txt", []byte("hello world"), "QmNxvA5bwvPGgMXbmtyhxA1cKFdvQXnsGnZLCGor3AzYxJ"},
+		// two chunks, the last one partial
+		{"big.bin", patternBytes(300 * 1024), "QmTiZ2a4QwDp11qgLmtSBNBUKJj6Y7MsZTsZzHfmRxSgLY"},
+		// twelve full chunks
+		{"big.bin", patternBytes(3 << 20), "QmZx4dhfuc3eDaV8DV8nFFREfErKsvSgaUsKr2SCehuwtT"},
+		// more chunks than links per node, two levels of internal nodes
+		{"big.bin", patternBytes(175*chunkSizeV0 + 1000), "Qma6LnBB3oMLQ1gs6a7qR7yTmwSTW8FYat5CKCvbFm9qv7"},
+		// an empty file is a leaf without data
+		{"e", nil, "QmafmSquGoThpY9CFG8iwfizHaJUxK23WBrGRMYqiHgSD4"},
+	}
+	for _, c := range cases {
+		computed, err := ComputeCidV0(c.fileName, c.content)
+		assert.Nil(t, err)
+		assert.Equal(t, c.cid, computed, "%s of %d bytes", c.fileName, len(c.content))
+		assert.Nil(t, VerifyCid(c.cid, c.fileName, c.content))
+	}
+	assert.ErrorContains(t, VerifyCid(cases[0].cid, "hello.txt", []byte("hello world!")), "cid mismatch")
+	assert.ErrorContains(t, VerifyCid(cases[0].cid, "hello2.txt", []byte("hello world")), "cid mismatch")
+}
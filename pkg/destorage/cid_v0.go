@@ -0,0 +1,173 @@
+package destorage
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-unixfsnode/data"
+	"github.com/ipfs/go-unixfsnode/data/builder"
+	dagpb "github.com/ipld/go-codec-dagpb"
+	"github.com/ipld/go-ipld-prime"
+	"github.com/ipld/go-ipld-prime/fluent/qp"
+	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
+	"github.com/multiformats/go-multihash"
+)
+
+// chunkSizeV0 is the size of the default chunker of kubo
+const chunkSizeV0 = 256 * 1024
+
+var prefixV0 = cid.Prefix{Version: 0, Codec: cid.DagProtobuf, MhType: multihash.SHA2_256, MhLength: -1}
+
+type fileShardV0 struct {
+	cid        cid.Cid
+	byteSize   uint64
+	storedSize uint64
+}
+
+// ComputeCidV0 computes the CIDv0 of a directory wrapping a single file, the way `ipfs add -w`
+// adds it by default (size-262144 chunker, dag-pb leaves, balanced layout), without storing any block
+func ComputeCidV0(fileName string, content []byte) (string, error) {
+	file, err := buildFileV0(content)
+	if err != nil {
+		return "", err
+	}
+	entry, err := builder.BuildUnixFSDirectoryEntry(fileName, int64(file.storedSize), cidlink.Link{Cid: file.cid})
+	if err != nil {
+		return "", err
+	}
+	ufd, err := builder.BuildUnixFS(func(b *builder.Builder) {
+		builder.DataType(b, data.Data_Directory)
+	})
+	if err != nil {
+		return "", err
+	}
+	root, err := storeNodeV0(data.EncodeUnixFSData(ufd), []dagpb.PBLink{entry})
+	if err != nil {
+		return "", err
+	}
+	return root.cid.String(), nil
+}
+
+// buildFileV0 grows the balanced tree one level at a time until the root stops changing,
+// the same way builder.BuildUnixFSFile does for CIDv1
+func buildFileV0(content []byte) (fileShardV0, error) {
+	src := bytes.NewReader(content)
+	var prev []fileShardV0
+	for depth := 1; ; depth++ {
+		next, err := fileTreeV0(depth, prev, src)
+		if err != nil {
+			return fileShardV0{}, err
+		}
+		if prev != nil && prev[0].cid == next.cid {
+			break
+		}
+		prev = []fileShardV0{next}
+	}
+	if !prev[0].cid.Defined() {
+		// an empty file is a single leaf without data
+		return storeLeafV0(nil)
+	}
+	return prev[0], nil
+}
+
+func fileTreeV0(depth int, children []fileShardV0, src *bytes.Reader) (fileShardV0, error) {
+	if depth == 1 {
+		if len(children) > 0 {
+			return fileShardV0{}, fmt.Errorf("leaf nodes cannot have children")
+		}
+		if src.Len() == 0 {
+			return fileShardV0{}, nil
+		}
+		leaf := make([]byte, min(src.Len(), chunkSizeV0))
+		if _, err := src.Read(leaf); err != nil {
+			return fileShardV0{}, err
+		}
+		return storeLeafV0(leaf)
+	}
+
+	for len(children) < builder.DefaultLinksPerBlock {
+		next, err := fileTreeV0(depth-1, nil, src)
+		if err != nil {
+			return fileShardV0{}, err
+		}
+		if !next.cid.Defined() {
+			break
+		}
+		children = append(children, next)
+	}
+	switch len(children) {
+	case 0:
+		return fileShardV0{}, nil
+	case 1:
+		return children[0], nil
+	}
+
+	var byteSize, childrenStoredSize uint64
+	byteSizes := make([]uint64, 0, len(children))
+	links := make([]dagpb.PBLink, 0, len(children))
+	for _, c := range children {
+		byteSize += c.byteSize
+		childrenStoredSize += c.storedSize
+		byteSizes = append(byteSizes, c.byteSize)
+		link, err := builder.BuildUnixFSDirectoryEntry("", int64(c.storedSize), cidlink.Link{Cid: c.cid})
+		if err != nil {
+			return fileShardV0{}, err
+		}
+		links = append(links, link)
+	}
+	ufd, err := builder.BuildUnixFS(func(b *builder.Builder) {
+		builder.FileSize(b, byteSize)
+		builder.BlockSizes(b, byteSizes)
+	})
+	if err != nil {
+		return fileShardV0{}, err
+	}
+	node, err := storeNodeV0(data.EncodeUnixFSData(ufd), links)
+	if err != nil {
+		return fileShardV0{}, err
+	}
+	return fileShardV0{cid: node.cid, byteSize: byteSize, storedSize: node.storedSize + childrenStoredSize}, nil
+}
+
+func storeLeafV0(leaf []byte) (fileShardV0, error) {
+	ufd, err := builder.BuildUnixFS(func(b *builder.Builder) {
+		if len(leaf) > 0 {
+			builder.Data(b, leaf)
+		}
+		builder.FileSize(b, uint64(len(leaf)))
+	})
+	if err != nil {
+		return fileShardV0{}, err
+	}
+	node, err := storeNodeV0(data.EncodeUnixFSData(ufd), nil)
+	if err != nil {
+		return fileShardV0{}, err
+	}
+	node.byteSize = uint64(len(leaf))
+	return node, nil
+}
+
+// storeNodeV0 encodes a dag-pb node and returns its CIDv0 and block size
+func storeNodeV0(ufsData []byte, links []dagpb.PBLink) (fileShardV0, error) {
+	node, err := qp.BuildMap(dagpb.Type.PBNode, 2, func(ma ipld.MapAssembler) {
+		qp.MapEntry(ma, "Data", qp.Bytes(ufsData))
+		qp.MapEntry(ma, "Links", qp.List(int64(len(links)), func(la ipld.ListAssembler) {
+			for _, l := range links {
+				qp.ListEntry(la, qp.Node(l))
+			}
+		}))
+	})
+	if err != nil {
+		return fileShardV0{}, err
+	}
+	var buf bytes.Buffer
+	if err := dagpb.Encode(node, &buf); err != nil {
+		return fileShardV0{}, err
+	}
+	c, err := prefixV0.Sum(buf.Bytes())
+	if err != nil {
+		return fileShardV0{}, err
+	}
+	return fileShardV0{cid: c, storedSize: uint64(buf.Len())}, nil
+}
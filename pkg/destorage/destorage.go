@@ -1,5 +1,10 @@
 package destorage
 
+import "errors"
+
+// ErrNotFound is returned by DownloadFile if the directory of cid has no file named fileName
+var ErrNotFound = errors.New("file not found")
+
 type DeStorage interface {
 	DownloadFile(cid, fileName string) (content []byte, err error)
 	// Upload a file to Decentralized Storage and get the CID for it
@@ -0,0 +1,281 @@
+package kubo
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/stafiprotocol/eth-lsd-relay/pkg/destorage"
+	"github.com/stafiprotocol/eth-lsd-relay/pkg/utils"
+)
+
+var _ destorage.DeStorage = &Client{}
+
+const defaultEndpoint = "http://127.0.0.1:5001"
+
+// pinsMutex guards pins files, clients of different lsd tokens may share one
+var pinsMutex sync.Mutex
+
+// Client talks to the http rpc api of a kubo (go-ipfs) node, files uploaded by it are
+// recorded in pinsFile so that they can be unpinned after some days
+type Client struct {
+	endpoint   string
+	pinsFile   string
+	httpClient *http.Client
+}
+
+type pinRecord struct {
+	Cid      string `json:"cid"`
+	PinnedAt int64  `json:"pinnedAt"`
+}
+
+func NewClient(endpoint, pinsFile string) (*Client, error) {
+	if endpoint == "" {
+		endpoint = defaultEndpoint
+	}
+	endpoint = strings.TrimSuffix(endpoint, "/")
+	if _, err := url.ParseRequestURI(endpoint); err != nil {
+		return nil, fmt.Errorf("invalid kubo endpoint: %w", err)
+	}
+
+	return &Client{
+		endpoint:   endpoint,
+		pinsFile:   pinsFile,
+		httpClient: &http.Client{Timeout: time.Minute},
+	}, nil
+}
+
+func (c *Client) StartUnpinFiles(pinDur time.Duration) {
+	if pinDur <= 0 || c.pinsFile == "" {
+		return
+	}
+
+	utils.SafeGoWithRestart(func() {
+		for {
+			count, err := c.UnpinFilesCreatedBefore(time.Now().Add(-pinDur))
+			if err != nil {
+				slog.Error("[kubo]: fail to unpin outdated files", "err", err)
+			}
+			if count > 0 {
+				slog.Info("[kubo]: successfully unpinned outdated files", "count", count)
+			}
+			time.Sleep(time.Hour * 24)
+		}
+	})
+}
+
+func (c *Client) DownloadFile(cid, fileName string) (content []byte, err error) {
+	query := url.Values{"arg": {cid + "/" + fileName}}
+	rsp, err := c.post("cat", query, nil, "")
+	if err != nil {
+		return nil, err
+	}
+	defer rsp.Body.Close()
+
+	bodyBytes, err := io.ReadAll(rsp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if len(bodyBytes) == 0 {
+		return nil, fmt.Errorf("bodyBytes zero err")
+	}
+	return bodyBytes, nil
+}
+
+type addResponse struct {
+	Name string `json:"Name"`
+	Hash string `json:"Hash"`
+}
+
+// UploadFile adds the file wrapped in a directory and pins it, the cid of the directory is returned
+func (c *Client) UploadFile(content []byte, path string) (cid string, err error) {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, err := writer.CreateFormFile("file", filepath.Base(path))
+	if err != nil {
+		return "", err
+	}
+	if _, err = part.Write(content); err != nil {
+		return "", err
+	}
+	if err = writer.Close(); err != nil {
+		return "", err
+	}
+
+	query := url.Values{
+		"wrap-with-directory": {"true"},
+		"cid-version":         {"1"},
+		"pin":                 {"true"},
+	}
+	rsp, err := c.post("add", query, body, writer.FormDataContentType())
+	if err != nil {
+		return "", err
+	}
+	defer rsp.Body.Close()
+
+	// one json object per added entry, the wrapping directory has an empty name
+	decoder := json.NewDecoder(rsp.Body)
+	for decoder.More() {
+		var entry addResponse
+		if err = decoder.Decode(&entry); err != nil {
+			return "", fmt.Errorf("fail to decode response: %w", err)
+		}
+		if entry.Name == "" {
+			cid = entry.Hash
+		}
+	}
+	if cid == "" {
+		return "", errors.New("no directory cid in add response")
+	}
+
+	if err = c.recordPin(cid, time.Now()); err != nil {
+		return "", fmt.Errorf("fail to record pin: %w", err)
+	}
+	return cid, nil
+}
+
+// UnpinFilesCreatedBefore unpins files recorded in pinsFile which were pinned before the given time
+func (c *Client) UnpinFilesCreatedBefore(before time.Time) (count int, err error) {
+	if c.pinsFile == "" {
+		return 0, nil
+	}
+	pinsMutex.Lock()
+	defer pinsMutex.Unlock()
+
+	records, err := c.readPins()
+	if err != nil {
+		return 0, err
+	}
+	remain := make([]pinRecord, 0, len(records))
+	for i, record := range records {
+		if record.PinnedAt >= before.Unix() {
+			remain = append(remain, record)
+			continue
+		}
+		if err = c.Unpin(record.Cid); err != nil {
+			remain = append(remain, records[i:]...)
+			break
+		}
+		count++
+	}
+	if writeErr := c.writePins(remain); writeErr != nil {
+		return count, errors.Join(err, writeErr)
+	}
+	return count, err
+}
+
+// Unpin removes the recursive pin of cid, it is not an error if cid is not pinned
+func (c *Client) Unpin(cid string) error {
+	rsp, err := c.post("pin/rm", url.Values{"arg": {cid}}, nil, "")
+	if err != nil {
+		if strings.Contains(err.Error(), "not pinned") {
+			return nil
+		}
+		return err
+	}
+	return rsp.Body.Close()
+}
+
+func (c *Client) recordPin(cid string, pinnedAt time.Time) error {
+	if c.pinsFile == "" {
+		return nil
+	}
+	pinsMutex.Lock()
+	defer pinsMutex.Unlock()
+
+	line, err := json.Marshal(pinRecord{Cid: cid, PinnedAt: pinnedAt.Unix()})
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(c.pinsFile, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(append(line, '\n'))
+	return err
+}
+
+func (c *Client) readPins() ([]pinRecord, error) {
+	f, err := os.Open(c.pinsFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	records := make([]pinRecord, 0)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var record pinRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return nil, fmt.Errorf("fail to decode pins file: %w", err)
+		}
+		records = append(records, record)
+	}
+	return records, scanner.Err()
+}
+
+func (c *Client) writePins(records []pinRecord) error {
+	buf := &bytes.Buffer{}
+	for _, record := range records {
+		line, err := json.Marshal(record)
+		if err != nil {
+			return err
+		}
+		buf.Write(append(line, '\n'))
+	}
+	tmp := c.pinsFile + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, c.pinsFile)
+}
+
+// post calls the rpc api, kubo only accepts POST
+func (c *Client) post(method string, query url.Values, body io.Reader, contentType string) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("%s/api/v0/%s?%s", c.endpoint, method, query.Encode()), body)
+	if err != nil {
+		return nil, fmt.Errorf("fail to create request: %w", err)
+	}
+	if contentType != "" {
+		req.Header.Set("content-type", contentType)
+	}
+
+	rsp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fail to send request: %w", err)
+	}
+	if rsp.StatusCode != http.StatusOK {
+		defer rsp.Body.Close()
+		// errors are returned as {"Message": "...", "Code": 0, "Type": "error"}
+		var kuboErr struct {
+			Message string `json:"Message"`
+		}
+		_ = json.NewDecoder(rsp.Body).Decode(&kuboErr)
+		err = fmt.Errorf("kubo %s returned an error: %d %s", method, rsp.StatusCode, kuboErr.Message)
+		// a missing path is a 500 too, only the message tells it apart
+		if strings.Contains(kuboErr.Message, "no link named") || strings.Contains(kuboErr.Message, "not found") {
+			return nil, fmt.Errorf("%w: %w", destorage.ErrNotFound, err)
+		}
+		return nil, err
+	}
+	return rsp, nil
+}
@@ -0,0 +1,136 @@
+package kubo_test
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stafiprotocol/eth-lsd-relay/pkg/destorage"
+	"github.com/stafiprotocol/eth-lsd-relay/pkg/destorage/kubo"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeKubo serves the part of the kubo rpc api used by the client
+type fakeKubo struct {
+	mu     sync.Mutex
+	files  map[string][]byte // "<dirCid>/<name>" => content
+	pinned map[string]bool
+}
+
+func newFakeKubo() *fakeKubo {
+	return &fakeKubo{files: make(map[string][]byte), pinned: make(map[string]bool)}
+}
+
+func (f *fakeKubo) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	arg := r.URL.Query().Get("arg")
+	switch r.URL.Path {
+	case "/api/v0/add":
+		if r.URL.Query().Get("wrap-with-directory") != "true" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		content, _ := io.ReadAll(file)
+		sum := sha256.Sum256(append([]byte(header.Filename), content...))
+		fileCid, dirCid := fmt.Sprintf("bafyfile%x", sum[:8]), fmt.Sprintf("bafydir%x", sum[:8])
+		f.files[dirCid+"/"+header.Filename] = content
+		f.pinned[dirCid] = true
+		encoder := json.NewEncoder(w)
+		_ = encoder.Encode(map[string]string{"Name": header.Filename, "Hash": fileCid})
+		_ = encoder.Encode(map[string]string{"Name": "", "Hash": dirCid})
+	case "/api/v0/cat":
+		content, exist := f.files[arg]
+		if !exist {
+			w.WriteHeader(http.StatusInternalServerError)
+			_ = json.NewEncoder(w).Encode(map[string]string{"Message": "no link named"})
+			return
+		}
+		_, _ = w.Write(content)
+	case "/api/v0/pin/rm":
+		if !f.pinned[arg] {
+			w.WriteHeader(http.StatusInternalServerError)
+			_ = json.NewEncoder(w).Encode(map[string]string{"Message": "not pinned or pinned indirectly"})
+			return
+		}
+		delete(f.pinned, arg)
+		_ = json.NewEncoder(w).Encode(map[string][]string{"Pins": {arg}})
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestUploadAndDownload(t *testing.T) {
+	server := httptest.NewServer(newFakeKubo())
+	defer server.Close()
+
+	client, err := kubo.NewClient(server.URL+"/", "")
+	assert.Nil(t, err)
+
+	fileName := "0xlsd-rewards-1-100.json"
+	fileContent := []byte(`{"list":[]}`)
+	cid, err := client.UploadFile(fileContent, "/tmp/"+fileName)
+	assert.Nil(t, err)
+	assert.True(t, strings.HasPrefix(cid, "bafydir"))
+
+	content, err := client.DownloadFile(cid, fileName)
+	assert.Nil(t, err)
+	assert.Equal(t, fileContent, content)
+
+	_, err = client.DownloadFile(cid, "other.json")
+	assert.ErrorContains(t, err, "no link named")
+	assert.ErrorIs(t, err, destorage.ErrNotFound)
+}
+
+func TestUnpinFilesCreatedBefore(t *testing.T) {
+	fake := newFakeKubo()
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	pinsFile := filepath.Join(t.TempDir(), "pins.jsonl")
+	client, err := kubo.NewClient(server.URL, pinsFile)
+	assert.Nil(t, err)
+
+	// a file pinned two days ago, recorded in the format of the pins file
+	oldCid := "bafydirold"
+	fake.pinned[oldCid] = true
+	oldRecord, err := json.Marshal(map[string]interface{}{"cid": oldCid, "pinnedAt": time.Now().Add(-48 * time.Hour).Unix()})
+	assert.Nil(t, err)
+	assert.Nil(t, os.WriteFile(pinsFile, append(oldRecord, '\n'), 0600))
+
+	newCid, err := client.UploadFile([]byte("new"), "new.json")
+	assert.Nil(t, err)
+
+	count, err := client.UnpinFilesCreatedBefore(time.Now().Add(-24 * time.Hour))
+	assert.Nil(t, err)
+	assert.Equal(t, 1, count)
+	assert.False(t, fake.pinned[oldCid])
+	assert.True(t, fake.pinned[newCid])
+
+	// files already unpinned on the node are dropped from the records as well
+	delete(fake.pinned, newCid)
+	count, err = client.UnpinFilesCreatedBefore(time.Now().Add(time.Second))
+	assert.Nil(t, err)
+	assert.Equal(t, 1, count)
+	count, err = client.UnpinFilesCreatedBefore(time.Now().Add(time.Second))
+	assert.Nil(t, err)
+	assert.Equal(t, 0, count)
+}
@@ -33,6 +33,9 @@ func (s *NftStorage) DownloadFile(cid string, fileName string) (content []byte,
 	}
 	defer rsp.Body.Close()
 
+	if rsp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("%w: rsp status err %d", destorage.ErrNotFound, rsp.StatusCode)
+	}
 	if rsp.StatusCode != http.StatusOK {
 		return nil, fmt.Errorf("rsp status err %d", rsp.StatusCode)
 	}
@@ -70,6 +70,9 @@ func (c *Client) DownloadFile(cid, fileName string) (content []byte, err error)
 	}
 	defer rsp.Body.Close()
 
+	if rsp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("%w: rsp status err %d", destorage.ErrNotFound, rsp.StatusCode)
+	}
 	if rsp.StatusCode != http.StatusOK {
 		return nil, fmt.Errorf("rsp status err %d", rsp.StatusCode)
 	}
@@ -0,0 +1,141 @@
+package replicated
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stafiprotocol/eth-lsd-relay/pkg/destorage"
+)
+
+var _ destorage.DeStorage = &Storage{}
+
+type Backend struct {
+	Name    string
+	Storage destorage.DeStorage
+}
+
+// Storage uploads files to all backends and keeps a local archive copy, downloads fall back
+// across the archive and the backends until the content matches the cid
+type Storage struct {
+	backends    []Backend
+	minReplicas int
+	archiveDir  string
+	log         *logrus.Entry
+}
+
+// NewStorage builds a storage over backends, uploads fail unless minReplicas of them store the file,
+// 0 requires all of them
+func NewStorage(backends []Backend, minReplicas int, archiveDir string, log *logrus.Entry) (*Storage, error) {
+	if len(backends) == 0 {
+		return nil, fmt.Errorf("no backend")
+	}
+	if minReplicas < 0 || minReplicas > len(backends) {
+		return nil, fmt.Errorf("minReplicas %d out of range [0, %d]", minReplicas, len(backends))
+	}
+	if minReplicas == 0 {
+		minReplicas = len(backends)
+	}
+	if log == nil {
+		return nil, fmt.Errorf("logger can not be nil")
+	}
+	if archiveDir != "" {
+		if err := os.MkdirAll(archiveDir, 0700); err != nil {
+			return nil, fmt.Errorf("fail to create archive dir: %w", err)
+		}
+	}
+	return &Storage{
+		backends:    backends,
+		minReplicas: minReplicas,
+		archiveDir:  archiveDir,
+		log:         log,
+	}, nil
+}
+
+// UploadFile succeeds if at least minReplicas backends store the file and every cid returned
+// matches the one computed locally, every backend is tried even if some fail
+func (s *Storage) UploadFile(content []byte, path string) (cid string, err error) {
+	fileName := filepath.Base(path)
+	cid, err = destorage.ComputeCid(fileName, content)
+	if err != nil {
+		return "", fmt.Errorf("fail to compute cid: %w", err)
+	}
+	if err = s.archive(cid, fileName, content); err != nil {
+		return "", err
+	}
+
+	uploaded := 0
+	var errs error
+	for _, backend := range s.backends {
+		backendCid, err := backend.Storage.UploadFile(content, path)
+		if err != nil {
+			s.log.WithFields(logrus.Fields{
+				"backend": backend.Name,
+				"file":    fileName,
+			}).WithError(err).Warn("upload to backend failed")
+			errs = errors.Join(errs, fmt.Errorf("%s: %w", backend.Name, err))
+			continue
+		}
+		if backendCid != cid {
+			return "", fmt.Errorf("backend %s returned cid %s, expected %s", backend.Name, backendCid, cid)
+		}
+		uploaded++
+	}
+	if uploaded < s.minReplicas {
+		return "", fmt.Errorf("uploaded to %d of %d backends, %d required: %w", uploaded, len(s.backends), s.minReplicas, errs)
+	}
+	return cid, nil
+}
+
+func (s *Storage) DownloadFile(cid, fileName string) (content []byte, err error) {
+	if content, err := s.readArchive(cid, fileName); err == nil {
+		if err = destorage.VerifyCid(cid, fileName, content); err == nil {
+			return content, nil
+		}
+		s.log.WithField("cid", cid).WithError(err).Warn("archive copy is invalid")
+	}
+
+	var errs error
+	for _, backend := range s.backends {
+		content, err := backend.Storage.DownloadFile(cid, fileName)
+		if err == nil {
+			err = destorage.VerifyCid(cid, fileName, content)
+		}
+		if err != nil {
+			s.log.WithFields(logrus.Fields{
+				"backend": backend.Name,
+				"cid":     cid,
+			}).WithError(err).Warn("download from backend failed")
+			errs = errors.Join(errs, fmt.Errorf("%s: %w", backend.Name, err))
+			continue
+		}
+		if err = s.archive(cid, fileName, content); err != nil {
+			s.log.WithError(err).Warn("archive downloaded file failed")
+		}
+		return content, nil
+	}
+	return nil, fmt.Errorf("download %s/%s from all backends failed: %w", cid, fileName, errs)
+}
+
+func (s *Storage) archivePath(cid, fileName string) string {
+	return filepath.Join(s.archiveDir, cid, filepath.Base(fileName))
+}
+
+func (s *Storage) archive(cid, fileName string, content []byte) error {
+	if s.archiveDir == "" {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Join(s.archiveDir, cid), 0700); err != nil {
+		return fmt.Errorf("fail to create archive dir: %w", err)
+	}
+	return os.WriteFile(s.archivePath(cid, fileName), content, 0600)
+}
+
+func (s *Storage) readArchive(cid, fileName string) ([]byte, error) {
+	if s.archiveDir == "" {
+		return nil, os.ErrNotExist
+	}
+	return os.ReadFile(s.archivePath(cid, fileName))
+}
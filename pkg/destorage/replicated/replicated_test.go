@@ -0,0 +1,138 @@
+package replicated_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stafiprotocol/eth-lsd-relay/pkg/destorage"
+	"github.com/stafiprotocol/eth-lsd-relay/pkg/destorage/replicated"
+	"github.com/stretchr/testify/assert"
+)
+
+// memStorage behaves like a backend wrapping uploaded files in a directory
+type memStorage struct {
+	files   map[string][]byte
+	failing bool
+	cid     string // returned instead of the real cid if set
+}
+
+func newMemStorage() *memStorage {
+	return &memStorage{files: make(map[string][]byte)}
+}
+
+func (m *memStorage) DownloadFile(cid, fileName string) ([]byte, error) {
+	if m.failing {
+		return nil, errors.New("unavailable")
+	}
+	content, exist := m.files[cid+"/"+fileName]
+	if !exist {
+		return nil, destorage.ErrNotFound
+	}
+	return content, nil
+}
+
+func (m *memStorage) UploadFile(content []byte, path string) (string, error) {
+	if m.failing {
+		return "", errors.New("unavailable")
+	}
+	cid, err := destorage.ComputeCid(filepath.Base(path), content)
+	if err != nil {
+		return "", err
+	}
+	m.files[cid+"/"+filepath.Base(path)] = content
+	if m.cid != "" {
+		return m.cid, nil
+	}
+	return cid, nil
+}
+
+func newStorage(t *testing.T, archiveDir string, backends ...*memStorage) *replicated.Storage {
+	return newStorageWithMinReplicas(t, archiveDir, 0, backends...)
+}
+
+func newStorageWithMinReplicas(t *testing.T, archiveDir string, minReplicas int, backends ...*memStorage) *replicated.Storage {
+	list := make([]replicated.Backend, 0, len(backends))
+	for i, b := range backends {
+		list = append(list, replicated.Backend{Name: string(rune('a' + i)), Storage: b})
+	}
+	s, err := replicated.NewStorage(list, minReplicas, archiveDir, logrus.NewEntry(logrus.StandardLogger()))
+	assert.Nil(t, err)
+	return s
+}
+
+func TestUploadFile(t *testing.T) {
+	fileName := "0xlsd-rewards-1-100.json"
+	content := []byte(`{"list":[]}`)
+	expected, err := destorage.ComputeCid(fileName, content)
+	assert.Nil(t, err)
+
+	a, b := newMemStorage(), newMemStorage()
+	archiveDir := t.TempDir()
+	cid, err := newStorage(t, archiveDir, a, b).UploadFile(content, fileName)
+	assert.Nil(t, err)
+	assert.Equal(t, expected, cid)
+	assert.Len(t, a.files, 1)
+	assert.Len(t, b.files, 1)
+	archived, err := os.ReadFile(filepath.Join(archiveDir, cid, fileName))
+	assert.Nil(t, err)
+	assert.Equal(t, content, archived)
+
+	// one backend down, all are required by default
+	a, b = newMemStorage(), newMemStorage()
+	b.failing = true
+	_, err = newStorage(t, "", a, b).UploadFile(content, fileName)
+	assert.ErrorContains(t, err, "uploaded to 1 of 2 backends, 2 required")
+	assert.Len(t, a.files, 1)
+	cid, err = newStorageWithMinReplicas(t, "", 1, a, b).UploadFile(content, fileName)
+	assert.Nil(t, err)
+	assert.Equal(t, expected, cid)
+
+	// all backends down
+	a.failing = true
+	_, err = newStorageWithMinReplicas(t, "", 1, a, b).UploadFile(content, fileName)
+	assert.ErrorContains(t, err, "uploaded to 0 of 2 backends, 1 required")
+
+	_, err = replicated.NewStorage([]replicated.Backend{{Name: "a", Storage: a}}, 2, "", logrus.NewEntry(logrus.StandardLogger()))
+	assert.ErrorContains(t, err, "minReplicas 2 out of range")
+
+	// backend disagrees
+	a, b = newMemStorage(), newMemStorage()
+	b.cid = "bafybeigdyrzt5sfp7udm7hu76uh7y26nf3efuylqabf3oclgtqy55fbzdi"
+	_, err = newStorage(t, "", a, b).UploadFile(content, fileName)
+	assert.ErrorContains(t, err, "backend b returned cid")
+}
+
+func TestDownloadFile(t *testing.T) {
+	fileName := "0xlsd-rewards-1-100.json"
+	content := []byte(`{"list":[]}`)
+	cid, err := destorage.ComputeCid(fileName, content)
+	assert.Nil(t, err)
+
+	// first backend lost the file, second serves corrupted content, third is fine
+	a, b, c := newMemStorage(), newMemStorage(), newMemStorage()
+	b.files[cid+"/"+fileName] = []byte(`{"list":[1]}`)
+	c.files[cid+"/"+fileName] = content
+	archiveDir := t.TempDir()
+	s := newStorage(t, archiveDir, a, b, c)
+	downloaded, err := s.DownloadFile(cid, fileName)
+	assert.Nil(t, err)
+	assert.Equal(t, content, downloaded)
+
+	// missing everywhere
+	_, err = s.DownloadFile(cid, "other.json")
+	assert.ErrorIs(t, err, destorage.ErrNotFound)
+
+	// archived after download, served even if all backends are down
+	a.failing, b.failing, c.failing = true, true, true
+	downloaded, err = s.DownloadFile(cid, fileName)
+	assert.Nil(t, err)
+	assert.Equal(t, content, downloaded)
+
+	// a tampered archive copy is not trusted
+	assert.Nil(t, os.WriteFile(filepath.Join(archiveDir, cid, fileName), []byte("x"), 0600))
+	_, err = s.DownloadFile(cid, fileName)
+	assert.ErrorContains(t, err, "from all backends failed")
+}
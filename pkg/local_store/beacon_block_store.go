@@ -0,0 +1,83 @@
+package local_store
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+// BeaconBlockStore keeps encoded beacon blocks by slot, an empty value marks a slot without block.
+type BeaconBlockStore interface {
+	// Get returns the data saved at slot, exist reports whether the slot was saved
+	Get(slot uint64) (data []byte, exist bool, err error)
+	Put(slot uint64, data []byte) error
+	// DeleteBefore removes all slots less than the given slot, return the number of removed slots
+	DeleteBefore(slot uint64) (int, error)
+	Close() error
+}
+
+var _ BeaconBlockStore = &LevelDBBeaconBlockStore{}
+
+type LevelDBBeaconBlockStore struct {
+	db *leveldb.DB
+}
+
+var beaconBlockKeyPrefix = []byte("bb/")
+
+func NewLevelDBBeaconBlockStore(path string) (*LevelDBBeaconBlockStore, error) {
+	db, err := leveldb.OpenFile(path, nil)
+	if err != nil {
+		return nil, fmt.Errorf("open beacon block store %s err: %w", path, err)
+	}
+	return &LevelDBBeaconBlockStore{db: db}, nil
+}
+
+// slots are big endian encoded, so keys are ordered by slot
+func beaconBlockKey(slot uint64) []byte {
+	key := make([]byte, len(beaconBlockKeyPrefix)+8)
+	copy(key, beaconBlockKeyPrefix)
+	binary.BigEndian.PutUint64(key[len(beaconBlockKeyPrefix):], slot)
+	return key
+}
+
+func (s *LevelDBBeaconBlockStore) Get(slot uint64) ([]byte, bool, error) {
+	data, err := s.db.Get(beaconBlockKey(slot), nil)
+	if err != nil {
+		if errors.Is(err, leveldb.ErrNotFound) {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+	return data, true, nil
+}
+
+func (s *LevelDBBeaconBlockStore) Put(slot uint64, data []byte) error {
+	return s.db.Put(beaconBlockKey(slot), data, nil)
+}
+
+func (s *LevelDBBeaconBlockStore) DeleteBefore(slot uint64) (int, error) {
+	iter := s.db.NewIterator(&util.Range{Start: beaconBlockKey(0), Limit: beaconBlockKey(slot)}, nil)
+	defer iter.Release()
+
+	batch := new(leveldb.Batch)
+	for iter.Next() {
+		batch.Delete(append([]byte{}, iter.Key()...))
+	}
+	if err := iter.Error(); err != nil {
+		return 0, err
+	}
+	if batch.Len() == 0 {
+		return 0, nil
+	}
+	if err := s.db.Write(batch, nil); err != nil {
+		return 0, err
+	}
+	return batch.Len(), nil
+}
+
+func (s *LevelDBBeaconBlockStore) Close() error {
+	return s.db.Close()
+}
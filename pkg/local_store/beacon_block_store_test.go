@@ -0,0 +1,41 @@
+package local_store_test
+
+import (
+	"testing"
+
+	"github.com/stafiprotocol/eth-lsd-relay/pkg/local_store"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBeaconBlockStore(t *testing.T) {
+	s, err := local_store.NewLevelDBBeaconBlockStore(t.TempDir())
+	assert.Nil(t, err)
+	defer s.Close()
+
+	_, exist, err := s.Get(1)
+	assert.Nil(t, err)
+	assert.False(t, exist)
+
+	for slot := uint64(1); slot <= 300; slot++ {
+		assert.Nil(t, s.Put(slot, []byte{byte(slot)}))
+	}
+	// missed slot
+	assert.Nil(t, s.Put(301, []byte{}))
+
+	data, exist, err := s.Get(301)
+	assert.Nil(t, err)
+	assert.True(t, exist)
+	assert.Len(t, data, 0)
+
+	removed, err := s.DeleteBefore(257)
+	assert.Nil(t, err)
+	assert.Equal(t, 256, removed)
+
+	_, exist, err = s.Get(256)
+	assert.Nil(t, err)
+	assert.False(t, exist)
+	data, exist, err = s.Get(257)
+	assert.Nil(t, err)
+	assert.True(t, exist)
+	assert.Equal(t, []byte{byte(257 % 256)}, data)
+}
@@ -0,0 +1,85 @@
+package local_store
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+// StateStore persists the sync state of lsd services, so a restarted relay can
+// resume from its last checkpoint instead of the network creation block.
+// A state is a set of named sections whose encoding is up to the caller.
+type StateStore interface {
+	// ReadState returns all sections saved for the lsd token, an empty map if none
+	ReadState(lsdToken string) (map[string][]byte, error)
+	// WriteState atomically writes the given sections, other sections are kept
+	WriteState(lsdToken string, sections map[string][]byte) error
+	// DeleteState removes all sections of the lsd token
+	DeleteState(lsdToken string) error
+	Close() error
+}
+
+var _ StateStore = &LevelDBStateStore{}
+
+type LevelDBStateStore struct {
+	db *leveldb.DB
+}
+
+func NewLevelDBStateStore(path string) (*LevelDBStateStore, error) {
+	db, err := leveldb.OpenFile(path, nil)
+	if err != nil {
+		return nil, fmt.Errorf("open state store %s err: %w", path, err)
+	}
+	return &LevelDBStateStore{db: db}, nil
+}
+
+func stateKeyPrefix(lsdToken string) []byte {
+	return []byte("state/" + strings.ToLower(lsdToken) + "/")
+}
+
+func (s *LevelDBStateStore) ReadState(lsdToken string) (map[string][]byte, error) {
+	prefix := stateKeyPrefix(lsdToken)
+	iter := s.db.NewIterator(util.BytesPrefix(prefix), nil)
+	defer iter.Release()
+
+	sections := make(map[string][]byte)
+	for iter.Next() {
+		value := make([]byte, len(iter.Value()))
+		copy(value, iter.Value())
+		sections[string(iter.Key()[len(prefix):])] = value
+	}
+	if err := iter.Error(); err != nil {
+		return nil, err
+	}
+	return sections, nil
+}
+
+func (s *LevelDBStateStore) WriteState(lsdToken string, sections map[string][]byte) error {
+	prefix := stateKeyPrefix(lsdToken)
+	batch := new(leveldb.Batch)
+	for name, value := range sections {
+		batch.Put(append(append([]byte{}, prefix...), name...), value)
+	}
+	return s.db.Write(batch, nil)
+}
+
+func (s *LevelDBStateStore) DeleteState(lsdToken string) error {
+	prefix := stateKeyPrefix(lsdToken)
+	iter := s.db.NewIterator(util.BytesPrefix(prefix), nil)
+	defer iter.Release()
+
+	batch := new(leveldb.Batch)
+	for iter.Next() {
+		batch.Delete(append([]byte{}, iter.Key()...))
+	}
+	if err := iter.Error(); err != nil {
+		return err
+	}
+	return s.db.Write(batch, nil)
+}
+
+func (s *LevelDBStateStore) Close() error {
+	return s.db.Close()
+}
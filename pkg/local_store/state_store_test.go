@@ -0,0 +1,48 @@
+package local_store_test
+
+import (
+	"testing"
+
+	"github.com/stafiprotocol/eth-lsd-relay/pkg/local_store"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStateStoreReadWrite(t *testing.T) {
+	dir := t.TempDir()
+	s, err := local_store.NewLevelDBStateStore(dir)
+	assert.Nil(t, err)
+
+	token := "0x179386303fC2B51c306Ae9D961C73Ea9a9EA0C8d"
+	other := "0x61135C59A4Eb452b89963188eD6B6a7487049764"
+
+	sections, err := s.ReadState(token)
+	assert.Nil(t, err)
+	assert.Len(t, sections, 0)
+
+	err = s.WriteState(token, map[string][]byte{"meta": []byte("1"), "nodes": []byte("[]")})
+	assert.Nil(t, err)
+	err = s.WriteState(other, map[string][]byte{"meta": []byte("other")})
+	assert.Nil(t, err)
+	err = s.WriteState(token, map[string][]byte{"meta": []byte("2")})
+	assert.Nil(t, err)
+
+	sections, err = s.ReadState(token)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string][]byte{"meta": []byte("2"), "nodes": []byte("[]")}, sections)
+
+	// reopen
+	assert.Nil(t, s.Close())
+	s, err = local_store.NewLevelDBStateStore(dir)
+	assert.Nil(t, err)
+	defer s.Close()
+
+	err = s.DeleteState(token)
+	assert.Nil(t, err)
+	sections, err = s.ReadState(token)
+	assert.Nil(t, err)
+	assert.Len(t, sections, 0)
+
+	sections, err = s.ReadState(other)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("other"), sections["meta"])
+}
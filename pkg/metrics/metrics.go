@@ -0,0 +1,224 @@
+// Package metrics exports prometheus metrics of the relay.
+package metrics
+
+import (
+	"math/big"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/stafiprotocol/eth-lsd-relay/pkg/utils"
+)
+
+const namespace = "lsd_relay"
+
+// endpoint types
+const (
+	Eth1 = "eth1"
+	Eth2 = "eth2"
+)
+
+// proposal results
+const (
+	ProposalSent         = "sent"
+	ProposalAlreadyVoted = "already_voted"
+	ProposalExecuted     = "executed"
+	ProposalReverted     = "reverted" // the pre-flight simulation reverted, nothing was sent
+)
+
+var (
+	registry = prometheus.NewRegistry()
+
+	handlerDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "handler_duration_seconds",
+		Help:      "Duration of handler runs.",
+		Buckets:   []float64{0.01, 0.1, 0.5, 1, 5, 15, 60, 300, 1200},
+	}, []string{"lsd_token", "handler"})
+	handlerRuns = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "handler_runs_total",
+		Help:      "Handler runs by result.",
+	}, []string{"lsd_token", "handler", "result"})
+	handlerRetries = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "handler_consecutive_retries",
+		Help:      "Consecutive failed rounds of the handler, reset to zero on success.",
+	}, []string{"lsd_token", "handler"})
+
+	rpcDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "rpc_request_duration_seconds",
+		Help:      "Latency of eth1 and eth2 rpc requests.",
+		Buckets:   []float64{0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30},
+	}, []string{"type", "endpoint", "method"})
+	rpcErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rpc_errors_total",
+		Help:      "Failed eth1 and eth2 rpc requests.",
+	}, []string{"type", "endpoint", "method"})
+	rpcEndpointScore = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "rpc_endpoint_score",
+		Help:      "Score of the endpoint in the endpoint pool, lower is tried first.",
+	}, []string{"type", "endpoint"})
+	rpcEndpointCircuitOpen = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "rpc_endpoint_circuit_open",
+		Help:      "1 if the circuit breaker of the endpoint is open or half-open.",
+	}, []string{"type", "endpoint"})
+
+	syncLagSlots = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "sync_lag_slots",
+		Help:      "Distance between the finalized slot of beacon head and the latest synced slot.",
+	}, []string{"lsd_token"})
+
+	proposals = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "proposals_total",
+		Help:      "Proposals by result: sent, already_voted, executed or reverted.",
+	}, []string{"lsd_token", "proposal", "result"})
+	proposalReverts = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "proposal_reverts_total",
+		Help:      "Proposal txs not sent as their simulation reverted, by decoded revert reason.",
+	}, []string{"lsd_token", "proposal", "reason"})
+
+	gasTipCap = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "gas_tip_cap_gwei",
+		Help:      "Gas tip cap estimated for the latest transaction.",
+	})
+	gasFeeCap = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "gas_fee_cap_gwei",
+		Help:      "Gas fee cap estimated for the latest transaction.",
+	})
+	relayerBalance = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "relayer_balance_ether",
+		Help:      "Balance of the relayer account.",
+	}, []string{"account"})
+	relayerBalanceLevel = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "relayer_balance_level",
+		Help:      "Level of the relayer balance against the configured thresholds: 0 ok, 1 warning, 2 critical.",
+	}, []string{"account"})
+
+	beaconEvents = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "beacon_events_total",
+		Help:      "Events received from the beacon event stream by topic.",
+	}, []string{"topic"})
+	beaconEventStreamUp = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "beacon_event_stream_up",
+		Help:      "1 if the beacon event stream is connected, the beacon head is polled otherwise.",
+	})
+	beaconReorgDepth = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "beacon_chain_reorg_depth_slots",
+		Help:      "Depth of the beacon chain reorgs reported by the event stream.",
+		Buckets:   []float64{1, 2, 3, 4, 8, 16, 32},
+	})
+)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		handlerDuration, handlerRuns, handlerRetries,
+		rpcDuration, rpcErrors, rpcEndpointScore, rpcEndpointCircuitOpen,
+		syncLagSlots, proposals, proposalReverts,
+		gasTipCap, gasFeeCap, relayerBalance, relayerBalanceLevel,
+		beaconEvents, beaconEventStreamUp, beaconReorgDepth,
+	)
+}
+
+// Handler serves all metrics of the relay in prometheus text format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
+
+func ObserveHandler(lsdToken, handler string, startAt time.Time, err error, retries int) {
+	handlerDuration.WithLabelValues(lsdToken, handler).Observe(time.Since(startAt).Seconds())
+	result := "success"
+	if err != nil {
+		result = "failure"
+	}
+	handlerRuns.WithLabelValues(lsdToken, handler, result).Inc()
+	handlerRetries.WithLabelValues(lsdToken, handler).Set(float64(retries))
+}
+
+// ObserveRpc records a request to endpoint, the endpoint is redacted to scheme and host
+func ObserveRpc(endpointType, endpoint, method string, startAt time.Time, failed bool) {
+	endpoint = utils.RedactUrl(endpoint)
+	rpcDuration.WithLabelValues(endpointType, endpoint, method).Observe(time.Since(startAt).Seconds())
+	if failed {
+		rpcErrors.WithLabelValues(endpointType, endpoint, method).Inc()
+	}
+}
+
+// SetEndpointScore exports the score of endpoint, the endpoint is redacted to scheme and host
+func SetEndpointScore(endpointType, endpoint string, score float64, circuitOpen bool) {
+	endpoint = utils.RedactUrl(endpoint)
+	rpcEndpointScore.WithLabelValues(endpointType, endpoint).Set(score)
+	if circuitOpen {
+		rpcEndpointCircuitOpen.WithLabelValues(endpointType, endpoint).Set(1)
+	} else {
+		rpcEndpointCircuitOpen.WithLabelValues(endpointType, endpoint).Set(0)
+	}
+}
+
+func SetSyncLag(lsdToken string, finalizedSlot, syncedSlot uint64) {
+	lag := float64(0)
+	if finalizedSlot > syncedSlot {
+		lag = float64(finalizedSlot - syncedSlot)
+	}
+	syncLagSlots.WithLabelValues(lsdToken).Set(lag)
+}
+
+func IncProposal(lsdToken, proposal, result string) {
+	proposals.WithLabelValues(lsdToken, proposal, result).Inc()
+}
+
+func IncProposalRevert(lsdToken, proposal, reason string) {
+	proposalReverts.WithLabelValues(lsdToken, proposal, reason).Inc()
+}
+
+func SetGasPrice(tipCap, feeCap *big.Int) {
+	gasTipCap.Set(weiTo(tipCap, 1e9))
+	gasFeeCap.Set(weiTo(feeCap, 1e9))
+}
+
+func SetRelayerBalance(account string, balance *big.Int) {
+	relayerBalance.WithLabelValues(account).Set(weiTo(balance, 1e18))
+}
+
+func SetRelayerBalanceLevel(account string, level int) {
+	relayerBalanceLevel.WithLabelValues(account).Set(float64(level))
+}
+
+func IncBeaconEvent(topic string) {
+	beaconEvents.WithLabelValues(topic).Inc()
+}
+
+func SetBeaconEventStreamUp(up bool) {
+	if up {
+		beaconEventStreamUp.Set(1)
+	} else {
+		beaconEventStreamUp.Set(0)
+	}
+}
+
+func ObserveBeaconReorg(depth uint64) {
+	beaconReorgDepth.Observe(float64(depth))
+}
+
+func weiTo(amount *big.Int, unit float64) float64 {
+	f, _ := new(big.Float).Quo(new(big.Float).SetInt(amount), big.NewFloat(unit)).Float64()
+	return f
+}
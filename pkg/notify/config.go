@@ -0,0 +1,31 @@
+package notify
+
+import (
+	"time"
+
+	"github.com/stafiprotocol/eth-lsd-relay/pkg/config"
+)
+
+// NewFromConfig returns nil if no sink is configured
+func NewFromConfig(cfg config.Notify) *Notifier {
+	sinks := make([]Sink, 0)
+	if cfg.Webhook != "" {
+		sinks = append(sinks, NewWebhookSink(cfg.Webhook))
+	}
+	if cfg.SlackWebhook != "" {
+		sinks = append(sinks, NewSlackSink(cfg.SlackWebhook))
+	}
+	if cfg.DiscordWebhook != "" {
+		sinks = append(sinks, NewDiscordSink(cfg.DiscordWebhook))
+	}
+	if cfg.TelegramBotToken != "" {
+		sinks = append(sinks, NewTelegramSink("", cfg.TelegramBotToken, cfg.TelegramChatId))
+	}
+	if len(sinks) == 0 {
+		return nil
+	}
+	return NewNotifier(sinks, Config{
+		DedupWindow:  time.Duration(cfg.DedupSeconds) * time.Second,
+		MaxPerMinute: cfg.MaxPerMinute,
+	})
+}
@@ -0,0 +1,233 @@
+// Package notify delivers operator alerts of the relay to chat and webhook sinks.
+// Alerts sharing a key are de-duplicated within a window and the overall rate is limited,
+// so a flapping endpoint raises one alert instead of flooding the channels.
+package notify
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stafiprotocol/eth-lsd-relay/pkg/utils"
+)
+
+type Severity string
+
+const (
+	SeverityInfo     Severity = "info"
+	SeverityWarning  Severity = "warning"
+	SeverityCritical Severity = "critical"
+)
+
+type Alert struct {
+	Key      string            `json:"key"` // alerts with the same key are de-duplicated
+	Severity Severity          `json:"severity"`
+	Title    string            `json:"title"`
+	Message  string            `json:"message"`
+	Fields   map[string]string `json:"fields,omitempty"`
+	At       time.Time         `json:"at"`
+	Repeated int               `json:"repeated,omitempty"` // times the alert fired since last sent
+	Dropped  int               `json:"dropped,omitempty"`  // alerts dropped by the rate limit since last sent
+}
+
+// Text renders the alert for chat sinks
+func (a Alert) Text() string {
+	b := strings.Builder{}
+	fmt.Fprintf(&b, "[%s] %s", strings.ToUpper(string(a.Severity)), a.Title)
+	if a.Message != "" {
+		b.WriteString("\n" + a.Message)
+	}
+	keys := make([]string, 0, len(a.Fields))
+	for k := range a.Fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(&b, "\n%s: %s", k, a.Fields[k])
+	}
+	if a.Repeated > 0 {
+		fmt.Fprintf(&b, "\n(fired %d more times since last sent)", a.Repeated)
+	}
+	if a.Dropped > 0 {
+		fmt.Fprintf(&b, "\n(%d other alerts dropped by rate limit)", a.Dropped)
+	}
+	return b.String()
+}
+
+type Sink interface {
+	Name() string
+	Send(ctx context.Context, alert Alert) error
+}
+
+type Config struct {
+	DedupWindow  time.Duration // an alert key is sent at most once per window
+	MaxPerMinute int           // alerts sent per minute over all keys
+	QueueSize    int
+	SendTimeout  time.Duration
+}
+
+type Notifier struct {
+	sinks []Sink
+	cfg   Config
+	log   *logrus.Entry
+
+	mutex      sync.Mutex
+	lastSent   map[string]time.Time
+	suppressed map[string]int
+	sentAt     []time.Time // within the last minute
+	dropped    int
+	closed     bool // queue is closed, guarded by mutex as alerts are sent to it under mutex
+
+	queue chan Alert
+	done  chan struct{}
+}
+
+func NewNotifier(sinks []Sink, cfg Config) *Notifier {
+	if cfg.QueueSize == 0 {
+		cfg.QueueSize = 100
+	}
+	if cfg.SendTimeout == 0 {
+		cfg.SendTimeout = 10 * time.Second
+	}
+	return &Notifier{
+		sinks:      sinks,
+		cfg:        cfg,
+		log:        logrus.WithField("module", "notify"),
+		lastSent:   make(map[string]time.Time),
+		suppressed: make(map[string]int),
+		queue:      make(chan Alert, cfg.QueueSize),
+		done:       make(chan struct{}),
+	}
+}
+
+func (n *Notifier) Start() {
+	utils.SafeGo(n.deliverService)
+}
+
+// Close stops accepting alerts and waits at most timeout for the queued ones to be delivered
+func (n *Notifier) Close(timeout time.Duration) {
+	n.mutex.Lock()
+	if n.closed {
+		n.mutex.Unlock()
+		return
+	}
+	n.closed = true
+	close(n.queue)
+	n.mutex.Unlock()
+
+	select {
+	case <-n.done:
+	case <-time.After(timeout):
+		n.log.Warn("close notifier timeout, queued alerts are dropped")
+	}
+}
+
+func (n *Notifier) deliverService() {
+	defer close(n.done)
+	for alert := range n.queue {
+		n.deliver(alert)
+	}
+}
+
+func (n *Notifier) deliver(alert Alert) {
+	for _, sink := range n.sinks {
+		ctx, cancel := context.WithTimeout(context.Background(), n.cfg.SendTimeout)
+		if err := sink.Send(ctx, alert); err != nil {
+			n.log.WithFields(logrus.Fields{
+				"sink":  sink.Name(),
+				"alert": alert.Key,
+			}).WithError(err).Warn("send alert failed")
+		}
+		cancel()
+	}
+}
+
+// Notify queues alert unless it is a duplicate or over the rate limit, it never blocks,
+// alerts are discarded once the notifier is closed
+func (n *Notifier) Notify(alert Alert) {
+	if alert.At.IsZero() {
+		alert.At = time.Now()
+	}
+	if !n.admit(&alert) {
+		return
+	}
+
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	if n.closed {
+		return
+	}
+	select {
+	case n.queue <- alert:
+	default:
+		n.log.WithField("alert", alert.Key).Warn("alert queue is full, drop alert")
+	}
+}
+
+// admit applies de-duplication then rate limiting, counters of skipped alerts are carried by the next sent one
+func (n *Notifier) admit(alert *Alert) bool {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	if last, exist := n.lastSent[alert.Key]; exist && alert.At.Sub(last) < n.cfg.DedupWindow {
+		n.suppressed[alert.Key]++
+		return false
+	}
+
+	recent := n.sentAt[:0]
+	for _, at := range n.sentAt {
+		if alert.At.Sub(at) < time.Minute {
+			recent = append(recent, at)
+		}
+	}
+	n.sentAt = recent
+	if n.cfg.MaxPerMinute > 0 && len(n.sentAt) >= n.cfg.MaxPerMinute {
+		n.dropped++
+		return false
+	}
+
+	alert.Repeated = n.suppressed[alert.Key]
+	alert.Dropped = n.dropped
+	delete(n.suppressed, alert.Key)
+	n.dropped = 0
+	n.lastSent[alert.Key] = alert.At
+	n.sentAt = append(n.sentAt, alert.At)
+	return true
+}
+
+var (
+	defaultNotifier *Notifier
+	defaultMutex    sync.RWMutex
+)
+
+// SetDefault sets the notifier used by Notify, alerts are discarded until it is set
+func SetDefault(n *Notifier) {
+	defaultMutex.Lock()
+	defer defaultMutex.Unlock()
+	defaultNotifier = n
+}
+
+// Notify sends alert through the default notifier
+func Notify(alert Alert) {
+	defaultMutex.RLock()
+	n := defaultNotifier
+	defaultMutex.RUnlock()
+	if n != nil {
+		n.Notify(alert)
+	}
+}
+
+// Close flushes the default notifier
+func Close(timeout time.Duration) {
+	defaultMutex.Lock()
+	n := defaultNotifier
+	defaultNotifier = nil
+	defaultMutex.Unlock()
+	if n != nil {
+		n.Close(timeout)
+	}
+}
@@ -0,0 +1,120 @@
+package notify_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stafiprotocol/eth-lsd-relay/pkg/notify"
+	"github.com/stretchr/testify/assert"
+)
+
+type memSink struct {
+	mutex  sync.Mutex
+	alerts []notify.Alert
+}
+
+func (s *memSink) Name() string { return "mem" }
+
+func (s *memSink) Send(_ context.Context, alert notify.Alert) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.alerts = append(s.alerts, alert)
+	return nil
+}
+
+func TestNotifierDedupAndRateLimit(t *testing.T) {
+	sink := &memSink{}
+	n := notify.NewNotifier([]notify.Sink{sink}, notify.Config{
+		DedupWindow:  time.Hour,
+		MaxPerMinute: 2,
+	})
+	n.Start()
+
+	start := time.Now()
+	// a flapping endpoint fires the same alert again and again
+	for i := 0; i < 5; i++ {
+		n.Notify(notify.Alert{Key: "eth1-out-of-sync", Title: "out of sync", At: start.Add(time.Duration(i) * time.Second)})
+	}
+	n.Notify(notify.Alert{Key: "gas-price", Title: "gas", At: start.Add(5 * time.Second)})
+	// over the rate limit
+	n.Notify(notify.Alert{Key: "relayer-balance", Title: "balance", At: start.Add(6 * time.Second)})
+	// after the dedup window, repeats and drops are reported
+	n.Notify(notify.Alert{Key: "eth1-out-of-sync", Title: "out of sync", At: start.Add(2 * time.Hour)})
+	n.Close(time.Second)
+
+	assert.Len(t, sink.alerts, 3)
+	assert.Equal(t, "eth1-out-of-sync", sink.alerts[0].Key)
+	assert.Equal(t, "gas-price", sink.alerts[1].Key)
+	assert.Equal(t, "eth1-out-of-sync", sink.alerts[2].Key)
+	assert.Equal(t, 4, sink.alerts[2].Repeated)
+	assert.Equal(t, 1, sink.alerts[2].Dropped)
+	assert.Contains(t, sink.alerts[2].Text(), "fired 4 more times")
+}
+
+func TestNotifyAfterClose(t *testing.T) {
+	n := notify.NewNotifier([]notify.Sink{&memSink{}}, notify.Config{})
+	n.Start()
+
+	// handlers keep notifying while the relay shuts down
+	wg := sync.WaitGroup{}
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				n.Notify(notify.Alert{Key: fmt.Sprintf("%d-%d", i, j)})
+			}
+		}(i)
+	}
+	n.Close(time.Second)
+	n.Close(time.Second)
+	wg.Wait()
+}
+
+func TestSinks(t *testing.T) {
+	var mutex sync.Mutex
+	bodies := make(map[string]map[string]interface{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := make(map[string]interface{})
+		assert.Nil(t, json.NewDecoder(r.Body).Decode(&body))
+		mutex.Lock()
+		bodies[r.URL.Path] = body
+		mutex.Unlock()
+	}))
+	defer server.Close()
+
+	alert := notify.Alert{
+		Key:      "relayer-balance",
+		Severity: notify.SeverityWarning,
+		Title:    "relayer balance is low",
+		Fields:   map[string]string{"balance": "0.5"},
+	}
+	sinks := []notify.Sink{
+		notify.NewWebhookSink(server.URL + "/webhook"),
+		notify.NewSlackSink(server.URL + "/slack"),
+		notify.NewDiscordSink(server.URL + "/discord"),
+		notify.NewTelegramSink(server.URL, "token", "42"),
+	}
+	for _, sink := range sinks {
+		assert.Nil(t, sink.Send(context.Background(), alert), sink.Name())
+	}
+
+	text := "[WARNING] relayer balance is low\nbalance: 0.5"
+	assert.Equal(t, "relayer-balance", bodies["/webhook"]["key"])
+	assert.Equal(t, text, bodies["/slack"]["text"])
+	assert.Equal(t, text, bodies["/discord"]["content"])
+	assert.Equal(t, text, bodies["/bottoken/sendMessage"]["text"])
+	assert.Equal(t, "42", bodies["/bottoken/sendMessage"]["chat_id"])
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer failing.Close()
+	assert.ErrorContains(t, notify.NewSlackSink(failing.URL).Send(context.Background(), alert), "status: 429")
+}
@@ -0,0 +1,118 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/stafiprotocol/eth-lsd-relay/pkg/utils"
+)
+
+var (
+	_ Sink = &WebhookSink{}
+	_ Sink = &SlackSink{}
+	_ Sink = &DiscordSink{}
+	_ Sink = &TelegramSink{}
+)
+
+const defaultTelegramEndpoint = "https://api.telegram.org"
+
+// WebhookSink posts the alert as json
+type WebhookSink struct {
+	url string
+}
+
+func NewWebhookSink(url string) *WebhookSink {
+	return &WebhookSink{url: url}
+}
+
+func (s *WebhookSink) Name() string { return "webhook" }
+
+func (s *WebhookSink) Send(ctx context.Context, alert Alert) error {
+	return postJson(ctx, s.url, alert)
+}
+
+// SlackSink posts to a slack incoming webhook
+type SlackSink struct {
+	webhook string
+}
+
+func NewSlackSink(webhook string) *SlackSink {
+	return &SlackSink{webhook: webhook}
+}
+
+func (s *SlackSink) Name() string { return "slack" }
+
+func (s *SlackSink) Send(ctx context.Context, alert Alert) error {
+	return postJson(ctx, s.webhook, map[string]string{"text": alert.Text()})
+}
+
+// DiscordSink posts to a discord channel webhook
+type DiscordSink struct {
+	webhook string
+}
+
+func NewDiscordSink(webhook string) *DiscordSink {
+	return &DiscordSink{webhook: webhook}
+}
+
+func (s *DiscordSink) Name() string { return "discord" }
+
+func (s *DiscordSink) Send(ctx context.Context, alert Alert) error {
+	content := alert.Text()
+	// discord rejects messages longer than 2000 characters
+	if len(content) > 2000 {
+		content = content[:1997] + "..."
+	}
+	return postJson(ctx, s.webhook, map[string]string{"content": content})
+}
+
+// TelegramSink sends through the sendMessage method of a telegram bot
+type TelegramSink struct {
+	endpoint string
+	botToken string
+	chatId   string
+}
+
+// NewTelegramSink uses the public bot api if endpoint is empty
+func NewTelegramSink(endpoint, botToken, chatId string) *TelegramSink {
+	if endpoint == "" {
+		endpoint = defaultTelegramEndpoint
+	}
+	return &TelegramSink{endpoint: endpoint, botToken: botToken, chatId: chatId}
+}
+
+func (s *TelegramSink) Name() string { return "telegram" }
+
+func (s *TelegramSink) Send(ctx context.Context, alert Alert) error {
+	return postJson(ctx, fmt.Sprintf("%s/bot%s/sendMessage", s.endpoint, s.botToken), map[string]string{
+		"chat_id": s.chatId,
+		"text":    alert.Text(),
+	})
+}
+
+func postJson(ctx context.Context, url string, body interface{}) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		// the url may carry a token
+		return fmt.Errorf("post %s failed", utils.RedactUrl(url))
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("post %s status: %d, body: %s", utils.RedactUrl(url), resp.StatusCode, string(respBody))
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/prysmaticlabs/prysm/v4/beacon-chain/core/signing"
+	"github.com/prysmaticlabs/prysm/v4/config/params"
+	"github.com/stafiprotocol/eth-lsd-relay/pkg/connection/beacon"
+)
+
+// ChainProfile holds the chain specific params of the relay, the beacon endpoint is checked against it on start
+type ChainProfile struct {
+	Name                       string
+	ChainId                    uint64
+	GenesisForkVersion         []byte
+	DomainDeposit              [4]byte
+	SecondsPerSlot             uint64 // not checked if zero
+	SlotsPerEpoch              uint64 // not checked if zero
+	EffectiveBalance           uint64 // ether
+	MaxPartialWithdrawalAmount uint64 // ether
+}
+
+// DepositDomain computes the domain of deposit sigs, which is independent of the genesis validators root
+func (p *ChainProfile) DepositDomain() ([]byte, error) {
+	return signing.ComputeDomain(p.DomainDeposit, p.GenesisForkVersion, params.BeaconConfig().ZeroHash[:])
+}
+
+// Check returns an error if the beacon endpoint serves another network
+func (p *ChainProfile) Check(eth2Config beacon.Eth2Config) error {
+	if !bytes.Equal(eth2Config.GenesisForkVersion, p.GenesisForkVersion) {
+		return fmt.Errorf("endpoint network not match, chain profile %s genesis fork version %s, endpoint %s",
+			p.Name, hexutil.Encode(p.GenesisForkVersion), hexutil.Encode(eth2Config.GenesisForkVersion))
+	}
+	if p.SecondsPerSlot != 0 && eth2Config.SecondsPerSlot != p.SecondsPerSlot {
+		return fmt.Errorf("endpoint network not match, chain profile %s seconds per slot %d, endpoint %d",
+			p.Name, p.SecondsPerSlot, eth2Config.SecondsPerSlot)
+	}
+	if p.SlotsPerEpoch != 0 && eth2Config.SlotsPerEpoch != p.SlotsPerEpoch {
+		return fmt.Errorf("endpoint network not match, chain profile %s slots per epoch %d, endpoint %d",
+			p.Name, p.SlotsPerEpoch, eth2Config.SlotsPerEpoch)
+	}
+	return nil
+}
+
+// ChainProfiles is keyed by chain id
+type ChainProfiles map[uint64]*ChainProfile
+
+// DefaultChainProfiles returns the built-in profiles of the supported networks
+func DefaultChainProfiles() ChainProfiles {
+	profiles := ChainProfiles{}
+	for _, p := range []*ChainProfile{
+		chainProfileOf("mainnet", 1, params.MainnetConfig(), 32, 8),
+		chainProfileOf("sepolia", 11155111, params.SepoliaConfig(), 32, 8),
+		chainProfileOf("holesky", 17000, params.HoleskyConfig(), 32, 8),
+		chainProfileOf("goerli", 5, params.PraterConfig(), 32, 8),
+		// pulse chain scales balances by 1e6
+		chainProfileOf("pulsechain", 369, PulseChainConfig(), 32_000_000, 8_000_000),
+		chainProfileOf(PulseChainTestnetV4Name, 943, PulseChainTestnetV4Config(), 32_000_000, 8_000_000),
+	} {
+		profiles[p.ChainId] = p
+	}
+	return profiles
+}
+
+func chainProfileOf(name string, chainId uint64, cfg *params.BeaconChainConfig, effectiveBalance, maxPartialWithdrawalAmount uint64) *ChainProfile {
+	return &ChainProfile{
+		Name:                       name,
+		ChainId:                    chainId,
+		GenesisForkVersion:         cfg.GenesisForkVersion,
+		DomainDeposit:              cfg.DomainDeposit,
+		SecondsPerSlot:             cfg.SecondsPerSlot,
+		SlotsPerEpoch:              uint64(cfg.SlotsPerEpoch),
+		EffectiveBalance:           effectiveBalance,
+		MaxPartialWithdrawalAmount: maxPartialWithdrawalAmount,
+	}
+}
+
+// Get returns the profile of chainId
+func (ps ChainProfiles) Get(chainId uint64) (*ChainProfile, error) {
+	p, exist := ps[chainId]
+	if !exist {
+		return nil, fmt.Errorf("unsupported chainId: %d, add a chain profile to config", chainId)
+	}
+	return p, nil
+}
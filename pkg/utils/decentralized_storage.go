@@ -12,3 +12,8 @@ func NodeRewardsFileNameAtEpoch(lsdToken string, chainID uint64, epoch uint64) s
 func NodeRewardsFileNameAtEpochOld(lsdToken string, epoch uint64) string {
 	return fmt.Sprintf("%s-nodeRewards-%d.json", strings.ToLower(lsdToken), epoch)
 }
+
+// BalancesReportFileNameAtEpoch returns the file name of the submitBalances report without extension
+func BalancesReportFileNameAtEpoch(lsdToken string, chainID uint64, epoch uint64) string {
+	return fmt.Sprintf("%s-balances-%d-%d", strings.ToLower(lsdToken), chainID, epoch)
+}
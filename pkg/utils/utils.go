@@ -8,6 +8,7 @@ import (
 	"encoding/hex"
 	"fmt"
 	"io"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strconv"
@@ -333,3 +334,12 @@ func Min[T constraints.Ordered](n1 T, nums ...T) T {
 	}
 	return min
 }
+
+// RedactUrl keeps only scheme and host of the url, path and query may contain api keys
+func RedactUrl(rawUrl string) string {
+	u, err := url.Parse(rawUrl)
+	if err != nil || u.Host == "" {
+		return "<invalid url>"
+	}
+	return u.Scheme + "://" + u.Host
+}
@@ -0,0 +1,179 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/sirupsen/logrus"
+	"github.com/stafiprotocol/eth-lsd-relay/pkg/connection"
+	"github.com/stafiprotocol/eth-lsd-relay/pkg/metrics"
+	"github.com/stafiprotocol/eth-lsd-relay/pkg/utils"
+)
+
+// ApiServer serves read-only json endpoints about the running relay:
+//
+//	GET /metrics                 prometheus metrics
+//	GET /endpoints               health of eth1 and eth2 endpoints
+//	GET /lsd                     lsd tokens served by this relay
+//	GET /lsd/{lsdToken}/status   sync cursors, caches and handler statuses of a lsd token
+//	GET /lsd/{lsdToken}/proof/{nodeAddress}?epoch=   merkle proof of node rewards, if enabled
+type ApiServer struct {
+	manager     *ServiceManager
+	server      *http.Server
+	mux         *http.ServeMux
+	merkleProof bool
+}
+
+type EndpointsHealth struct {
+	Eth1 []connection.EndpointHealth `json:"eth1"`
+	Eth2 []connection.EndpointHealth `json:"eth2"`
+}
+
+type apiError struct {
+	Error string `json:"error"`
+}
+
+func NewApiServer(listenAddr string, merkleProof bool, manager *ServiceManager) *ApiServer {
+	s := &ApiServer{
+		manager:     manager,
+		mux:         http.NewServeMux(),
+		merkleProof: merkleProof,
+	}
+	s.mux.Handle("/metrics", metrics.Handler())
+	s.mux.HandleFunc("/endpoints", s.handleEndpoints)
+	s.mux.HandleFunc("/lsd", s.handleLsdTokens)
+	s.mux.HandleFunc("/lsd/", s.handleLsdToken)
+
+	s.server = &http.Server{
+		Addr:              listenAddr,
+		Handler:           s.mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	return s
+}
+
+func (s *ApiServer) Start() {
+	utils.SafeGo(func() {
+		logrus.Infof("api server listening on %s", s.server.Addr)
+		if err := s.server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logrus.Errorf("api server stopped: %s", err.Error())
+		}
+	})
+}
+
+func (s *ApiServer) Stop() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.server.Shutdown(ctx); err != nil {
+		logrus.Warnf("api server shutdown err: %s", err.Error())
+	}
+}
+
+func (s *ApiServer) handleEndpoints(w http.ResponseWriter, r *http.Request) {
+	if !checkGet(w, r) {
+		return
+	}
+	writeJSON(w, http.StatusOK, EndpointsHealth{
+		Eth1: s.manager.connection.Eth1EndpointsHealth(),
+		Eth2: s.manager.connection.Eth2EndpointsHealth(),
+	})
+}
+
+func (s *ApiServer) handleLsdTokens(w http.ResponseWriter, r *http.Request) {
+	if !checkGet(w, r) {
+		return
+	}
+	tokens := make([]string, 0)
+	s.manager.srvs.Range(func(_ string, srv *Service) bool {
+		tokens = append(tokens, srv.LsdTokenAddress().String())
+		return true
+	})
+	writeJSON(w, http.StatusOK, tokens)
+}
+
+// handleLsdToken dispatches /lsd/{lsdToken}/{action}[/{arg}]
+func (s *ApiServer) handleLsdToken(w http.ResponseWriter, r *http.Request) {
+	if !checkGet(w, r) {
+		return
+	}
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/lsd/"), "/"), "/")
+	if len(parts) != 2 && len(parts) != 3 {
+		writeJSON(w, http.StatusNotFound, apiError{Error: "not found"})
+		return
+	}
+	if !common.IsHexAddress(parts[0]) {
+		writeJSON(w, http.StatusBadRequest, apiError{Error: "invalid lsd token address"})
+		return
+	}
+	srv := s.manager.serviceOf(common.HexToAddress(parts[0]))
+	if srv == nil {
+		writeJSON(w, http.StatusNotFound, apiError{Error: "lsd token not served"})
+		return
+	}
+
+	switch {
+	case parts[1] == "status" && len(parts) == 2:
+		status := srv.Status()
+		if status == nil {
+			writeJSON(w, http.StatusServiceUnavailable, apiError{Error: "status not ready"})
+			return
+		}
+		writeJSON(w, http.StatusOK, status)
+	case parts[1] == "proof" && len(parts) == 3 && s.merkleProof:
+		s.handleNodeRewardProof(w, r, srv, parts[2])
+	default:
+		writeJSON(w, http.StatusNotFound, apiError{Error: "not found"})
+	}
+}
+
+func (s *ApiServer) handleNodeRewardProof(w http.ResponseWriter, r *http.Request, srv *Service, node string) {
+	if !common.IsHexAddress(node) {
+		writeJSON(w, http.StatusBadRequest, apiError{Error: "invalid node address"})
+		return
+	}
+	var epoch uint64
+	if epochStr := r.URL.Query().Get("epoch"); epochStr != "" {
+		var err error
+		if epoch, err = strconv.ParseUint(epochStr, 10, 64); err != nil {
+			writeJSON(w, http.StatusBadRequest, apiError{Error: "invalid epoch"})
+			return
+		}
+	}
+
+	proof, err := srv.NodeRewardProof(common.HexToAddress(node), epoch)
+	switch {
+	case err == nil:
+		writeJSON(w, http.StatusOK, proof)
+	case errors.Is(err, ErrNoMerkleRoot), errors.Is(err, ErrMerkleRootNotFound), errors.Is(err, ErrNodeNotInRewards):
+		writeJSON(w, http.StatusNotFound, apiError{Error: err.Error()})
+	default:
+		logrus.WithFields(logrus.Fields{
+			"lsdToken": srv.LsdTokenAddress().String(),
+			"node":     node,
+			"epoch":    epoch,
+		}).WithError(err).Warn("api server get node reward proof failed")
+		writeJSON(w, http.StatusServiceUnavailable, apiError{Error: "proof not available"})
+	}
+}
+
+func checkGet(w http.ResponseWriter, r *http.Request) bool {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, apiError{Error: "method not allowed"})
+		return false
+	}
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		logrus.Debugf("api server write response err: %s", err.Error())
+	}
+}
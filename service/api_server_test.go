@@ -0,0 +1,67 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	xsync "github.com/puzpuzpuz/xsync/v3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApiServerLsdStatus(t *testing.T) {
+	srv := newStateTestService(nil)
+	srv.latestBlockOfSyncEvents = 500
+	srv.validators["0a"] = &Validator{Status: 6}
+	srv.validators["0b"] = &Validator{Status: 6}
+	srv.stakerWithdrawals[2] = &StakerWithdrawal{WithdrawIndex: 2}
+	srv.stakerWithdrawals[1] = &StakerWithdrawal{WithdrawIndex: 1, ClaimedBlockNumber: 10}
+
+	m := &ServiceManager{srvs: xsync.NewMapOf[string, *Service]()}
+	m.srvs.Store(srv.lsdTokenAddress.String(), srv)
+	ts := httptest.NewServer(NewApiServer("", false, m).mux)
+	defer ts.Close()
+
+	get := func(path string, v any) int {
+		resp, err := http.Get(ts.URL + path)
+		assert.Nil(t, err)
+		defer resp.Body.Close()
+		if v != nil {
+			assert.Nil(t, json.NewDecoder(resp.Body).Decode(v))
+		}
+		return resp.StatusCode
+	}
+
+	tokens := []string{}
+	assert.Equal(t, http.StatusOK, get("/lsd", &tokens))
+	assert.Equal(t, []string{srv.lsdTokenAddress.String()}, tokens)
+
+	// snapshot not built yet
+	assert.Equal(t, http.StatusServiceUnavailable, get("/lsd/"+srv.lsdTokenAddress.String()+"/status", nil))
+
+	assert.Nil(t, srv.refreshStatus())
+	srv.recordHandlerRun("syncEvents", time.Now(), errors.New("rpc down"), 3)
+
+	status := ServiceStatus{}
+	// lower case address is accepted
+	assert.Equal(t, http.StatusOK, get("/lsd/0x61135c59a4eb452b89963188ed6b6a7487049764/status", &status))
+	assert.Equal(t, uint64(500), status.LatestBlockOfSyncEvents)
+	assert.Equal(t, 2, status.Validators)
+	assert.Equal(t, 2, status.ValidatorsByStatus[6])
+	assert.Len(t, status.PendingStakerWithdrawals, 1)
+	assert.Equal(t, uint64(2), status.PendingStakerWithdrawals[0].WithdrawIndex)
+	assert.Len(t, status.Handlers, 1)
+	assert.Equal(t, "rpc down", status.Handlers[0].LastError)
+	assert.Equal(t, 3, status.Handlers[0].RetryTimes)
+
+	assert.Equal(t, http.StatusNotFound, get("/lsd/"+common.HexToAddress("0x01").String()+"/status", nil))
+	assert.Equal(t, http.StatusBadRequest, get("/lsd/abc/status", nil))
+	assert.Equal(t, http.StatusNotFound, get("/lsd/"+srv.lsdTokenAddress.String()+"/unknown", nil))
+	// merkle proofs are disabled
+	assert.Equal(t, http.StatusNotFound, get("/lsd/"+srv.lsdTokenAddress.String()+"/proof/"+common.HexToAddress("0x01").String(), nil))
+	assert.Equal(t, http.StatusOK, get("/metrics", nil))
+}
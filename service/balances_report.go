@@ -0,0 +1,91 @@
+package service
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stafiprotocol/eth-lsd-relay/pkg/destorage"
+	"github.com/stafiprotocol/eth-lsd-relay/pkg/utils"
+)
+
+const balancesReportDir = "balances_reports"
+
+// writeBalancesReport writes the breakdown of a submitBalances round to the log dir as json and csv,
+// the json report is uploaded to the decentralized storage once per epoch if enabled, dry run only logs its cid
+func (s *Service) writeBalancesReport(b *BalancesBreakdown) error {
+	dir := filepath.Join(s.logFilePath, balancesReportDir)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	fileName := utils.BalancesReportFileNameAtEpoch(s.lsdTokenAddress.String(), s.chainID, b.TargetEpoch)
+
+	jsonBts, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, fileName+".json"), jsonBts, 0600); err != nil {
+		return err
+	}
+	csvBts, err := balancesReportCsv(b)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, fileName+".csv"), csvBts, 0600); err != nil {
+		return err
+	}
+
+	if !s.uploadBalancesReport || s.latestUploadedBalancesReportEpoch == b.TargetEpoch {
+		return nil
+	}
+	if s.dryRun {
+		cid, err := destorage.ComputeCid(fileName+".json", jsonBts)
+		if err != nil {
+			return err
+		}
+		s.latestUploadedBalancesReportEpoch = b.TargetEpoch
+		s.log.WithFields(logrus.Fields{
+			"targetEpoch": b.TargetEpoch,
+			"cid":         cid,
+		}).Info("dry run: balances report not uploaded")
+		return nil
+	}
+	cid, err := s.dds.UploadFile(jsonBts, fileName+".json")
+	if err != nil {
+		return fmt.Errorf("upload balances report err: %w", err)
+	}
+	s.latestUploadedBalancesReportEpoch = b.TargetEpoch
+	s.log.WithFields(logrus.Fields{
+		"targetEpoch": b.TargetEpoch,
+		"cid":         cid,
+	}).Info("uploaded balances report")
+	return nil
+}
+
+// balancesReportCsv lists validators of the breakdown, one row per validator
+func balancesReportCsv(b *BalancesBreakdown) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	w := csv.NewWriter(buf)
+	records := [][]string{{"pubkey", "validatorIndex", "nodeAddress", "nodeType", "onchainStatus", "beaconStatus", "balance", "userEth"}}
+	for _, v := range b.Validators {
+		records = append(records, []string{
+			v.Pubkey,
+			strconv.FormatUint(v.ValidatorIndex, 10),
+			v.NodeAddress,
+			strconv.FormatUint(uint64(v.NodeType), 10),
+			strconv.FormatUint(uint64(v.OnchainStatus), 10),
+			strconv.FormatUint(uint64(v.BeaconStatus), 10),
+			strconv.FormatUint(v.Balance, 10),
+			v.UserEth.StringFixed(0),
+		})
+	}
+	if err := w.WriteAll(records); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,79 @@
+package service
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stafiprotocol/eth-lsd-relay/pkg/destorage"
+	"github.com/stretchr/testify/assert"
+)
+
+type memDeStorage struct {
+	uploaded map[string][]byte
+}
+
+func (m *memDeStorage) DownloadFile(cid, fileName string) ([]byte, error) {
+	content, exist := m.uploaded[fileName]
+	if !exist {
+		return nil, destorage.ErrNotFound
+	}
+	return content, nil
+}
+
+func (m *memDeStorage) UploadFile(content []byte, path string) (string, error) {
+	m.uploaded[path] = content
+	return "cid-" + path, nil
+}
+
+func TestWriteBalancesReport(t *testing.T) {
+	dds := &memDeStorage{uploaded: make(map[string][]byte)}
+	s := newStateTestService(nil)
+	s.chainID = 943
+	s.logFilePath = t.TempDir()
+	s.dds = dds
+	s.uploadBalancesReport = true
+
+	b := &BalancesBreakdown{
+		LsdToken:     s.lsdTokenAddress.String(),
+		TargetEpoch:  225,
+		TargetBlock:  1000,
+		TotalUserEth: decimal.RequireFromString("64000000000000000000"),
+		Validators: []*ValidatorBalance{
+			{Pubkey: "0a", ValidatorIndex: 1, OnchainStatus: 2, BeaconStatus: 6, Balance: 32e9, UserEth: decimal.RequireFromString("31000000000000000000")},
+			{Pubkey: "0b", OnchainStatus: 1, UserEth: decimal.Zero},
+		},
+	}
+	assert.Nil(t, s.writeBalancesReport(b))
+
+	name := "0x61135c59a4eb452b89963188ed6b6a7487049764-balances-943-225"
+	jsonBts, err := os.ReadFile(filepath.Join(s.logFilePath, balancesReportDir, name+".json"))
+	assert.Nil(t, err)
+	decoded := BalancesBreakdown{}
+	assert.Nil(t, json.Unmarshal(jsonBts, &decoded))
+	assert.Len(t, decoded.Validators, 2)
+	assert.True(t, decoded.Validators[0].UserEth.Equal(decimal.RequireFromString("31000000000000000000")))
+
+	csvBts, err := os.ReadFile(filepath.Join(s.logFilePath, balancesReportDir, name+".csv"))
+	assert.Nil(t, err)
+	lines := strings.Split(strings.TrimSpace(string(csvBts)), "\n")
+	assert.Len(t, lines, 3)
+	assert.Equal(t, "0a,1,,0,2,6,32000000000,31000000000000000000", lines[1])
+
+	// uploaded once per epoch
+	assert.Equal(t, jsonBts, dds.uploaded[name+".json"])
+	delete(dds.uploaded, name+".json")
+	assert.Nil(t, s.writeBalancesReport(b))
+	assert.Empty(t, dds.uploaded)
+
+	// written but never uploaded in dry run
+	s.dryRun = true
+	b.TargetEpoch = 226
+	assert.Nil(t, s.writeBalancesReport(b))
+	assert.Empty(t, dds.uploaded)
+	_, err = os.Stat(filepath.Join(s.logFilePath, balancesReportDir, "0x61135c59a4eb452b89963188ed6b6a7487049764-balances-943-226.json"))
+	assert.Nil(t, err)
+}
@@ -0,0 +1,73 @@
+package service
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// encodeCachedBeaconBlock encodes block as uvarints: execution block number, proposer index,
+// withdrawals count and (validator index, amount) of each withdrawal. slot is the store key.
+func encodeCachedBeaconBlock(block *CachedBeaconBlock) []byte {
+	buf := make([]byte, 0, binary.MaxVarintLen64*(3+2*len(block.Withdrawals)))
+	buf = binary.AppendUvarint(buf, block.ExecutionBlockNumber)
+	buf = binary.AppendUvarint(buf, block.ProposerIndex)
+	buf = binary.AppendUvarint(buf, uint64(len(block.Withdrawals)))
+	for _, w := range block.Withdrawals {
+		buf = binary.AppendUvarint(buf, w.ValidatorIndex)
+		buf = binary.AppendUvarint(buf, w.Amount)
+	}
+	return buf
+}
+
+func decodeCachedBeaconBlock(slot uint64, data []byte) (*CachedBeaconBlock, error) {
+	next := func() (uint64, error) {
+		value, n := binary.Uvarint(data)
+		if n <= 0 {
+			return 0, fmt.Errorf("decode cached beacon block %d failed", slot)
+		}
+		data = data[n:]
+		return value, nil
+	}
+
+	executionBlockNumber, err := next()
+	if err != nil {
+		return nil, err
+	}
+	proposerIndex, err := next()
+	if err != nil {
+		return nil, err
+	}
+	withdrawalsLen, err := next()
+	if err != nil {
+		return nil, err
+	}
+	// each withdrawal takes at least 2 bytes
+	if withdrawalsLen > uint64(len(data)/2) {
+		return nil, fmt.Errorf("decode cached beacon block %d failed: withdrawals length %d", slot, withdrawalsLen)
+	}
+
+	block := CachedBeaconBlock{
+		BeaconBlockId:        slot,
+		ExecutionBlockNumber: executionBlockNumber,
+		ProposerIndex:        proposerIndex,
+		Withdrawals:          make([]*CachedWithdrawal, 0, withdrawalsLen),
+	}
+	for i := uint64(0); i < withdrawalsLen; i++ {
+		validatorIndex, err := next()
+		if err != nil {
+			return nil, err
+		}
+		amount, err := next()
+		if err != nil {
+			return nil, err
+		}
+		block.Withdrawals = append(block.Withdrawals, &CachedWithdrawal{
+			ValidatorIndex: validatorIndex,
+			Amount:         amount,
+		})
+	}
+	if len(data) != 0 {
+		return nil, fmt.Errorf("decode cached beacon block %d failed: %d trailing bytes", slot, len(data))
+	}
+	return &block, nil
+}
@@ -0,0 +1,25 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCachedBeaconBlockCodec(t *testing.T) {
+	block := &CachedBeaconBlock{
+		BeaconBlockId:        8000001,
+		ExecutionBlockNumber: 17000000,
+		ProposerIndex:        123456,
+		Withdrawals: []*CachedWithdrawal{
+			{ValidatorIndex: 1, Amount: 32000000000},
+			{ValidatorIndex: 99999, Amount: 1},
+		},
+	}
+	decoded, err := decodeCachedBeaconBlock(block.BeaconBlockId, encodeCachedBeaconBlock(block))
+	assert.Nil(t, err)
+	assert.Equal(t, block, decoded)
+
+	_, err = decodeCachedBeaconBlock(1, []byte{0x01, 0x02, 0x05})
+	assert.NotNil(t, err)
+}
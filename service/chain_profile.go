@@ -0,0 +1,58 @@
+package service
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/shopspring/decimal"
+	"github.com/stafiprotocol/eth-lsd-relay/pkg/config"
+	"github.com/stafiprotocol/eth-lsd-relay/pkg/utils"
+)
+
+// chainProfilesOf merges the profiles of config into the built-in ones, a config profile replaces the built-in one of the same chain id
+func chainProfilesOf(cfgProfiles []config.ChainProfile) (utils.ChainProfiles, error) {
+	profiles := utils.DefaultChainProfiles()
+	for _, cfgProfile := range cfgProfiles {
+		genesisForkVersion, err := hexutil.Decode(cfgProfile.GenesisForkVersion)
+		if err != nil || len(genesisForkVersion) != 4 {
+			return nil, fmt.Errorf("chainProfile %s genesisForkVersion %s is not 4 bytes hex", cfgProfile.Name, cfgProfile.GenesisForkVersion)
+		}
+		domainDeposit, err := hexutil.Decode(cfgProfile.DomainDeposit)
+		if err != nil || len(domainDeposit) != 4 {
+			return nil, fmt.Errorf("chainProfile %s domainDeposit %s is not 4 bytes hex", cfgProfile.Name, cfgProfile.DomainDeposit)
+		}
+		profile := &utils.ChainProfile{
+			Name:                       cfgProfile.Name,
+			ChainId:                    cfgProfile.ChainId,
+			GenesisForkVersion:         genesisForkVersion,
+			SecondsPerSlot:             cfgProfile.SecondsPerSlot,
+			SlotsPerEpoch:              cfgProfile.SlotsPerEpoch,
+			EffectiveBalance:           cfgProfile.EffectiveBalance,
+			MaxPartialWithdrawalAmount: cfgProfile.MaxPartialWithdrawalAmount,
+		}
+		copy(profile.DomainDeposit[:], domainDeposit)
+		profiles[profile.ChainId] = profile
+	}
+	return profiles, nil
+}
+
+// initConstants inits constant variables of utils from the chain profile, values set in config take precedence
+func initConstants(cfg *config.Config, profile *utils.ChainProfile) error {
+	effectiveBalance := profile.EffectiveBalance
+	if cfg.Eth2EffectiveBalance != 0 {
+		effectiveBalance = cfg.Eth2EffectiveBalance
+	}
+	maxPartialWithdrawalAmount := profile.MaxPartialWithdrawalAmount
+	if cfg.MaxPartialWithdrawalAmount != 0 {
+		maxPartialWithdrawalAmount = cfg.MaxPartialWithdrawalAmount
+	}
+	if maxPartialWithdrawalAmount >= effectiveBalance {
+		return fmt.Errorf("maxPartialWithdrawalAmount %d must be less than eth2EffectiveBalance %d", maxPartialWithdrawalAmount, effectiveBalance)
+	}
+
+	utils.StandardEffectiveBalance = effectiveBalance * 1e9                                                                // unit Gwei
+	utils.StandardEffectiveBalanceDeci = decimal.NewFromInt(int64(utils.StandardEffectiveBalance)).Mul(utils.GweiDeci)     // unit wei
+	utils.MaxPartialWithdrawalAmount = maxPartialWithdrawalAmount * 1e9                                                    // unit Gwei
+	utils.MaxPartialWithdrawalAmountDeci = decimal.NewFromInt(int64(utils.MaxPartialWithdrawalAmount)).Mul(utils.GweiDeci) // unit wei
+	return nil
+}
@@ -0,0 +1,67 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/prysmaticlabs/prysm/v4/beacon-chain/core/signing"
+	"github.com/prysmaticlabs/prysm/v4/config/params"
+	"github.com/stafiprotocol/eth-lsd-relay/pkg/config"
+	"github.com/stafiprotocol/eth-lsd-relay/pkg/connection/beacon"
+	"github.com/stafiprotocol/eth-lsd-relay/pkg/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChainProfilesOf(t *testing.T) {
+	profiles, err := chainProfilesOf([]config.ChainProfile{
+		{
+			Name:                       "devnet",
+			ChainId:                    3151908,
+			GenesisForkVersion:         "0x10000038",
+			DomainDeposit:              "0x03000000",
+			SecondsPerSlot:             6,
+			SlotsPerEpoch:              32,
+			EffectiveBalance:           32,
+			MaxPartialWithdrawalAmount: 8,
+		},
+	})
+	assert.Nil(t, err)
+
+	// built-in profiles are kept
+	mainnet, err := profiles.Get(1)
+	assert.Nil(t, err)
+	domain, err := mainnet.DepositDomain()
+	assert.Nil(t, err)
+	expected, err := signing.ComputeDomain(params.MainnetConfig().DomainDeposit, params.MainnetConfig().GenesisForkVersion, params.MainnetConfig().ZeroHash[:])
+	assert.Nil(t, err)
+	assert.Equal(t, expected, domain)
+
+	devnet, err := profiles.Get(3151908)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte{0x10, 0x00, 0x00, 0x38}, devnet.GenesisForkVersion)
+	assert.Nil(t, devnet.Check(beacon.Eth2Config{GenesisForkVersion: []byte{0x10, 0x00, 0x00, 0x38}, SecondsPerSlot: 6, SlotsPerEpoch: 32}))
+	assert.ErrorContains(t, devnet.Check(beacon.Eth2Config{GenesisForkVersion: []byte{0x10, 0x00, 0x00, 0x38}, SecondsPerSlot: 12, SlotsPerEpoch: 32}), "seconds per slot")
+	assert.ErrorContains(t, devnet.Check(beacon.Eth2Config{GenesisForkVersion: params.MainnetConfig().GenesisForkVersion}), "genesis fork version")
+
+	_, err = profiles.Get(12345)
+	assert.ErrorContains(t, err, "unsupported chainId")
+
+	_, err = chainProfilesOf([]config.ChainProfile{{Name: "bad", ChainId: 1, GenesisForkVersion: "0x01", DomainDeposit: "0x03000000"}})
+	assert.ErrorContains(t, err, "genesisForkVersion")
+}
+
+func TestInitConstants(t *testing.T) {
+	profile, err := utils.DefaultChainProfiles().Get(369)
+	assert.Nil(t, err)
+
+	assert.Nil(t, initConstants(&config.Config{}, profile))
+	assert.Equal(t, uint64(32_000_000e9), utils.StandardEffectiveBalance)
+	assert.Equal(t, uint64(8_000_000e9), utils.MaxPartialWithdrawalAmount)
+
+	// values of config take precedence
+	assert.Nil(t, initConstants(&config.Config{Eth2EffectiveBalance: 32, MaxPartialWithdrawalAmount: 8}, profile))
+	assert.Equal(t, uint64(32e9), utils.StandardEffectiveBalance)
+	assert.Equal(t, "32000000000000000000", utils.StandardEffectiveBalanceDeci.String())
+	assert.Equal(t, uint64(8e9), utils.MaxPartialWithdrawalAmount)
+
+	assert.ErrorContains(t, initConstants(&config.Config{MaxPartialWithdrawalAmount: 64}, &utils.ChainProfile{EffectiveBalance: 32}), "must be less than")
+}
@@ -7,6 +7,7 @@ import (
 	"math/big"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
@@ -17,6 +18,8 @@ import (
 	"github.com/stafiprotocol/eth-lsd-relay/pkg/connection"
 	"github.com/stafiprotocol/eth-lsd-relay/pkg/connection/beacon"
 	"github.com/stafiprotocol/eth-lsd-relay/pkg/connection/types"
+	"github.com/stafiprotocol/eth-lsd-relay/pkg/metrics"
+	"github.com/stafiprotocol/eth-lsd-relay/pkg/notify"
 	"github.com/stafiprotocol/eth-lsd-relay/pkg/utils"
 )
 
@@ -24,46 +27,61 @@ func init() {
 	decimal.MarshalJSONWithoutQuotes = true
 }
 
-func (s *Service) waitProposalTxOk(txHash common.Hash, proposalId [32]byte) error {
-	_, err := s.connection.Eth1Client().WaitTxOkCommon(txHash)
-	if err != nil {
-		p, err := s.networkProposalContract.Proposals(nil, proposalId)
-		if err != nil {
-			return err
+// requestShutdown alerts the operators before asking the relay to shut down
+func requestShutdown(lsdToken, reason string) {
+	notify.Notify(notify.Alert{
+		Key:      "shutdown",
+		Severity: notify.SeverityCritical,
+		Title:    "relay is shutting down",
+		Message:  reason,
+		Fields:   map[string]string{"lsdToken": lsdToken},
+	})
+	utils.ShutdownRequestChannel <- struct{}{}
+}
+
+// proposal txs not mined in time are left to the tx manager, the next round finds them still pending
+const proposalTxWaitTimeout = 30 * time.Minute
+
+func (s *Service) waitProposalTxOk(proposal string, ptx *connection.PendingTx, proposalId [32]byte) error {
+	return s.waitProposalsTxOk(proposal, ptx, [][32]byte{proposalId})
+}
+
+// waitProposalsTxOk waits for the tx to be mined, a failed tx is ok if all its proposals were executed by other voters
+func (s *Service) waitProposalsTxOk(proposal string, ptx *connection.PendingTx, proposalIds [][32]byte) error {
+	if err := s.waitProposalTx(ptx); err != nil {
+		executed, checkErr := s.proposalsExecuted(proposalIds)
+		if checkErr != nil {
+			return checkErr
 		}
-		// proposal is executed
-		if p.Status == 2 {
+		if executed {
 			return nil
 		}
 		return err
 	}
 
+	s.observeProposalsExecuted(proposal, proposalIds)
 	return nil
 }
 
-func (s *Service) waitProposalsTxOk(txHash common.Hash, proposalIds [][32]byte) error {
-	_, err := s.connection.Eth1Client().WaitTxOkCommon(txHash)
-	if err != nil {
-		allProposalsExecuted := true
-		for _, proposalId := range proposalIds {
-			p, err := s.networkProposalContract.Proposals(nil, proposalId)
-			if err != nil {
-				return err
-			}
-			// proposal is executed
-			if p.Status != 2 {
-				allProposalsExecuted = false
-				break
-			}
+func (s *Service) waitProposalTx(ptx *connection.PendingTx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), proposalTxWaitTimeout)
+	defer cancel()
+	_, err := s.connection.TxManager().Wait(ctx, ptx)
+	return err
+}
+
+// observeProposalsExecuted counts proposals executed by our vote
+func (s *Service) observeProposalsExecuted(proposal string, proposalIds [][32]byte) {
+	for _, proposalId := range proposalIds {
+		p, err := s.networkProposalContract.Proposals(nil, proposalId)
+		if err != nil {
+			s.log.WithError(err).Debug("observeProposalsExecuted")
+			return
 		}
-		if allProposalsExecuted {
-			return nil
+		if p.Status == 2 {
+			metrics.IncProposal(s.lsdTokenAddress.String(), proposal, metrics.ProposalExecuted)
 		}
-
-		return err
 	}
-
-	return nil
 }
 
 func (s *Service) getEpochStartBlocknumberWithCheck(epoch uint64) (uint64, error) {
@@ -0,0 +1,100 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stafiprotocol/eth-lsd-relay/pkg/utils"
+)
+
+// ComputeBalances computes balances of lsdToken at targetEpoch the same way submitBalances does,
+// the service of lsdToken is synced to the target block without starting any handler or sending tx
+func (m *ServiceManager) ComputeBalances(ctx context.Context, lsdToken string, targetEpoch uint64) (*BalancesBreakdown, error) {
+	srvConfig := *m.cfg
+	srvConfig.Contracts.LsdTokenAddress = lsdToken
+	srv, err := NewService(&srvConfig, m, m.connection, m.localStore, m.stateStore)
+	if err != nil {
+		return nil, fmt.Errorf("new service for lsd token %s err %s", lsdToken, err.Error())
+	}
+	defer srv.Stop()
+
+	if err = srv.initialize(); err != nil {
+		return nil, fmt.Errorf("init service for lsd token %s err %s", lsdToken, err.Error())
+	}
+	return srv.computeBalancesOfEpoch(ctx, targetEpoch)
+}
+
+func (s *Service) computeBalancesOfEpoch(ctx context.Context, targetEpoch uint64) (*BalancesBreakdown, error) {
+	beaconHead, err := s.connection.BeaconHead()
+	if err != nil {
+		return nil, err
+	}
+	if targetEpoch > beaconHead.FinalizedEpoch {
+		return nil, fmt.Errorf("target epoch %d is not finalized, finalized epoch: %d", targetEpoch, beaconHead.FinalizedEpoch)
+	}
+	targetBlock, err := s.getEpochStartBlocknumberWithCheck(targetEpoch)
+	if err != nil {
+		return nil, err
+	}
+
+	// blocks after the latest distribute heights at target block are needed
+	withdrawalsHeight, priorityFeeHeight, err := s.latestDistributeHeightsAt(targetBlock)
+	if err != nil {
+		return nil, err
+	}
+	if err = s.syncToBlock(ctx, utils.Min(withdrawalsHeight, priorityFeeHeight), targetBlock); err != nil {
+		return nil, err
+	}
+
+	return s.computeBalances(ctx, targetEpoch, targetBlock)
+}
+
+// syncToBlock runs the sync handlers until events, validators and blocks are synced to targetBlock,
+// the block cursor is rewound to fromBlock first if blocks after it are needed but not synced
+func (s *Service) syncToBlock(ctx context.Context, fromBlock, targetBlock uint64) error {
+	if s.waitFirstNodeStakeEvent {
+		found, err := s.seekFirstNodeStakeEvent()
+		if err != nil {
+			return err
+		}
+		if !found {
+			return fmt.Errorf("no node has staked in lsd network of %s", s.lsdTokenAddress.String())
+		}
+	}
+
+	if fromBlock < s.latestBlockOfSyncBlock {
+		block, err := s.connection.Eth1Client().BlockByNumber(ctx, big.NewInt(int64(fromBlock)))
+		if err != nil {
+			return err
+		}
+		s.latestBlockOfSyncBlock = fromBlock
+		s.latestSlotOfSyncBlock = utils.SlotAtTimestamp(s.eth2Config, block.Time())
+	}
+
+	syncHandlers := []func() error{s.syncEvents, s.updateValidatorsFromNetwork, s.updateValidatorsFromBeacon, s.syncBlocks}
+	for s.latestBlockOfSyncEvents < targetBlock || s.latestBlockOfSyncBlock < targetBlock {
+		for _, handler := range syncHandlers {
+			if err := handler(); err != nil {
+				return err
+			}
+		}
+		s.log.WithFields(logrus.Fields{
+			"targetBlock":             targetBlock,
+			"latestBlockOfSyncEvents": s.latestBlockOfSyncEvents,
+			"latestBlockOfSyncBlock":  s.latestBlockOfSyncBlock,
+		}).Info("syncing to target block")
+
+		if s.latestBlockOfSyncEvents >= targetBlock && s.latestBlockOfSyncBlock >= targetBlock {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Duration(s.eth2Config.SecondsPerSlot) * time.Second):
+		}
+	}
+	return nil
+}
@@ -137,27 +137,15 @@ func (s *Service) calMaxClaimableWithdrawIndex(targetEth1BlockHeight uint64, tot
 }
 
 func (s *Service) sendDistributeTx(distributeType uint8, targetEth1BlockHeight, totalUserEth, totalNodeEth, totalPlatformEth, newMaxClaimableWithdrawIndex *big.Int) error {
-	err := s.connection.LockAndUpdateTxOpts()
-	if err != nil {
-		return fmt.Errorf("LockAndUpdateTxOpts err: %w", err)
-	}
-	defer s.connection.UnlockTxOpts()
-
 	encodeBts, err := s.networkWithdrawAbi.Pack("distribute", distributeType, targetEth1BlockHeight,
 		totalUserEth, totalNodeEth, totalPlatformEth, newMaxClaimableWithdrawIndex)
 	if err != nil {
 		return err
 	}
 
-	proposalId := utils.ProposalId(s.networkWithdrawAddress, encodeBts, targetEth1BlockHeight)
-
-	// check voted
-	hasVoted, err := s.networkProposalContract.HasVoted(nil, proposalId, s.connection.Keypair().CommonAddress())
-	if err != nil {
-		return fmt.Errorf("networkProposalContract.HasVoted err: %s", err)
-	}
-	if hasVoted {
-		return nil
+	proposal := "distributeWithdrawals"
+	if distributeType == utils.DistributeTypePriorityFee {
+		proposal = "distributePriorityFee"
 	}
 
 	s.log.WithFields(logrus.Fields{
@@ -169,12 +157,5 @@ func (s *Service) sendDistributeTx(distributeType uint8, targetEth1BlockHeight,
 		"newMaxClaimableWithdrawIndex": newMaxClaimableWithdrawIndex,
 	}).Info("Will sendDistributeTx")
 
-	tx, err := s.networkProposalContract.ExecProposal(s.connection.TxOpts(), s.networkWithdrawAddress, encodeBts, targetEth1BlockHeight)
-	if err != nil {
-		return err
-	}
-
-	s.log.Infof("send Distribute tx hash: %s", tx.Hash().String())
-
-	return s.waitProposalTxOk(tx.Hash(), proposalId)
+	return s.execProposal(proposal, newProposalCall(s.networkWithdrawAddress, encodeBts, targetEth1BlockHeight))
 }
@@ -0,0 +1,373 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/sirupsen/logrus"
+	"github.com/stafiprotocol/eth-lsd-relay/pkg/utils"
+)
+
+// dry run results
+const (
+	DryRunPending    = "pending"    // not executed on chain yet
+	DryRunMatched    = "matched"    // the same proposal got executed on chain
+	DryRunMismatched = "mismatched" // another proposal of the same round got executed on chain
+	DryRunExpired    = "expired"    // nothing of the same round executed on chain before dryRunProposalTTL
+)
+
+const dryRunProposalTTL = 3 * utils.Day
+
+// DryRunProposal is a proposal computed but not sent in dry run mode, one json line is appended to
+// the report when it is recorded and another one when it is resolved
+type DryRunProposal struct {
+	LsdToken      string    `json:"lsdToken"`
+	Proposal      string    `json:"proposal"`
+	Result        string    `json:"result"`
+	ProposalId    string    `json:"proposalId"`
+	To            string    `json:"to"`
+	Method        string    `json:"method"`
+	Factor        string    `json:"factor"`
+	Args          []string  `json:"args"`
+	CallData      string    `json:"callData"`
+	RecordedAt    time.Time `json:"recordedAt"`
+	RecordedBlock uint64    `json:"recordedBlock"`
+
+	ExecutedProposalId string    `json:"executedProposalId,omitempty"`
+	ExecutedArgs       []string  `json:"executedArgs,omitempty"`
+	ExecutedCallData   string    `json:"executedCallData,omitempty"`
+	ExecutedTx         string    `json:"executedTx,omitempty"`
+	ExecutedBlock      uint64    `json:"executedBlock,omitempty"`
+	ResolvedAt         time.Time `json:"resolvedAt,omitempty"`
+
+	round string
+}
+
+// executedProposal is a proposal executed on chain found by scanning ProposalExecuted events
+type executedProposal struct {
+	call       proposalCall
+	args       []string
+	txHash     common.Hash
+	block      uint64
+	observedAt time.Time
+}
+
+type dryRunReport struct {
+	mutex        sync.Mutex
+	path         string
+	recorded     map[[32]byte]bool
+	pending      map[[32]byte]*DryRunProposal
+	executed     map[string]*executedProposal // round -> latest executed proposal of the round
+	scannedBlock uint64
+}
+
+func newDryRunReport(path string) *dryRunReport {
+	return &dryRunReport{
+		path:     path,
+		recorded: make(map[[32]byte]bool),
+		pending:  make(map[[32]byte]*DryRunProposal),
+		executed: make(map[string]*executedProposal),
+	}
+}
+
+func (r *dryRunReport) append(p *DryRunProposal) error {
+	line, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(r.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(append(line, '\n'))
+	return err
+}
+
+// recordDryRunProposals appends calls not recorded yet to the dry run report
+func (s *Service) recordDryRunProposals(proposal string, calls []proposalCall) error {
+	latestBlock, err := s.connection.Eth1LatestBlock()
+	if err != nil {
+		return err
+	}
+
+	r := s.dryRunReport
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for _, call := range calls {
+		if r.recorded[call.id] {
+			continue
+		}
+		round, method, args := s.decodeProposalCall(call)
+		p := &DryRunProposal{
+			LsdToken:      s.lsdTokenAddress.String(),
+			Proposal:      proposal,
+			Result:        DryRunPending,
+			ProposalId:    hexutil.Encode(call.id[:]),
+			To:            call.to.String(),
+			Method:        method,
+			Factor:        call.factor.String(),
+			Args:          args,
+			CallData:      hexutil.Encode(call.callData),
+			RecordedAt:    time.Now(),
+			RecordedBlock: latestBlock,
+			round:         round,
+		}
+		if err := r.append(p); err != nil {
+			return fmt.Errorf("append dry run report err: %w", err)
+		}
+		r.recorded[call.id] = true
+		r.pending[call.id] = p
+
+		s.log.WithFields(logrus.Fields{
+			"proposal":   proposal,
+			"proposalId": p.ProposalId,
+			"method":     method,
+			"args":       args,
+		}).Info("dry run: proposal recorded instead of sent")
+	}
+	return nil
+}
+
+// checkDryRunProposals compares pending dry run proposals with proposals executed on chain
+func (s *Service) checkDryRunProposals() error {
+	r := s.dryRunReport
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if len(r.pending) == 0 && r.scannedBlock == 0 {
+		return nil
+	}
+	latestBlock, err := s.connection.Eth1LatestBlock()
+	if err != nil {
+		return err
+	}
+
+	fromBlock := r.scannedBlock + 1
+	if r.scannedBlock == 0 {
+		// proposals of a round may be executed by other voters before we record ours
+		lookback := s.submitBalancesDuEpochs * s.eth2Config.SlotsPerEpoch
+		fromBlock = latestBlock
+		for _, p := range r.pending {
+			fromBlock = utils.Min(fromBlock, p.RecordedBlock)
+		}
+		if fromBlock > lookback {
+			fromBlock -= lookback
+		}
+	}
+	if fromBlock <= latestBlock {
+		if err := s.scanExecutedProposals(fromBlock, latestBlock); err != nil {
+			return err
+		}
+		r.scannedBlock = latestBlock
+	}
+
+	for id, p := range r.pending {
+		executed, exist := r.executed[p.round]
+		if !exist {
+			// executed before the scanned range
+			onchain, err := s.networkProposalContract.Proposals(nil, id)
+			if err != nil {
+				return err
+			}
+			switch {
+			case onchain.Status == 2:
+				p.Result = DryRunMatched
+				p.ExecutedProposalId = p.ProposalId
+			case time.Since(p.RecordedAt) > dryRunProposalTTL:
+				p.Result = DryRunExpired
+			default:
+				continue
+			}
+		} else {
+			p.ExecutedProposalId = hexutil.Encode(executed.call.id[:])
+			p.ExecutedArgs = executed.args
+			p.ExecutedCallData = hexutil.Encode(executed.call.callData)
+			p.ExecutedTx = executed.txHash.String()
+			p.ExecutedBlock = executed.block
+			p.Result = DryRunMatched
+			if executed.call.id != id {
+				p.Result = DryRunMismatched
+			}
+		}
+		p.ResolvedAt = time.Now()
+		if err := r.append(p); err != nil {
+			return fmt.Errorf("append dry run report err: %w", err)
+		}
+		delete(r.pending, id)
+
+		log := s.log.WithFields(logrus.Fields{
+			"proposal":           p.Proposal,
+			"proposalId":         p.ProposalId,
+			"executedProposalId": p.ExecutedProposalId,
+			"args":               p.Args,
+			"executedArgs":       p.ExecutedArgs,
+			"executedTx":         p.ExecutedTx,
+		})
+		switch p.Result {
+		case DryRunMismatched:
+			log.Warn("dry run: proposal mismatched with the one executed on chain")
+		case DryRunExpired:
+			log.Warn("dry run: proposal expired, nothing of the same round executed on chain")
+		default:
+			log.Info("dry run: proposal matched with the one executed on chain")
+		}
+	}
+
+	for round, executed := range r.executed {
+		if time.Since(executed.observedAt) > dryRunProposalTTL {
+			delete(r.executed, round)
+		}
+	}
+	return nil
+}
+
+// scanExecutedProposals caches proposals executed between fromBlock and toBlock by their round,
+// the executed call data is decoded from the input of the transaction emitting ProposalExecuted
+func (s *Service) scanExecutedProposals(fromBlock, toBlock uint64) error {
+	calls := make(map[common.Hash][]proposalCall)
+	for start := fromBlock; start <= toBlock; start += s.eventFilterMaxSpanBlocks {
+		end := start + s.eventFilterMaxSpanBlocks - 1
+		if end > toBlock {
+			end = toBlock
+		}
+		iter, err := s.networkProposalContract.FilterProposalExecuted(&bind.FilterOpts{
+			Start: start,
+			End:   &end,
+		}, nil)
+		if err != nil {
+			return fmt.Errorf("filter proposal executed err: %w", err)
+		}
+
+		for iter.Next() {
+			txHash := iter.Event.Raw.TxHash
+			txCalls, exist := calls[txHash]
+			if !exist {
+				tx, _, err := s.connection.Eth1Client().TransactionByHash(context.Background(), txHash)
+				if err != nil {
+					iter.Close()
+					return fmt.Errorf("get tx %s err: %w", txHash.String(), err)
+				}
+				txCalls, err = s.decodeExecProposalsInput(tx.Data())
+				if err != nil {
+					s.log.WithFields(logrus.Fields{
+						"tx":  txHash.String(),
+						"err": err,
+					}).Debug("dry run: skip undecodable proposal tx")
+				}
+				calls[txHash] = txCalls
+			}
+
+			for _, call := range txCalls {
+				if call.id != iter.Event.ProposalId {
+					continue
+				}
+				round, _, args := s.decodeProposalCall(call)
+				s.dryRunReport.executed[round] = &executedProposal{
+					call:       call,
+					args:       args,
+					txHash:     txHash,
+					block:      iter.Event.Raw.BlockNumber,
+					observedAt: time.Now(),
+				}
+			}
+		}
+		iter.Close()
+	}
+	return nil
+}
+
+// decodeExecProposalsInput decodes calls from the input of an execProposal or batchExecProposals tx
+func (s *Service) decodeExecProposalsInput(input []byte) ([]proposalCall, error) {
+	if len(input) < 4 {
+		return nil, fmt.Errorf("input too short")
+	}
+	method, err := s.networkProposalAbi.MethodById(input[:4])
+	if err != nil {
+		return nil, err
+	}
+	args, err := method.Inputs.Unpack(input[4:])
+	if err != nil {
+		return nil, err
+	}
+
+	switch method.Name {
+	case "execProposal":
+		return []proposalCall{newProposalCall(args[0].(common.Address), args[1].([]byte), args[2].(*big.Int))}, nil
+	case "batchExecProposals":
+		tos, callDatas, factors := args[0].([]common.Address), args[1].([][]byte), args[2].([]*big.Int)
+		if len(tos) != len(callDatas) || len(tos) != len(factors) {
+			return nil, fmt.Errorf("batchExecProposals args len not match")
+		}
+		calls := make([]proposalCall, len(tos))
+		for i := range tos {
+			calls[i] = newProposalCall(tos[i], callDatas[i], factors[i])
+		}
+		return calls, nil
+	default:
+		return nil, fmt.Errorf("unsupported method %s", method.Name)
+	}
+}
+
+// decodeProposalCall returns the round, method and args of call. Calls of the same round target the
+// same method with the same proposal factor (block, epoch or cycle), calls with zero factor such as
+// voteWithdrawCredentials are told apart by their first arg.
+func (s *Service) decodeProposalCall(call proposalCall) (round, method string, args []string) {
+	method = hexutil.Encode(call.callData[:utils.Min(4, len(call.callData))])
+	round = fmt.Sprintf("%s/%s/%s", call.to.String(), method, call.factor.String())
+
+	var contractAbi *abi.ABI
+	switch call.to {
+	case s.networkWithdrawAddress:
+		contractAbi = &s.networkWithdrawAbi
+	case s.networkBalancesAddress:
+		contractAbi = &s.networkBalancesAbi
+	case s.nodeDepositAddress:
+		contractAbi = &s.nodeDepositAbi
+	default:
+		return
+	}
+	if len(call.callData) < 4 {
+		return
+	}
+	m, err := contractAbi.MethodById(call.callData[:4])
+	if err != nil {
+		return
+	}
+	values, err := m.Inputs.Unpack(call.callData[4:])
+	if err != nil {
+		return
+	}
+
+	method = m.Name
+	args = make([]string, len(values))
+	for i, v := range values {
+		args[i] = formatProposalArg(v)
+	}
+	round = fmt.Sprintf("%s/%s/%s", call.to.String(), method, call.factor.String())
+	if call.factor.Sign() == 0 && len(args) > 0 {
+		round += "/" + args[0]
+	}
+	return
+}
+
+func formatProposalArg(v any) string {
+	switch v := v.(type) {
+	case []byte:
+		return hexutil.Encode(v)
+	case [32]byte:
+		return hexutil.Encode(v[:])
+	default:
+		return fmt.Sprint(v)
+	}
+}
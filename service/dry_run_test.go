@@ -0,0 +1,115 @@
+package service
+
+import (
+	"bufio"
+	"encoding/json"
+	"math/big"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	network_balances "github.com/stafiprotocol/eth-lsd-relay/bindings/NetworkBalances"
+	network_proposal "github.com/stafiprotocol/eth-lsd-relay/bindings/NetworkProposal"
+	node_deposit "github.com/stafiprotocol/eth-lsd-relay/bindings/NodeDeposit"
+	"github.com/stretchr/testify/assert"
+)
+
+func newDryRunTestService(t *testing.T) *Service {
+	s := newStateTestService(nil)
+	s.networkBalancesAddress = common.HexToAddress("0x01")
+	s.nodeDepositAddress = common.HexToAddress("0x02")
+
+	var err error
+	s.networkBalancesAbi, err = abi.JSON(strings.NewReader(network_balances.NetworkBalancesABI))
+	assert.Nil(t, err)
+	s.nodeDepositAbi, err = abi.JSON(strings.NewReader(node_deposit.NodeDepositABI))
+	assert.Nil(t, err)
+	s.networkProposalAbi, err = abi.JSON(strings.NewReader(network_proposal.NetworkProposalABI))
+	assert.Nil(t, err)
+	s.dryRun = true
+	s.dryRunReport = newDryRunReport(t.TempDir() + "/dry_run.jsonl")
+	return s
+}
+
+func TestDecodeProposalCallRound(t *testing.T) {
+	s := newDryRunTestService(t)
+
+	submitBalances := func(totalUserEth int64) proposalCall {
+		callData, err := s.networkBalancesAbi.Pack("submitBalances", big.NewInt(100), big.NewInt(totalUserEth), big.NewInt(5))
+		assert.Nil(t, err)
+		return newProposalCall(s.networkBalancesAddress, callData, big.NewInt(100))
+	}
+	round1, method, args := s.decodeProposalCall(submitBalances(1))
+	round2, _, _ := s.decodeProposalCall(submitBalances(2))
+	assert.Equal(t, "submitBalances", method)
+	assert.Equal(t, []string{"100", "1", "5"}, args)
+	assert.Equal(t, round1, round2)
+
+	voteWithdrawCredentials := func(pubkey []byte, match bool) proposalCall {
+		callData, err := s.nodeDepositAbi.Pack("voteWithdrawCredentials", pubkey, match)
+		assert.Nil(t, err)
+		return newProposalCall(s.nodeDepositAddress, callData, big.NewInt(0))
+	}
+	roundA, _, _ := s.decodeProposalCall(voteWithdrawCredentials([]byte{0x0a}, true))
+	roundAUnmatch, _, _ := s.decodeProposalCall(voteWithdrawCredentials([]byte{0x0a}, false))
+	roundB, _, _ := s.decodeProposalCall(voteWithdrawCredentials([]byte{0x0b}, true))
+	assert.Equal(t, roundA, roundAUnmatch)
+	assert.NotEqual(t, roundA, roundB)
+
+	// unknown contract falls back to selector
+	round, method, args := s.decodeProposalCall(newProposalCall(common.HexToAddress("0x03"), []byte{1, 2, 3, 4, 5}, big.NewInt(7)))
+	assert.Equal(t, "0x01020304", method)
+	assert.Nil(t, args)
+	assert.True(t, strings.HasSuffix(round, "/0x01020304/7"))
+}
+
+func TestDecodeExecProposalsInput(t *testing.T) {
+	s := newDryRunTestService(t)
+
+	calls := []proposalCall{
+		newProposalCall(s.nodeDepositAddress, []byte{1, 2, 3, 4}, big.NewInt(0)),
+		newProposalCall(s.networkBalancesAddress, []byte{5, 6, 7, 8}, big.NewInt(9)),
+	}
+	input, err := s.networkProposalAbi.Pack("batchExecProposals",
+		[]common.Address{calls[0].to, calls[1].to},
+		[][]byte{calls[0].callData, calls[1].callData},
+		[]*big.Int{calls[0].factor, calls[1].factor})
+	assert.Nil(t, err)
+	decoded, err := s.decodeExecProposalsInput(input)
+	assert.Nil(t, err)
+	assert.Len(t, decoded, 2)
+	assert.Equal(t, calls[0].id, decoded[0].id)
+	assert.Equal(t, calls[1].id, decoded[1].id)
+
+	input, err = s.networkProposalAbi.Pack("execProposal", calls[1].to, calls[1].callData, calls[1].factor)
+	assert.Nil(t, err)
+	decoded, err = s.decodeExecProposalsInput(input)
+	assert.Nil(t, err)
+	assert.Len(t, decoded, 1)
+	assert.Equal(t, calls[1].id, decoded[0].id)
+
+	_, err = s.decodeExecProposalsInput([]byte{1, 2})
+	assert.NotNil(t, err)
+}
+
+func TestDryRunReportAppend(t *testing.T) {
+	s := newDryRunTestService(t)
+	r := s.dryRunReport
+
+	assert.Nil(t, r.append(&DryRunProposal{Proposal: "submitBalances", Result: DryRunPending}))
+	assert.Nil(t, r.append(&DryRunProposal{Proposal: "submitBalances", Result: DryRunMismatched, ExecutedTx: "0x01"}))
+
+	f, err := os.Open(r.path)
+	assert.Nil(t, err)
+	defer f.Close()
+	results := make([]string, 0)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		p := DryRunProposal{}
+		assert.Nil(t, json.Unmarshal(scanner.Bytes(), &p))
+		results = append(results, p.Result)
+	}
+	assert.Equal(t, []string{DryRunPending, DryRunMismatched}, results)
+}
@@ -0,0 +1,274 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/shopspring/decimal"
+	network_withdraw "github.com/stafiprotocol/eth-lsd-relay/bindings/NetworkWithdraw"
+	"github.com/stafiprotocol/eth-lsd-relay/pkg/utils"
+)
+
+// the SetMerkleRoot event of epoch N is searched within this many merkle root durations after the start block of N:
+// voters only vote for N while the finalized epoch is below N + merkleRootDuEpochs, one more duration is left for
+// votes mined late
+const setMerkleRootWindowDuEpochs = 2
+
+var (
+	ErrNoMerkleRoot       = errors.New("no merkle root set on chain")
+	ErrMerkleRootNotFound = errors.New("merkle root of epoch not found on chain")
+	ErrNodeNotInRewards   = errors.New("node not in rewards list")
+)
+
+// NodeRewardProof is what a node needs to claim on the network withdraw contract
+type NodeRewardProof struct {
+	LsdToken               string          `json:"lsdToken"`
+	Epoch                  uint64          `json:"epoch"`
+	Address                string          `json:"address"`
+	Index                  uint32          `json:"index"`
+	TotalRewardAmount      decimal.Decimal `json:"totalRewardAmount"`
+	TotalExitDepositAmount decimal.Decimal `json:"totalExitDepositAmount"`
+	Proof                  []string        `json:"proof"`
+	MerkleRoot             string          `json:"merkleRoot"`
+	NodeRewardsFileCid     string          `json:"nodeRewardsFileCid"`
+	IsLatest               bool            `json:"isLatest"` // only proofs of the latest merkle root can be claimed
+}
+
+// merkleRootOfEpoch is the root and the node rewards file cid set on chain for an epoch
+type merkleRootOfEpoch struct {
+	root common.Hash
+	cid  string
+}
+
+// verifiedNodeRewards is a rewards list whose merkle root matches the one set on chain
+type verifiedNodeRewards struct {
+	epoch uint64
+	root  common.Hash
+	cid   string
+	nodes map[common.Address]*NodeReward
+	tree  *utils.MerkleTree
+}
+
+// NodeRewardProof returns the proof of node at epoch, the latest merkle root epoch is used if epoch is 0.
+// The rewards file is checked against the cid and the merkle root set on chain before answering
+func (s *Service) NodeRewardProof(node common.Address, epoch uint64) (*NodeRewardProof, error) {
+	latestBlock, err := s.connection.Eth1LatestBlock()
+	if err != nil {
+		return nil, err
+	}
+	callOpts := s.connection.CallOpts(new(big.Int).SetUint64(latestBlock))
+	latestEpochBig, err := s.networkWithdrawContract.LatestMerkleRootEpoch(callOpts)
+	if err != nil {
+		return nil, err
+	}
+	latestEpoch := latestEpochBig.Uint64()
+	if latestEpoch == 0 {
+		return nil, ErrNoMerkleRoot
+	}
+	if epoch == 0 {
+		epoch = latestEpoch
+	}
+	if err = s.checkMerkleRootEpoch(epoch, latestEpoch); err != nil {
+		return nil, err
+	}
+
+	rewards, err := s.verifiedNodeRewardsOfEpoch(epoch, latestEpoch, callOpts)
+	if err != nil {
+		return nil, err
+	}
+	proof, err := rewards.proofOf(node)
+	if err != nil {
+		return nil, err
+	}
+	proof.LsdToken = s.lsdTokenAddress.String()
+	proof.IsLatest = epoch == latestEpoch
+	return proof, nil
+}
+
+// checkMerkleRootEpoch rejects epochs no merkle root can be set for before any rpc is called for them
+func (s *Service) checkMerkleRootEpoch(epoch, latestEpoch uint64) error {
+	if epoch > latestEpoch {
+		return fmt.Errorf("%w: epoch %d is after the latest merkle root epoch %d", ErrMerkleRootNotFound, epoch, latestEpoch)
+	}
+	if s.merkleRootDuEpochs > 0 && epoch%s.merkleRootDuEpochs != 0 {
+		return fmt.Errorf("%w: epoch %d is not a multiple of %d", ErrMerkleRootNotFound, epoch, s.merkleRootDuEpochs)
+	}
+	return nil
+}
+
+func (s *Service) verifiedNodeRewardsOfEpoch(epoch, latestEpoch uint64, callOpts *bind.CallOpts) (*verifiedNodeRewards, error) {
+	if rewards, exist := s.nodeRewardsCache.Get(epoch); exist {
+		return rewards, nil
+	}
+
+	var root common.Hash
+	var cid string
+	var err error
+	if epoch == latestEpoch {
+		if root, err = s.networkWithdrawContract.MerkleRoot(callOpts); err != nil {
+			return nil, err
+		}
+		if cid, err = s.networkWithdrawContract.NodeRewardsFileCid(callOpts); err != nil {
+			return nil, err
+		}
+	} else {
+		if root, cid, err = s.pastMerkleRootOfEpoch(epoch, callOpts.BlockNumber.Uint64()); err != nil {
+			return nil, err
+		}
+	}
+
+	fileBytes, err := s.downloadNodeRewards(cid, epoch)
+	if err != nil {
+		return nil, err
+	}
+	list := NodeRewardsList{}
+	if err = json.Unmarshal(fileBytes, &list); err != nil {
+		return nil, err
+	}
+	if list.Epoch != epoch {
+		return nil, fmt.Errorf("node rewards file epoch %d does not match %d, cid: %s", list.Epoch, epoch, cid)
+	}
+	rewards, err := newVerifiedNodeRewards(list, cid, root)
+	if err != nil {
+		return nil, err
+	}
+
+	s.nodeRewardsCache.Add(epoch, rewards)
+	return rewards, nil
+}
+
+// newVerifiedNodeRewards rebuilds the merkle tree of list and checks its root against the root set on chain
+func newVerifiedNodeRewards(list NodeRewardsList, cid string, onchainRoot common.Hash) (*verifiedNodeRewards, error) {
+	rewards := &verifiedNodeRewards{
+		epoch: list.Epoch,
+		cid:   cid,
+		nodes: make(map[common.Address]*NodeReward, len(list.List)),
+	}
+	if len(list.List) > 0 {
+		tree, err := buildMerkleTree(list)
+		if err != nil {
+			return nil, err
+		}
+		rootHash, err := tree.GetRootHash()
+		if err != nil {
+			return nil, err
+		}
+		rewards.tree = tree
+		rewards.root = common.BytesToHash(rootHash)
+	}
+	if rewards.root != onchainRoot {
+		return nil, fmt.Errorf("merkle root of node rewards file %s does not match on chain root %s, epoch: %d cid: %s",
+			rewards.root.String(), onchainRoot.String(), list.Epoch, cid)
+	}
+	for _, nodeReward := range list.List {
+		rewards.nodes[common.HexToAddress(nodeReward.Address)] = nodeReward
+	}
+	return rewards, nil
+}
+
+func (r *verifiedNodeRewards) proofOf(node common.Address) (*NodeRewardProof, error) {
+	nodeReward, exist := r.nodes[node]
+	if !exist {
+		return nil, ErrNodeNotInRewards
+	}
+
+	nodeHash := utils.GetNodeHash(big.NewInt(int64(nodeReward.Index)), node,
+		nodeReward.TotalRewardAmount.BigInt(), nodeReward.TotalExitDepositAmount.BigInt())
+	proofList, err := r.tree.GetProof(nodeHash)
+	if err != nil {
+		return nil, fmt.Errorf("tree.GetProof failed: %w", err)
+	}
+	proof := make([]string, len(proofList))
+	for i, p := range proofList {
+		proof[i] = common.BytesToHash(p).String()
+	}
+
+	return &NodeRewardProof{
+		Epoch:                  r.epoch,
+		Address:                node.String(),
+		Index:                  nodeReward.Index,
+		TotalRewardAmount:      nodeReward.TotalRewardAmount,
+		TotalExitDepositAmount: nodeReward.TotalExitDepositAmount,
+		Proof:                  proof,
+		MerkleRoot:             r.root.String(),
+		NodeRewardsFileCid:     r.cid,
+	}, nil
+}
+
+// pastMerkleRootOfEpoch returns the root set for epoch before the latest merkle root epoch, which can not change
+// anymore, so both the root and its absence are cached
+func (s *Service) pastMerkleRootOfEpoch(epoch, toBlock uint64) (common.Hash, string, error) {
+	if cached, exist := s.merkleRootsCache.Get(epoch); exist {
+		if cached == nil {
+			return common.Hash{}, "", fmt.Errorf("%w: epoch %d", ErrMerkleRootNotFound, epoch)
+		}
+		return cached.root, cached.cid, nil
+	}
+
+	event, err := s.setMerkleRootEventOfEpoch(epoch, toBlock)
+	if err != nil {
+		if errors.Is(err, ErrMerkleRootNotFound) {
+			s.merkleRootsCache.Add(epoch, nil)
+		}
+		return common.Hash{}, "", err
+	}
+	s.merkleRootsCache.Add(epoch, &merkleRootOfEpoch{root: event.MerkleRoot, cid: event.NodeRewardsFileCid})
+	return event.MerkleRoot, event.NodeRewardsFileCid, nil
+}
+
+// setMerkleRootEventOfEpoch looks for the SetMerkleRoot event of epoch, which is emitted after the start block of epoch
+func (s *Service) setMerkleRootEventOfEpoch(epoch, toBlock uint64) (*network_withdraw.NetworkWithdrawSetMerkleRoot, error) {
+	fromBlock, err := s.getEpochStartBlocknumberWithCheck(epoch)
+	if err != nil {
+		return nil, err
+	}
+	toBlock = s.setMerkleRootSearchEnd(fromBlock, toBlock)
+	for start := fromBlock; start <= toBlock; start += s.eventFilterMaxSpanBlocks {
+		end := start + s.eventFilterMaxSpanBlocks - 1
+		if end > toBlock {
+			end = toBlock
+		}
+		event, err := s.firstSetMerkleRootEvent(start, end, []*big.Int{new(big.Int).SetUint64(epoch)})
+		if err != nil {
+			return nil, err
+		}
+		if event != nil {
+			return event, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: epoch %d", ErrMerkleRootNotFound, epoch)
+}
+
+// setMerkleRootSearchEnd bounds toBlock to the setMerkleRootWindowDuEpochs window after fromBlock,
+// there is at most one execution block per slot
+func (s *Service) setMerkleRootSearchEnd(fromBlock, toBlock uint64) uint64 {
+	if s.merkleRootDuEpochs == 0 || s.eth2Config.SlotsPerEpoch == 0 {
+		return toBlock
+	}
+	windowEnd := fromBlock + setMerkleRootWindowDuEpochs*s.merkleRootDuEpochs*s.eth2Config.SlotsPerEpoch
+	if windowEnd < toBlock {
+		return windowEnd
+	}
+	return toBlock
+}
+
+// firstSetMerkleRootEvent returns nil if there is no SetMerkleRoot event between start and end
+func (s *Service) firstSetMerkleRootEvent(start, end uint64, epochs []*big.Int) (*network_withdraw.NetworkWithdrawSetMerkleRoot, error) {
+	iter, err := s.networkWithdrawContract.FilterSetMerkleRoot(&bind.FilterOpts{
+		Start: start,
+		End:   &end,
+	}, epochs)
+	if err != nil {
+		return nil, fmt.Errorf("filter set merkle root err: %w", err)
+	}
+	defer iter.Close()
+
+	if iter.Next() {
+		return iter.Event, nil
+	}
+	return nil, iter.Error()
+}
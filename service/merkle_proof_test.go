@@ -0,0 +1,93 @@
+package service
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	lru "github.com/hashicorp/golang-lru/v2"
+	"github.com/shopspring/decimal"
+	"github.com/stafiprotocol/eth-lsd-relay/pkg/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerifiedNodeRewardsProof(t *testing.T) {
+	list := NodeRewardsList{Epoch: 900}
+	for i := 0; i < 5; i++ {
+		list.List = append(list.List, &NodeReward{
+			Address:                common.BigToAddress(big.NewInt(int64(i + 1))).String(),
+			Index:                  uint32(i),
+			TotalRewardAmount:      decimal.NewFromInt(int64(i+1) * 1e15),
+			TotalExitDepositAmount: decimal.NewFromInt(int64(i) * 1e18),
+		})
+	}
+	tree, err := buildMerkleTree(list)
+	assert.Nil(t, err)
+	rootHash, err := tree.GetRootHash()
+	assert.Nil(t, err)
+	root := common.BytesToHash(rootHash)
+
+	_, err = newVerifiedNodeRewards(list, "cid", common.Hash{1})
+	assert.ErrorContains(t, err, "does not match on chain root")
+
+	rewards, err := newVerifiedNodeRewards(list, "cid", root)
+	assert.Nil(t, err)
+
+	node := common.BigToAddress(big.NewInt(3))
+	proof, err := rewards.proofOf(node)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(900), proof.Epoch)
+	assert.Equal(t, uint32(2), proof.Index)
+	assert.Equal(t, root.String(), proof.MerkleRoot)
+	assert.True(t, proof.TotalRewardAmount.Equal(decimal.NewFromInt(3e15)))
+
+	proofList := make([]utils.NodeHash, len(proof.Proof))
+	for i, p := range proof.Proof {
+		proofList[i] = common.HexToHash(p).Bytes()
+	}
+	leaf := utils.GetNodeHash(big.NewInt(2), node, big.NewInt(3e15), big.NewInt(2e18))
+	assert.True(t, utils.VerifyProof(leaf, proofList, rootHash))
+
+	_, err = rewards.proofOf(common.BigToAddress(big.NewInt(100)))
+	assert.ErrorIs(t, err, ErrNodeNotInRewards)
+
+	// an empty list has the zero root
+	empty, err := newVerifiedNodeRewards(NodeRewardsList{Epoch: 1}, "cid", common.Hash{})
+	assert.Nil(t, err)
+	_, err = empty.proofOf(node)
+	assert.ErrorIs(t, err, ErrNodeNotInRewards)
+}
+
+func TestCheckMerkleRootEpoch(t *testing.T) {
+	s := &Service{merkleRootDuEpochs: 225}
+	assert.Nil(t, s.checkMerkleRootEpoch(450, 900))
+	assert.Nil(t, s.checkMerkleRootEpoch(900, 900))
+	assert.ErrorIs(t, s.checkMerkleRootEpoch(1125, 900), ErrMerkleRootNotFound)
+	assert.ErrorIs(t, s.checkMerkleRootEpoch(1, 900), ErrMerkleRootNotFound)
+	assert.ErrorIs(t, s.checkMerkleRootEpoch(451, 900), ErrMerkleRootNotFound)
+}
+
+func TestSetMerkleRootSearchEnd(t *testing.T) {
+	s := &Service{merkleRootDuEpochs: 225}
+	s.eth2Config.SlotsPerEpoch = 32
+	// two durations of slots after the epoch start block
+	assert.Equal(t, uint64(1000+2*225*32), s.setMerkleRootSearchEnd(1000, 1e8))
+	assert.Equal(t, uint64(5000), s.setMerkleRootSearchEnd(1000, 5000))
+}
+
+func TestPastMerkleRootOfEpochCache(t *testing.T) {
+	cache, err := lru.New[uint64, *merkleRootOfEpoch](8)
+	assert.Nil(t, err)
+	// no contract, any rpc call would panic
+	s := &Service{merkleRootsCache: cache}
+
+	cache.Add(450, &merkleRootOfEpoch{root: common.Hash{1}, cid: "cid"})
+	root, cid, err := s.pastMerkleRootOfEpoch(450, 1e8)
+	assert.Nil(t, err)
+	assert.Equal(t, common.Hash{1}, root)
+	assert.Equal(t, "cid", cid)
+
+	cache.Add(675, nil)
+	_, _, err = s.pastMerkleRootOfEpoch(675, 1e8)
+	assert.ErrorIs(t, err, ErrMerkleRootNotFound)
+}
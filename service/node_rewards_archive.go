@@ -0,0 +1,69 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stafiprotocol/eth-lsd-relay/pkg/destorage"
+	"github.com/stafiprotocol/eth-lsd-relay/pkg/utils"
+)
+
+// node rewards files are archived as <logFilePath>/node_rewards/<epoch>/<cid>/<fileName>
+const nodeRewardsArchiveDir = "node_rewards"
+
+func (s *Service) nodeRewardsArchivePath(epoch uint64, cid, fileName string) string {
+	return filepath.Join(s.logFilePath, nodeRewardsArchiveDir, strconv.FormatUint(epoch, 10), cid, fileName)
+}
+
+func (s *Service) archiveNodeRewards(epoch uint64, cid, fileName string, content []byte) error {
+	path := s.nodeRewardsArchivePath(epoch, cid, fileName)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	return os.WriteFile(path, content, 0600)
+}
+
+// downloadNodeRewards returns the node rewards file of epoch, the content is taken from the local archive
+// or the decentralized storage and is only used if it matches cid
+func (s *Service) downloadNodeRewards(cid string, epoch uint64) ([]byte, error) {
+	fileName := utils.NodeRewardsFileNameAtEpoch(s.lsdTokenAddress.String(), s.chainID, epoch)
+	oldFileName := utils.NodeRewardsFileNameAtEpochOld(s.lsdTokenAddress.String(), epoch)
+
+	for _, name := range []string{fileName, oldFileName} {
+		content, err := os.ReadFile(s.nodeRewardsArchivePath(epoch, cid, name))
+		if err != nil {
+			continue
+		}
+		if err = destorage.VerifyCid(cid, name, content); err != nil {
+			s.log.WithFields(logrus.Fields{
+				"epoch": epoch,
+				"cid":   cid,
+			}).WithError(err).Warn("archived node rewards file is invalid, will download it")
+			continue
+		}
+		return content, nil
+	}
+
+	content, err := s.dds.DownloadFile(cid, fileName)
+	if err != nil {
+		if !errors.Is(err, destorage.ErrNotFound) {
+			return nil, err
+		}
+		// try old
+		fileName = oldFileName
+		if content, err = s.dds.DownloadFile(cid, fileName); err != nil {
+			return nil, err
+		}
+	}
+	if err = destorage.VerifyCid(cid, fileName, content); err != nil {
+		return nil, fmt.Errorf("node rewards file of epoch %d: %w", epoch, err)
+	}
+	if err = s.archiveNodeRewards(epoch, cid, fileName, content); err != nil {
+		s.log.WithError(err).Warn("archive node rewards file failed")
+	}
+	return content, nil
+}
@@ -0,0 +1,52 @@
+package service
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stafiprotocol/eth-lsd-relay/pkg/destorage"
+	"github.com/stafiprotocol/eth-lsd-relay/pkg/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDownloadNodeRewards(t *testing.T) {
+	dds := &memDeStorage{uploaded: make(map[string][]byte)}
+	s := newStateTestService(nil)
+	s.chainID = 943
+	s.logFilePath = t.TempDir()
+	s.dds = dds
+
+	var epoch uint64 = 450
+	fileName := utils.NodeRewardsFileNameAtEpoch(s.lsdTokenAddress.String(), s.chainID, epoch)
+	content := []byte(`{"Epoch":450,"List":[]}`)
+	cid, err := destorage.ComputeCid(fileName, content)
+	assert.Nil(t, err)
+
+	// a gateway serving other bytes is rejected
+	dds.uploaded[fileName] = []byte(`{"Epoch":450,"List":[{"address":"0x01"}]}`)
+	_, err = s.downloadNodeRewards(cid, epoch)
+	assert.ErrorContains(t, err, "cid mismatch")
+	_, err = os.Stat(s.nodeRewardsArchivePath(epoch, cid, fileName))
+	assert.True(t, os.IsNotExist(err))
+
+	dds.uploaded[fileName] = content
+	downloaded, err := s.downloadNodeRewards(cid, epoch)
+	assert.Nil(t, err)
+	assert.Equal(t, content, downloaded)
+
+	// served from the archive afterwards
+	delete(dds.uploaded, fileName)
+	downloaded, err = s.downloadNodeRewards(cid, epoch)
+	assert.Nil(t, err)
+	assert.Equal(t, content, downloaded)
+
+	// files of old relays are named without the chain id
+	oldFileName := utils.NodeRewardsFileNameAtEpochOld(s.lsdTokenAddress.String(), epoch+1)
+	oldContent := []byte(`{"Epoch":451,"List":[]}`)
+	oldCid, err := destorage.ComputeCid(oldFileName, oldContent)
+	assert.Nil(t, err)
+	dds.uploaded[oldFileName] = oldContent
+	downloaded, err = s.downloadNodeRewards(oldCid, epoch+1)
+	assert.Nil(t, err)
+	assert.Equal(t, oldContent, downloaded)
+}
@@ -143,43 +143,19 @@ func (s *Service) notifyValidatorExit() error {
 }
 
 func (s *Service) sendNotifyExitTx(withdrawCycle, startCycle uint64, selectVals []*big.Int) error {
-	err := s.connection.LockAndUpdateTxOpts()
-	if err != nil {
-		return fmt.Errorf("LockAndUpdateTxOpts err: %w", err)
-	}
-	defer s.connection.UnlockTxOpts()
-
 	encodeBts, err := s.networkWithdrawAbi.Pack("notifyValidatorExit", big.NewInt(int64(withdrawCycle)),
 		big.NewInt(int64(startCycle)), selectVals)
 	if err != nil {
 		return err
 	}
 
-	proposalId := utils.ProposalId(s.networkWithdrawAddress, encodeBts, big.NewInt(int64(withdrawCycle)))
-
-	// check voted
-	hasVoted, err := s.networkProposalContract.HasVoted(nil, proposalId, s.connection.Keypair().CommonAddress())
-	if err != nil {
-		return fmt.Errorf("networkProposalContract.HasVoted err: %s", err)
-	}
-	if hasVoted {
-		return nil
-	}
-
 	s.log.WithFields(logrus.Fields{
 		"startCycle":      startCycle,
 		"withdrawalCycle": withdrawCycle,
 		"selectVal":       selectVals,
 	}).Debug("will sendNotifyValidatorExitTx")
 
-	tx, err := s.networkProposalContract.ExecProposal(s.connection.TxOpts(), s.networkWithdrawAddress, encodeBts, big.NewInt(int64(withdrawCycle)))
-	if err != nil {
-		return err
-	}
-
-	s.log.Info("send NotifyValidatorExit tx hash: ", tx.Hash().String())
-
-	return s.waitProposalTxOk(tx.Hash(), proposalId)
+	return s.execProposal("notifyValidatorExit", newProposalCall(s.networkWithdrawAddress, encodeBts, big.NewInt(int64(withdrawCycle))))
 }
 
 func (s *Service) currentCycleAndStartTimestamp() (int64, int64, error) {
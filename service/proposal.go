@@ -0,0 +1,186 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/samber/lo"
+	"github.com/sirupsen/logrus"
+	"github.com/stafiprotocol/eth-lsd-relay/pkg/connection"
+	"github.com/stafiprotocol/eth-lsd-relay/pkg/metrics"
+	"github.com/stafiprotocol/eth-lsd-relay/pkg/utils"
+)
+
+// proposalCall is a call of contract `to` voted through the network proposal contract
+type proposalCall struct {
+	to       common.Address
+	callData []byte
+	factor   *big.Int
+	id       [32]byte
+}
+
+func newProposalCall(to common.Address, callData []byte, factor *big.Int) proposalCall {
+	return proposalCall{
+		to:       to,
+		callData: callData,
+		factor:   factor,
+		id:       utils.ProposalId(to, callData, factor),
+	}
+}
+
+// execProposal votes for call with execProposal, in dry run mode the call is only recorded
+func (s *Service) execProposal(proposal string, call proposalCall) error {
+	if s.dryRun {
+		return s.recordDryRunProposals(proposal, []proposalCall{call})
+	}
+
+	calls, err := s.unvotedProposalCalls(proposal, []proposalCall{call})
+	if err != nil {
+		return err
+	}
+	if len(calls) == 0 {
+		s.log.WithField("proposal", proposal).Info("already voted wait other voters")
+		return nil
+	}
+
+	input, err := s.networkProposalAbi.Pack("execProposal", call.to, call.callData, call.factor)
+	if err != nil {
+		return err
+	}
+	ptx, err := s.sendProposalTx(proposal, [][32]byte{call.id}, input, func(opts *bind.TransactOpts) (*types.Transaction, error) {
+		return s.networkProposalContract.ExecProposal(opts, call.to, call.callData, call.factor)
+	})
+	if err != nil || ptx == nil {
+		return err
+	}
+
+	s.log.WithFields(logrus.Fields{
+		"proposal": proposal,
+		"nonce":    ptx.Nonce,
+		"txHash":   ptx.Hash().String(),
+	}).Info("send proposal tx")
+	metrics.IncProposal(s.lsdTokenAddress.String(), proposal, metrics.ProposalSent)
+
+	return s.waitProposalTxOk(proposal, ptx, call.id)
+}
+
+// batchExecProposals votes for calls not voted yet with batchExecProposals, in dry run mode calls are only recorded
+func (s *Service) batchExecProposals(proposal string, calls []proposalCall) error {
+	if s.dryRun {
+		return s.recordDryRunProposals(proposal, calls)
+	}
+
+	calls, err := s.unvotedProposalCalls(proposal, calls)
+	if err != nil {
+		return err
+	}
+	if len(calls) == 0 {
+		return nil
+	}
+
+	tos := make([]common.Address, len(calls))
+	callDatas := make([][]byte, len(calls))
+	factors := make([]*big.Int, len(calls))
+	proposalIds := make([][32]byte, len(calls))
+	for i, call := range calls {
+		tos[i] = call.to
+		callDatas[i] = call.callData
+		factors[i] = call.factor
+		proposalIds[i] = call.id
+	}
+
+	input, err := s.networkProposalAbi.Pack("batchExecProposals", tos, callDatas, factors)
+	if err != nil {
+		return err
+	}
+	ptx, err := s.sendProposalTx(proposal, proposalIds, input, func(opts *bind.TransactOpts) (*types.Transaction, error) {
+		return s.networkProposalContract.BatchExecProposals(opts, tos, callDatas, factors)
+	})
+	if err != nil || ptx == nil {
+		return err
+	}
+
+	s.log.WithFields(logrus.Fields{
+		"proposal":  proposal,
+		"proposals": len(calls),
+		"nonce":     ptx.Nonce,
+		"txHash":    ptx.Hash().String(),
+	}).Info("send batch proposals tx")
+	for range calls {
+		metrics.IncProposal(s.lsdTokenAddress.String(), proposal, metrics.ProposalSent)
+	}
+
+	return s.waitProposalsTxOk(proposal, ptx, proposalIds)
+}
+
+func (s *Service) unvotedProposalCalls(proposal string, calls []proposalCall) ([]proposalCall, error) {
+	unvoted := make([]proposalCall, 0, len(calls))
+	for _, call := range calls {
+		hasVoted, err := s.networkProposalContract.HasVoted(nil, call.id, s.connection.Signer().Address())
+		if err != nil {
+			return nil, fmt.Errorf("networkProposalContract.HasVoted err: %s", err)
+		}
+		if hasVoted {
+			// handlers check a voted proposal again every round until other voters execute it, count it once
+			if exist, _ := s.countedVotedProposals.ContainsOrAdd(call.id, struct{}{}); !exist {
+				metrics.IncProposal(s.lsdTokenAddress.String(), proposal, metrics.ProposalAlreadyVoted)
+			}
+			continue
+		}
+		unvoted = append(unvoted, call)
+	}
+	return unvoted, nil
+}
+
+// sendProposalTx sends the tx voting for proposalIds through the tx manager, which keeps replacing it until mined.
+// The pending tx is returned if one voting for the same proposals was sent before, otherwise input is simulated
+// first and nil is returned if the proposals were executed by other voters meanwhile
+func (s *Service) sendProposalTx(proposal string, proposalIds [][32]byte, input []byte,
+	send func(opts *bind.TransactOpts) (*types.Transaction, error)) (*connection.PendingTx, error) {
+	ids := make([]common.Hash, len(proposalIds))
+	for i, id := range proposalIds {
+		ids[i] = id
+	}
+	key := crypto.Keccak256Hash(lo.Map(ids, func(id common.Hash, _ int) []byte { return id.Bytes() })...).String()
+
+	txManager := s.connection.TxManager()
+	// our pending vote is part of the pending block, simulating it again would revert
+	if ptx := txManager.PendingOf(key); ptx != nil {
+		return ptx, nil
+	}
+	skip, err := s.simulateProposalTx(proposal, proposalIds, input)
+	if err != nil || skip {
+		return nil, err
+	}
+
+	return txManager.Send(context.Background(), key, ids, s.connection.GasLimit(), send)
+}
+
+// proposalTxObsolete tells the tx manager a stuck proposal tx can be cancelled as other voters executed its proposals
+func (s *Service) proposalTxObsolete(ptx *connection.PendingTx) (bool, error) {
+	ids := make([][32]byte, len(ptx.ProposalIds))
+	for i, id := range ptx.ProposalIds {
+		ids[i] = id
+	}
+	return s.proposalsExecuted(ids)
+}
+
+// proposalsExecuted is false if proposalIds is empty
+func (s *Service) proposalsExecuted(proposalIds [][32]byte) (bool, error) {
+	for _, id := range proposalIds {
+		p, err := s.networkProposalContract.Proposals(nil, id)
+		if err != nil {
+			return false, err
+		}
+		// proposal is executed
+		if p.Status != 2 {
+			return false, nil
+		}
+	}
+	return len(proposalIds) > 0, nil
+}
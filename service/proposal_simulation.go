@@ -0,0 +1,105 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	ethereum "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/sirupsen/logrus"
+	"github.com/stafiprotocol/eth-lsd-relay/pkg/metrics"
+)
+
+// ProposalRevertError is returned instead of sending a proposal tx whose simulation reverts
+type ProposalRevertError struct {
+	Proposal string
+	Reason   string // decoded revert reason, e.g. AlreadyDealedEpoch
+	Data     []byte // raw revert data
+}
+
+func (e *ProposalRevertError) Error() string {
+	return fmt.Sprintf("proposal %s reverts in simulation: %s", e.Proposal, e.Reason)
+}
+
+// simulateProposalTx runs the proposal tx with eth_call on the pending block. A revert caused by other voters
+// executing the proposals first is reported as skip, any other revert as a ProposalRevertError
+func (s *Service) simulateProposalTx(proposal string, proposalIds [][32]byte, input []byte) (skip bool, err error) {
+	to := s.networkProposalAddress
+	_, err = s.connection.Eth1Client().CallContract(context.Background(), ethereum.CallMsg{
+		From: s.connection.Signer().Address(),
+		To:   &to,
+		Gas:  s.connection.GasLimit(),
+		Data: input,
+	}, big.NewInt(int64(rpc.PendingBlockNumber)))
+	if err == nil {
+		return false, nil
+	}
+	data, reverted := revertData(err)
+	if !reverted {
+		return false, fmt.Errorf("simulate proposal %s err: %w", proposal, err)
+	}
+
+	reason := s.decodeRevertReason(data)
+	metrics.IncProposal(s.lsdTokenAddress.String(), proposal, metrics.ProposalReverted)
+	metrics.IncProposalRevert(s.lsdTokenAddress.String(), proposal, reason)
+
+	executed, err := s.proposalsExecuted(proposalIds)
+	if err != nil {
+		return false, err
+	}
+	logger := s.log.WithFields(logrus.Fields{
+		"proposal": proposal,
+		"reason":   reason,
+	})
+	if executed {
+		logger.Info("proposal tx reverts in simulation as already executed by other voters, skip")
+		return true, nil
+	}
+	logger.Warn("proposal tx reverts in simulation, will not send it")
+	return false, &ProposalRevertError{Proposal: proposal, Reason: reason, Data: data}
+}
+
+// revertData returns the revert data carried by err, reverted is false if err is not a revert
+func revertData(err error) (data []byte, reverted bool) {
+	var dataErr rpc.DataError
+	if errors.As(err, &dataErr) {
+		if hexData, ok := dataErr.ErrorData().(string); ok {
+			if data, decodeErr := hexutil.Decode(hexData); decodeErr == nil {
+				return data, true
+			}
+		}
+	}
+	// some nodes drop the revert data
+	return nil, strings.Contains(err.Error(), "execution reverted")
+}
+
+// decodeRevertReason decodes Error(string), Panic(uint256) and the custom errors of the network contracts,
+// which share one error set, the selector is returned for unknown errors
+func (s *Service) decodeRevertReason(data []byte) string {
+	if len(data) < 4 {
+		return "execution reverted"
+	}
+	if reason, err := abi.UnpackRevert(data); err == nil {
+		return reason
+	}
+	for _, abiErr := range s.networkProposalAbi.Errors {
+		if !bytes.Equal(abiErr.ID[:4], data[:4]) {
+			continue
+		}
+		if len(abiErr.Inputs) == 0 {
+			return abiErr.Name
+		}
+		args, err := abiErr.Unpack(data)
+		if err != nil {
+			return abiErr.Name
+		}
+		return fmt.Sprintf("%s%v", abiErr.Name, args)
+	}
+	return hexutil.Encode(data[:4])
+}
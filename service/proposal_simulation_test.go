@@ -0,0 +1,63 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	network_proposal "github.com/stafiprotocol/eth-lsd-relay/bindings/NetworkProposal"
+	"github.com/stretchr/testify/assert"
+)
+
+// revertErr behaves like the json-rpc error of a reverted eth_call
+type revertErr struct {
+	data string
+}
+
+func (e *revertErr) Error() string          { return "execution reverted" }
+func (e *revertErr) ErrorCode() int         { return 3 }
+func (e *revertErr) ErrorData() interface{} { return e.data }
+
+func TestDecodeRevertReason(t *testing.T) {
+	s := newStateTestService(nil)
+	var err error
+	s.networkProposalAbi, err = abi.JSON(strings.NewReader(network_proposal.NetworkProposalABI))
+	assert.Nil(t, err)
+
+	// custom error of the network contracts
+	selector := crypto.Keccak256([]byte("AlreadyDealedEpoch()"))[:4]
+	data, reverted := revertData(fmt.Errorf("call err: %w", &revertErr{data: hexutil.Encode(selector)}))
+	assert.True(t, reverted)
+	assert.Equal(t, "AlreadyDealedEpoch", s.decodeRevertReason(data))
+
+	// Error(string)
+	stringTy, _ := abi.NewType("string", "", nil)
+	packed, err := abi.Arguments{{Type: stringTy}}.Pack("rate limit exceeded")
+	assert.Nil(t, err)
+	errorData := append(crypto.Keccak256([]byte("Error(string)"))[:4], packed...)
+	data, reverted = revertData(&revertErr{data: hexutil.Encode(errorData)})
+	assert.True(t, reverted)
+	assert.Equal(t, "rate limit exceeded", s.decodeRevertReason(data))
+
+	// Panic(uint256)
+	uintTy, _ := abi.NewType("uint256", "", nil)
+	packed, err = abi.Arguments{{Type: uintTy}}.Pack(big.NewInt(0x11))
+	assert.Nil(t, err)
+	panicData := append(crypto.Keccak256([]byte("Panic(uint256)"))[:4], packed...)
+	assert.Contains(t, s.decodeRevertReason(panicData), "overflow")
+
+	// unknown selector and missing data
+	assert.Equal(t, "0x01020304", s.decodeRevertReason([]byte{1, 2, 3, 4}))
+	data, reverted = revertData(errors.New("execution reverted"))
+	assert.True(t, reverted)
+	assert.Equal(t, "execution reverted", s.decodeRevertReason(data))
+
+	// not a revert
+	_, reverted = revertData(errors.New("connection refused"))
+	assert.False(t, reverted)
+}
@@ -0,0 +1,90 @@
+package service
+
+import (
+	"context"
+	"math/big"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/sirupsen/logrus"
+	"github.com/stafiprotocol/eth-lsd-relay/pkg/metrics"
+	"github.com/stafiprotocol/eth-lsd-relay/pkg/notify"
+)
+
+const relayerBalanceCheckInterval = time.Minute
+
+// relayer balance levels, exposed by the relayer_balance_level metric
+const (
+	relayerBalanceOk = iota
+	relayerBalanceWarning
+	relayerBalanceCritical
+)
+
+func (m *ServiceManager) relayerBalanceService() {
+	for {
+		select {
+		case <-m.stop:
+			return
+		default:
+			if err := m.checkRelayerBalance(); err != nil {
+				logrus.Warnf("check relayer balance err: %s", err.Error())
+			}
+		}
+		time.Sleep(relayerBalanceCheckInterval)
+	}
+}
+
+func (m *ServiceManager) checkRelayerBalance() error {
+	account := m.connection.Signer().Address()
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	balance, err := m.connection.Eth1Client().BalanceAt(ctx, account, nil)
+	if err != nil {
+		return err
+	}
+
+	level := relayerBalanceLevel(balance, m.relayerBalanceWarning, m.relayerBalanceCritical)
+	metrics.SetRelayerBalance(account.String(), balance)
+	metrics.SetRelayerBalanceLevel(account.String(), level)
+	if level == relayerBalanceOk {
+		return nil
+	}
+
+	threshold, severity := m.relayerBalanceWarning, notify.SeverityWarning
+	if level == relayerBalanceCritical {
+		threshold, severity = m.relayerBalanceCritical, notify.SeverityCritical
+	}
+	fields := map[string]string{
+		"account":   account.String(),
+		"balance":   decimal.NewFromBigInt(balance, -18).String(),
+		"threshold": decimal.NewFromBigInt(threshold, -18).String(),
+	}
+	log := logrus.WithFields(logrus.Fields{
+		"account":   fields["account"],
+		"balance":   fields["balance"],
+		"threshold": fields["threshold"],
+	})
+	if level == relayerBalanceCritical {
+		log.Error("relayer balance is critically low, proposal txs may not be sent")
+	} else {
+		log.Warn("relayer balance is low")
+	}
+	notify.Notify(notify.Alert{
+		Key:      "relayer-balance/" + string(severity),
+		Severity: severity,
+		Title:    "relayer balance is low",
+		Fields:   fields,
+	})
+	return nil
+}
+
+func relayerBalanceLevel(balance, warning, critical *big.Int) int {
+	switch {
+	case balance.Cmp(critical) < 0:
+		return relayerBalanceCritical
+	case balance.Cmp(warning) < 0:
+		return relayerBalanceWarning
+	default:
+		return relayerBalanceOk
+	}
+}
@@ -0,0 +1,17 @@
+package service
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRelayerBalanceLevel(t *testing.T) {
+	warning, critical := big.NewInt(1e18), big.NewInt(2e17)
+
+	assert.Equal(t, relayerBalanceOk, relayerBalanceLevel(big.NewInt(1e18), warning, critical))
+	assert.Equal(t, relayerBalanceWarning, relayerBalanceLevel(big.NewInt(5e17), warning, critical))
+	assert.Equal(t, relayerBalanceWarning, relayerBalanceLevel(big.NewInt(2e17), warning, critical))
+	assert.Equal(t, relayerBalanceCritical, relayerBalanceLevel(big.NewInt(0), warning, critical))
+}
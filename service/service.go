@@ -1,7 +1,6 @@
 package service
 
 import (
-	"bytes"
 	"context"
 	"errors"
 	"fmt"
@@ -11,8 +10,10 @@ import (
 	"reflect"
 	"runtime"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/avast/retry-go/v4"
@@ -20,8 +21,6 @@ import (
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
 	lru "github.com/hashicorp/golang-lru/v2"
-	"github.com/prysmaticlabs/prysm/v4/beacon-chain/core/signing"
-	"github.com/prysmaticlabs/prysm/v4/config/params"
 	"github.com/shopspring/decimal"
 	"github.com/sirupsen/logrus"
 	deposit_contract "github.com/stafiprotocol/eth-lsd-relay/bindings/DepositContract"
@@ -37,8 +36,9 @@ import (
 	"github.com/stafiprotocol/eth-lsd-relay/pkg/connection"
 	"github.com/stafiprotocol/eth-lsd-relay/pkg/connection/beacon"
 	"github.com/stafiprotocol/eth-lsd-relay/pkg/destorage"
-	"github.com/stafiprotocol/eth-lsd-relay/pkg/destorage/pinata"
+	"github.com/stafiprotocol/eth-lsd-relay/pkg/destorage/backend"
 	"github.com/stafiprotocol/eth-lsd-relay/pkg/local_store"
+	"github.com/stafiprotocol/eth-lsd-relay/pkg/notify"
 	"github.com/stafiprotocol/eth-lsd-relay/pkg/utils"
 )
 
@@ -57,6 +57,7 @@ type Service struct {
 	batchRequestBlocksNumber      uint64
 	batchQueryBalanceBlockNumbers uint64
 	eventFilterMaxSpanBlocks      uint64
+	eventConfirmationBlocks       uint64
 	maxEjectedValPerCycle         int
 
 	connection          *connection.CachedConnection
@@ -72,10 +73,19 @@ type Service struct {
 	networkWithdrawAddress   common.Address
 	networkBalancesAddress   common.Address
 	nodeDepositAddress       common.Address
+	networkProposalAddress   common.Address
 
 	networkWithdrawAbi abi.ABI
 	networkBalancesAbi abi.ABI
 	nodeDepositAbi     abi.ABI
+	networkProposalAbi abi.ABI
+
+	dryRun       bool // compute proposals but never send them
+	dryRunReport *dryRunReport
+
+	logFilePath                       string
+	uploadBalancesReport              bool
+	latestUploadedBalancesReportEpoch uint64
 
 	lsdNetworkFactoryContract *lsd_network_factory.LsdNetworkFactory
 	nodeDepositContract       *node_deposit.CustomNodeDeposit
@@ -95,11 +105,16 @@ type Service struct {
 	waitFirstNodeStakeEvent bool
 	localSyncedBlockHeight  uint64
 	localStore              *local_store.LocalStore
+	stateStore              local_store.StateStore
+	stateDigests            map[string][32]byte // state section -> digest of the latest checkpoint
 
 	latestBlockOfSyncEvents      uint64
+	safeBlockOfEvents            uint64 // finalized execution block, cached by finalizedSlotOfSafeBlock
+	finalizedSlotOfSafeBlock     uint64
 	latestBlockOfUpdateValidator uint64
 	latestEpochOfUpdateValidator uint64
 	startAtBlock                 uint64
+	networkCreateBlock           uint64
 
 	cycleSeconds                      uint64
 	latestDistributeWithdrawalsHeight uint64
@@ -110,7 +125,7 @@ type Service struct {
 
 	validators             map[string]*Validator // pubkey(hex.encodeToString) -> validator
 	validatorsByIndex      map[uint64]*Validator // validator index -> validator
-	validatorsByIndexMutex sync.RWMutex
+	validatorsByIndexMutex sync.RWMutex          // guards validatorsByIndex and the validator fields updated from beacon
 
 	nodes map[common.Address]*Node // nodeAddress -> node
 
@@ -119,10 +134,16 @@ type Service struct {
 	minExecutionBlockHeight uint64
 
 	cacheEpochToBlockID      *lru.Cache[uint64, uint64]
+	nodeRewardsCache         *lru.Cache[uint64, *verifiedNodeRewards] // epoch => rewards list verified on chain, for proofs
+	merkleRootsCache         *lru.Cache[uint64, *merkleRootOfEpoch]   // epoch => root set on chain, nil if none was set
+	countedVotedProposals    *lru.Cache[[32]byte, struct{}]           // proposal ids already counted as voted by the metrics
 	cacheEpochToBlockIDMutex sync.RWMutex
 
 	exitElections   map[uint64]*ExitElection // cycle -> exitElection
 	feePoolBalances sync.Map                 // blockNumber -> balance
+
+	handlerStatuses sync.Map // handler name -> HandlerStatus
+	status          atomic.Pointer[ServiceStatus]
 }
 
 type Node struct {
@@ -193,6 +214,7 @@ func NewService(
 	manager *ServiceManager,
 	conn *connection.CachedConnection,
 	localStore *local_store.LocalStore,
+	stateStore local_store.StateStore,
 ) (*Service, error) {
 	if !common.IsHexAddress(cfg.Contracts.LsdTokenAddress) {
 		return nil, fmt.Errorf("LsdTokenAddress contract address fmt err")
@@ -227,19 +249,27 @@ func NewService(
 		"lsdToken": cfg.Contracts.LsdTokenAddress,
 	})
 
-	dds, err := pinata.NewClient(
-		cfg.Pinata.Endpoint,
-		cfg.Pinata.Apikey,
-	)
+	dds, err := backend.New(cfg, log)
 	if err != nil {
-		return nil, fmt.Errorf("fail to new pinata client: %w", err)
+		return nil, err
 	}
-	dds.StartUnpinFiles(utils.Day * time.Duration(cfg.Pinata.PinDays))
 
 	cacheEpochToBlockID, err := lru.New[uint64, uint64](1024 * 1000)
 	if err != nil {
 		return nil, err
 	}
+	nodeRewardsCache, err := lru.New[uint64, *verifiedNodeRewards](8)
+	if err != nil {
+		return nil, err
+	}
+	merkleRootsCache, err := lru.New[uint64, *merkleRootOfEpoch](1024)
+	if err != nil {
+		return nil, err
+	}
+	countedVotedProposals, err := lru.New[[32]byte, struct{}](1024)
+	if err != nil {
+		return nil, err
+	}
 	transferFeeAddresses := []string{}
 	for _, address := range cfg.TransferFeeAddresses {
 		if !common.IsHexAddress(address) {
@@ -260,9 +290,15 @@ func NewService(
 		batchRequestBlocksNumber:      cfg.BatchRequestBlocksNumber,
 		batchQueryBalanceBlockNumbers: cfg.BatchQueryBalanceBlockNumbers,
 		eventFilterMaxSpanBlocks:      cfg.EventFilterMaxSpanBlocks,
+		eventConfirmationBlocks:       cfg.EventConfirmationBlocks,
 		maxEjectedValPerCycle:         cfg.MaxEjectedValPerCycle,
 		localSyncedBlockHeight:        localSyncedBlockHeight,
 		localStore:                    localStore,
+		stateStore:                    stateStore,
+		stateDigests:                  make(map[string][32]byte),
+		dryRun:                        cfg.DryRun,
+		logFilePath:                   cfg.LogFilePath,
+		uploadBalancesReport:          cfg.UploadBalancesReport,
 
 		govDeposits:         make(map[string][][]byte),
 		validators:          make(map[string]*Validator),
@@ -272,12 +308,44 @@ func NewService(
 		exitElections:       make(map[uint64]*ExitElection),
 		feePoolBalances:     sync.Map{},
 		cacheEpochToBlockID: cacheEpochToBlockID,
+		nodeRewardsCache:    nodeRewardsCache,
+
+		merkleRootsCache:      merkleRootsCache,
+		countedVotedProposals: countedVotedProposals,
+	}
+
+	if s.dryRun {
+		s.dryRunReport = newDryRunReport(fmt.Sprintf("%s/dry_run_%s.jsonl", cfg.LogFilePath, s.lsdTokenAddress.String()))
+		log.WithField("report", s.dryRunReport.path).Warn("dry run mode, proposals will be recorded instead of sent")
 	}
 
 	return s, nil
 }
 
 func (s *Service) Start() error {
+	if err := s.initialize(); err != nil {
+		return err
+	}
+	if err := s.refreshStatus(); err != nil {
+		return err
+	}
+	if txManager := s.connection.TxManager(); txManager != nil {
+		txManager.SetObsoleteChecker(s.networkProposalAddress, s.proposalTxObsolete)
+	}
+
+	// start services
+	s.log.Info("start services...")
+	if s.waitFirstNodeStakeEvent {
+		s.startSeekFirstNodeStakeEvent()
+	} else {
+		s.startHandlers()
+	}
+
+	return nil
+}
+
+// initialize checks the network, inits contracts and restores the persisted state, no handler is started
+func (s *Service) initialize() error {
 	chainId, err := s.connection.ChainID()
 	if err != nil {
 		return err
@@ -288,94 +356,13 @@ func (s *Service) Start() error {
 		return err
 	}
 
-	switch chainId.Uint64() {
-	case 1: //mainnet
-		if !bytes.Equal(s.eth2Config.GenesisForkVersion, params.MainnetConfig().GenesisForkVersion) {
-			return fmt.Errorf("endpoint network not match")
-		}
-
-		domain, err := signing.ComputeDomain(
-			params.MainnetConfig().DomainDeposit,
-			params.MainnetConfig().GenesisForkVersion,
-			params.MainnetConfig().ZeroHash[:],
-		)
-		if err != nil {
-			return err
-		}
-		s.domain = domain
-
-	case 11155111: // sepolia
-		if !bytes.Equal(s.eth2Config.GenesisForkVersion, params.SepoliaConfig().GenesisForkVersion) {
-			return fmt.Errorf("endpoint network not match")
-		}
-
-		domain, err := signing.ComputeDomain(
-			params.SepoliaConfig().DomainDeposit,
-			params.SepoliaConfig().GenesisForkVersion,
-			params.SepoliaConfig().ZeroHash[:],
-		)
-		if err != nil {
-			return err
-		}
-		s.domain = domain
-	case 17000: // holesky
-		chainCfg := params.HoleskyConfig()
-		if !bytes.Equal(s.eth2Config.GenesisForkVersion, chainCfg.GenesisForkVersion) {
-			return fmt.Errorf("endpoint network not match")
-		}
-		domain, err := signing.ComputeDomain(
-			chainCfg.DomainDeposit,
-			chainCfg.GenesisForkVersion,
-			chainCfg.ZeroHash[:],
-		)
-		if err != nil {
-			return err
-		}
-		s.domain = domain
-	case 5: // goerli
-		if !bytes.Equal(s.eth2Config.GenesisForkVersion, params.PraterConfig().GenesisForkVersion) {
-			return fmt.Errorf("endpoint network not match")
-		}
-		domain, err := signing.ComputeDomain(
-			params.PraterConfig().DomainDeposit,
-			params.PraterConfig().GenesisForkVersion,
-			params.PraterConfig().ZeroHash[:],
-		)
-		if err != nil {
-			return err
-		}
-		s.domain = domain
-	case 369: // pulse chain mainnet
-		chainConfig := utils.PulseChainConfig()
-		if !bytes.Equal(s.eth2Config.GenesisForkVersion, chainConfig.GenesisForkVersion) {
-			return fmt.Errorf("endpoint network not match")
-		}
-		domain, err := signing.ComputeDomain(
-			chainConfig.DomainDeposit,
-			chainConfig.GenesisForkVersion,
-			chainConfig.ZeroHash[:],
-		)
-		if err != nil {
-			return err
-		}
-		s.domain = domain
-	case 943: // pulse chain testnet
-		chainConfig := utils.PulseChainTestnetV4Config()
-		if !bytes.Equal(s.eth2Config.GenesisForkVersion, chainConfig.GenesisForkVersion) {
-			return fmt.Errorf("endpoint network not match")
-		}
-		domain, err := signing.ComputeDomain(
-			chainConfig.DomainDeposit,
-			chainConfig.GenesisForkVersion,
-			chainConfig.ZeroHash[:],
-		)
-		if err != nil {
-			return err
-		}
-		s.domain = domain
-	default:
-		return fmt.Errorf("unsupported chainId: %d", chainId.Int64())
+	if chainId.Uint64() != s.manager.chainProfile.ChainId {
+		return fmt.Errorf("chainId %d not match chain profile %s", chainId.Uint64(), s.manager.chainProfile.Name)
+	}
+	if err = s.manager.chainProfile.Check(s.eth2Config); err != nil {
+		return err
 	}
+	s.domain, err = s.manager.chainProfile.DepositDomain()
 	if err != nil {
 		return err
 	}
@@ -430,6 +417,9 @@ func (s *Service) Start() error {
 	if err = s.initLatestBlockOfSyncBlock(); err != nil {
 		return err
 	}
+	if _, err = s.loadState(); err != nil {
+		return err
+	}
 
 	block, err := s.connection.Eth1Client().BlockByNumber(context.Background(), big.NewInt(int64(s.latestBlockOfSyncBlock)))
 	if err != nil {
@@ -460,13 +450,9 @@ func (s *Service) Start() error {
 	if err != nil {
 		return err
 	}
-
-	// start services
-	s.log.Info("start services...")
-	if s.waitFirstNodeStakeEvent {
-		s.startSeekFirstNodeStakeEvent()
-	} else {
-		s.startHandlers()
+	s.networkProposalAbi, err = abi.JSON(strings.NewReader(network_proposal.NetworkProposalABI))
+	if err != nil {
+		return err
 	}
 
 	return nil
@@ -495,6 +481,7 @@ func (s *Service) startSeekFirstNodeStakeEvent() {
 				if found {
 					// first node stake event has been found
 					log.Info("found first node stake event")
+					s.startHandlers()
 					return
 				}
 				if err != nil {
@@ -547,8 +534,6 @@ func (s *Service) seekFirstNodeStakeEvent() (bool, error) {
 				return false, err
 			}
 			s.latestSlotOfSyncBlock = utils.SlotAtTimestamp(s.eth2Config, block.Time())
-
-			s.startHandlers()
 			return true, nil
 		}
 	}
@@ -560,7 +545,7 @@ func (s *Service) seekFirstNodeStakeEvent() (bool, error) {
 		return false, err
 	}
 	s.latestBlockOfSyncBlock = end
-	return false, nil
+	return false, s.refreshStatus()
 }
 
 func (s *Service) startHandlers() {
@@ -599,9 +584,13 @@ func (s *Service) startHandlers() {
 			"latestBlockOfSyncBlock": s.latestBlockOfSyncBlock,
 		}).Info("start voting handlers")
 
+		slotHandlers := []func() error{s.syncEvents, s.updateValidatorsFromNetwork, s.checkpointState, s.syncBlocks, s.voteWithdrawCredentials, s.pruneBlocks, s.refreshStatus}
+		if s.dryRun {
+			slotHandlers = append(slotHandlers, s.checkDryRunProposals)
+		}
 		s.startGroupHandlers(func() time.Duration {
 			return time.Duration(s.eth2Config.SecondsPerSlot) * time.Second
-		}, s.syncEvents, s.updateValidatorsFromNetwork, s.syncBlocks, s.voteWithdrawCredentials, s.pruneBlocks)
+		}, slotHandlers...)
 		s.startGroupHandlers(func() time.Duration {
 			slotDur := time.Duration(s.eth2Config.SecondsPerSlot) * time.Second
 			epochDur := time.Duration(s.eth2Config.SlotsPerEpoch) * slotDur
@@ -689,8 +678,10 @@ func (s *Service) initContract() error {
 	s.networkWithdrawAddress = networkContracts.NetworkWithdraw
 	s.networkBalancesAddress = networkContracts.NetworkBalances
 	s.nodeDepositAddress = networkContracts.NodeDeposit
+	s.networkProposalAddress = networkContracts.NetworkProposal
 
 	s.startAtBlock = networkContracts.Block.Uint64()
+	s.networkCreateBlock = s.startAtBlock
 
 	s.feePoolAddress = networkContracts.FeePool
 
@@ -757,6 +748,9 @@ func (s *Service) initLatestBlockOfSyncBlock() error {
 	return nil
 }
 
+// a handler failing this many times in a row is alerted
+const handlerRetryAlertThreshold = 50
+
 func (s *Service) startGroupHandlers(sleepIntervalFn func() time.Duration, handlerFns ...func() error) {
 	if len(handlerFns) == 0 {
 		panic("handlers can not be empty")
@@ -779,7 +773,9 @@ func (s *Service) startGroupHandlers(sleepIntervalFn func() time.Duration, handl
 	}
 
 	log := s.log
+	finalized, unsubscribe := s.connection.SubscribeFinalizedCheckpoint()
 	utils.SafeGo(func() {
+		defer unsubscribe()
 		retry := 0
 		var retryLog *logrus.Entry
 
@@ -789,7 +785,7 @@ func (s *Service) startGroupHandlers(sleepIntervalFn func() time.Duration, handl
 				if retryLog != nil {
 					retryLog.Errorf("shutting down for too many attempts failed, check your RPC status first")
 				}
-				utils.ShutdownRequestChannel <- struct{}{}
+				requestShutdown(s.lsdTokenAddress.String(), "too many attempts failed, check your RPC status first")
 				return
 			}
 
@@ -804,36 +800,70 @@ func (s *Service) startGroupHandlers(sleepIntervalFn func() time.Duration, handl
 					log := log.WithField("handler", funcName)
 					log.Debugf("handler begin")
 
+					startAt := time.Now()
 					err := handler.method()
 					if err != nil {
 						if errors.Is(err, ErrHandlerExit) {
+							s.recordHandlerRun(funcName, startAt, err, retry)
 							log.Error(err.Error())
-							utils.ShutdownRequestChannel <- struct{}{}
+							requestShutdown(s.lsdTokenAddress.String(), fmt.Sprintf("handler %s: %s", funcName, err.Error()))
 							return
 						}
 
 						retryIn := sleepIntervalFn()
 						var gasErr *connection.GasPriceError
 						if errors.As(err, &gasErr) {
+							s.recordHandlerRun(funcName, startAt, err, retry)
 							log.WithField("retry_in", retryIn).Error(gasErr.Error())
+							notify.Notify(notify.Alert{
+								Key:      "gas-price",
+								Severity: notify.SeverityWarning,
+								Title:    "gas price exceeds maxGasPrice, proposals are delayed",
+								Message:  gasErr.Error(),
+								Fields:   map[string]string{"lsdToken": s.lsdTokenAddress.String(), "handler": funcName},
+							})
+							time.Sleep(retryIn)
+							continue Out
+						}
+						// waits for a top up without counting as a failed attempt
+						var balanceErr *connection.InsufficientBalanceError
+						if errors.As(err, &balanceErr) {
+							s.recordHandlerRun(funcName, startAt, err, retry)
+							log.WithField("retry_in", retryIn).Error(balanceErr.Error())
+							notify.Notify(notify.Alert{
+								Key:      "insufficient-balance",
+								Severity: notify.SeverityCritical,
+								Title:    "relayer balance can not pay for proposal txs",
+								Message:  balanceErr.Error(),
+								Fields:   map[string]string{"lsdToken": s.lsdTokenAddress.String(), "handler": funcName},
+							})
 							time.Sleep(retryIn)
 							continue Out
 						}
 
 						retry++
+						s.recordHandlerRun(funcName, startAt, err, retry)
 						retryLog = log.WithFields(logrus.Fields{
 							"retry_times": retry,
 							"err":         err,
 						})
 						log := retryLog.WithField("retry_in", retryIn)
-						if retry < 50 {
+						if retry < handlerRetryAlertThreshold {
 							log.Debugf("failed waiting retry")
 						} else {
 							log.Warnf("failed waiting retry")
+							notify.Notify(notify.Alert{
+								Key:      fmt.Sprintf("handler-retry/%s/%s", s.lsdTokenAddress.String(), funcName),
+								Severity: notify.SeverityWarning,
+								Title:    fmt.Sprintf("handler %s keeps failing", funcName),
+								Message:  err.Error(),
+								Fields:   map[string]string{"lsdToken": s.lsdTokenAddress.String(), "retries": strconv.Itoa(retry)},
+							})
 						}
 						time.Sleep(retryIn)
 						continue Out
 					}
+					s.recordHandlerRun(funcName, startAt, nil, 0)
 					log.Debugf("handler end")
 				}
 
@@ -841,7 +871,14 @@ func (s *Service) startGroupHandlers(sleepIntervalFn func() time.Duration, handl
 				retryLog = nil
 			}
 
-			time.Sleep(sleepIntervalFn())
+			// a new finalized checkpoint starts the next round early, the interval is the fallback without the event stream
+			timer := time.NewTimer(sleepIntervalFn())
+			select {
+			case <-s.stop:
+			case <-finalized:
+			case <-timer.C:
+			}
+			timer.Stop()
 		}
 	})
 }
@@ -12,7 +12,6 @@ import (
 	"github.com/samber/lo"
 	"github.com/shopspring/decimal"
 	"github.com/sirupsen/logrus"
-	"github.com/stafiprotocol/chainbridge/utils/crypto/secp256k1"
 	lsd_network_factory "github.com/stafiprotocol/eth-lsd-relay/bindings/LsdNetworkFactory"
 	"github.com/stafiprotocol/eth-lsd-relay/pkg/config"
 	"github.com/stafiprotocol/eth-lsd-relay/pkg/connection"
@@ -26,13 +25,21 @@ type ServiceManager struct {
 	connection *connection.CachedConnection
 	srvs       *xsync.MapOf[string, *Service]
 	localStore *local_store.LocalStore
+	stateStore local_store.StateStore
+	apiServer  *ApiServer
+
+	relayerBalanceWarning  *big.Int
+	relayerBalanceCritical *big.Int
+	chainProfile           *utils.ChainProfile
 
 	cachedBeaconBlock                  *xsync.MapOf[uint64, *CachedBeaconBlock] // beacon block id: (uint64) => beaconblock: (*CachedBeaconBlock)
 	cachedBeaconBlockByExecBlockHeight *xsync.MapOf[uint64, *CachedBeaconBlock] // execution block height: (uint64) => beaconblock: (*CachedBeaconBlock)
 	beaconBlockMutex                   *utils.KeyedMutex[uint64]
+	beaconBlockStore                   local_store.BeaconBlockStore // durable copy of cachedBeaconBlock
 }
 
-func NewServiceManager(cfg *config.Config, keyPair *secp256k1.Keypair) (*ServiceManager, error) {
+// NewServiceManager creates a read-only manager if signer is nil
+func NewServiceManager(cfg *config.Config, signer connection.Signer) (*ServiceManager, error) {
 	if !common.IsHexAddress(cfg.Contracts.LsdFactoryAddress) {
 		return nil, fmt.Errorf("LsdFactoryAddress contract address fmt err")
 	}
@@ -55,11 +62,45 @@ func NewServiceManager(cfg *config.Config, keyPair *secp256k1.Keypair) (*Service
 	}
 	gasPriceMultiplier := new(big.Float).SetFloat64(cfg.GasPriceMultiplier)
 
-	conn, err := connection.NewConnection(cfg.Endpoints, keyPair,
+	relayerBalanceWarningDeci, err := decimal.NewFromString(cfg.RelayerBalance.Warning)
+	if err != nil {
+		return nil, fmt.Errorf("parse config relayerBalance warning error: %w", err)
+	}
+	relayerBalanceCriticalDeci, err := decimal.NewFromString(cfg.RelayerBalance.Critical)
+	if err != nil {
+		return nil, fmt.Errorf("parse config relayerBalance critical error: %w", err)
+	}
+	if relayerBalanceCriticalDeci.GreaterThan(relayerBalanceWarningDeci) {
+		return nil, fmt.Errorf("relayerBalance critical can not be greater than warning")
+	}
+
+	conn, err := connection.NewConnection(cfg.Endpoints, signer,
 		gasLimitDeci.BigInt(), maxGasPriceDeci.BigInt(), gasPriceMultiplier)
 	if err != nil {
 		return nil, err
 	}
+	if err = conn.ConfigureEndpointPools(connection.EndpointPoolConfig{
+		FailureThreshold: cfg.EndpointPool.FailureThreshold,
+		OpenTimeout:      time.Duration(cfg.EndpointPool.OpenSeconds) * time.Second,
+	}); err != nil {
+		return nil, fmt.Errorf("ConfigureEndpointPools err: %w", err)
+	}
+	if err = conn.EnableQuorum(connection.Quorum{
+		Size:      cfg.Quorum.Size,
+		Threshold: cfg.Quorum.Threshold,
+	}); err != nil {
+		return nil, fmt.Errorf("EnableQuorum err: %w", err)
+	}
+	if signer != nil {
+		err = conn.EnableTxManager(connection.TxManagerConfig{
+			BumpAfter:   time.Duration(cfg.TxManager.BumpAfterSeconds) * time.Second,
+			BumpPercent: cfg.TxManager.BumpPercent,
+			StoreFile:   cfg.PendingTxsFilePath,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("EnableTxManager err: %w", err)
+		}
+	}
 	cachedConn, err := connection.NewCachedConnection(conn)
 	if err != nil {
 		return nil, err
@@ -67,12 +108,41 @@ func NewServiceManager(cfg *config.Config, keyPair *secp256k1.Keypair) (*Service
 	if err = cachedConn.Start(); err != nil {
 		return nil, err
 	}
+	chainProfiles, err := chainProfilesOf(cfg.ChainProfiles)
+	if err != nil {
+		return nil, err
+	}
+	chainId, err := cachedConn.ChainID()
+	if err != nil {
+		return nil, err
+	}
+	chainProfile, err := chainProfiles.Get(chainId.Uint64())
+	if err != nil {
+		return nil, err
+	}
+	if err = initConstants(cfg, chainProfile); err != nil {
+		return nil, err
+	}
+	logrus.WithFields(logrus.Fields{
+		"chainId":                    chainProfile.ChainId,
+		"profile":                    chainProfile.Name,
+		"effectiveBalance":           utils.StandardEffectiveBalanceDeci.Div(utils.EtherDeci).String(),
+		"maxPartialWithdrawalAmount": utils.MaxPartialWithdrawalAmountDeci.Div(utils.EtherDeci).String(),
+	}).Info("chain profile")
 	localStore, err := local_store.NewLocalStore(cfg.BlockstoreFilePath)
 	if err != nil {
 		return nil, err
 	}
+	stateStore, err := local_store.NewLevelDBStateStore(cfg.StateStorePath)
+	if err != nil {
+		return nil, err
+	}
+	beaconBlockStore, err := local_store.NewLevelDBBeaconBlockStore(cfg.BeaconBlockStorePath)
+	if err != nil {
+		return nil, err
+	}
 
-	return &ServiceManager{
+	m := &ServiceManager{
 		stop:                               make(chan struct{}),
 		cfg:                                cfg,
 		connection:                         cachedConn,
@@ -81,11 +151,27 @@ func NewServiceManager(cfg *config.Config, keyPair *secp256k1.Keypair) (*Service
 		cachedBeaconBlockByExecBlockHeight: xsync.NewMapOf[uint64, *CachedBeaconBlock](),
 		beaconBlockMutex:                   &utils.KeyedMutex[uint64]{},
 		localStore:                         localStore,
-	}, nil
+		stateStore:                         stateStore,
+		beaconBlockStore:                   beaconBlockStore,
+		relayerBalanceWarning:              relayerBalanceWarningDeci.Mul(utils.EtherDeci).BigInt(),
+		relayerBalanceCritical:             relayerBalanceCriticalDeci.Mul(utils.EtherDeci).BigInt(),
+		chainProfile:                       chainProfile,
+	}
+	if cfg.Api.ListenAddr != "" {
+		m.apiServer = NewApiServer(cfg.Api.ListenAddr, cfg.Api.MerkleProof, m)
+	}
+
+	return m, nil
 }
 
 func (m *ServiceManager) Start() error {
 	utils.SafeGoWithRestart(m.pruneCachedBeaconBlocksService)
+	if m.apiServer != nil {
+		m.apiServer.Start()
+	}
+	if m.connection.Signer() != nil {
+		utils.SafeGoWithRestart(m.relayerBalanceService)
+	}
 
 	if !m.cfg.RunForEntrustedLsdNetwork {
 		if _, err := m.newAndStartServiceFor(m.cfg.Contracts.LsdTokenAddress); err != nil {
@@ -107,11 +193,20 @@ func (m *ServiceManager) Start() error {
 
 func (m *ServiceManager) Stop() {
 	close(m.stop)
+	if m.apiServer != nil {
+		m.apiServer.Stop()
+	}
 	m.srvs.Range(func(key string, value *Service) bool {
 		value.Stop()
 		return true
 	})
 	m.connection.Stop()
+	if err := m.stateStore.Close(); err != nil {
+		logrus.Warnf("close state store err: %s", err.Error())
+	}
+	if err := m.beaconBlockStore.Close(); err != nil {
+		logrus.Warnf("close beacon block store err: %s", err.Error())
+	}
 }
 
 func (m *ServiceManager) startSyncService() {
@@ -122,7 +217,7 @@ func (m *ServiceManager) startSyncService() {
 Out:
 	for {
 		if retry > utils.RetryLimit {
-			utils.ShutdownRequestChannel <- struct{}{}
+			requestShutdown(m.cfg.Contracts.LsdFactoryAddress, "too many attempts to sync entrusted lsd tokens failed")
 			return
 		}
 
@@ -191,7 +286,7 @@ func (m *ServiceManager) newAndStartServiceFor(lsdToken string) (*Service, error
 	log.Debug("new service instance")
 	srvConfig := *m.cfg
 	srvConfig.Contracts.LsdTokenAddress = lsdToken
-	srv, err := NewService(&srvConfig, m, m.connection, m.localStore)
+	srv, err := NewService(&srvConfig, m, m.connection, m.localStore, m.stateStore)
 	if err != nil {
 		return nil, fmt.Errorf("new service for lsd token %s err %s", lsdToken, err.Error())
 	}
@@ -203,6 +298,19 @@ func (m *ServiceManager) newAndStartServiceFor(lsdToken string) (*Service, error
 	return srv, nil
 }
 
+// serviceOf returns the service of the lsd token, nil if the token is not served
+func (m *ServiceManager) serviceOf(lsdToken common.Address) *Service {
+	var found *Service
+	m.srvs.Range(func(_ string, srv *Service) bool {
+		if srv.LsdTokenAddress() == lsdToken {
+			found = srv
+			return false
+		}
+		return true
+	})
+	return found
+}
+
 var notExistBeaconBlock = &CachedBeaconBlock{}
 
 func (m *ServiceManager) CacheBeaconBlock(blockId uint64) (*CachedBeaconBlock, bool, error) {
@@ -217,11 +325,32 @@ func (m *ServiceManager) CacheBeaconBlock(blockId uint64) (*CachedBeaconBlock, b
 		return block, true, nil
 	}
 
+	data, exist, err := m.beaconBlockStore.Get(blockId)
+	if err != nil {
+		return nil, false, fmt.Errorf("read beacon block %d from store err: %w", blockId, err)
+	}
+	if exist {
+		if len(data) == 0 {
+			m.cachedBeaconBlock.Store(blockId, notExistBeaconBlock)
+			return nil, false, nil
+		}
+		cachedBlock, err := decodeCachedBeaconBlock(blockId, data)
+		if err == nil {
+			m.cachedBeaconBlockByExecBlockHeight.Store(cachedBlock.ExecutionBlockNumber, cachedBlock)
+			m.cachedBeaconBlock.Store(blockId, cachedBlock)
+			return cachedBlock, true, nil
+		}
+		logrus.Warnf("%s, will refetch it", err.Error())
+	}
+
 	block, exist, err := m.connection.GetBeaconBlock(blockId)
 	if err != nil {
 		return nil, false, err
 	}
 	if !exist {
+		if err := m.beaconBlockStore.Put(blockId, []byte{}); err != nil {
+			return nil, false, fmt.Errorf("save beacon block %d to store err: %w", blockId, err)
+		}
 		m.cachedBeaconBlock.Store(blockId, notExistBeaconBlock)
 		return nil, false, nil
 	}
@@ -239,6 +368,9 @@ func (m *ServiceManager) CacheBeaconBlock(blockId uint64) (*CachedBeaconBlock, b
 		})
 	}
 
+	if err := m.beaconBlockStore.Put(blockId, encodeCachedBeaconBlock(&cachedBlock)); err != nil {
+		return nil, false, fmt.Errorf("save beacon block %d to store err: %w", blockId, err)
+	}
 	m.cachedBeaconBlockByExecBlockHeight.Store(block.ExecutionBlockNumber, &cachedBlock)
 	m.cachedBeaconBlock.Store(blockId, &cachedBlock)
 
@@ -299,14 +431,21 @@ func (m *ServiceManager) pruneCachedBeaconBlocks() {
 		}
 		return true
 	})
+
+	storeRemoveCount, err := m.beaconBlockStore.DeleteBefore(maxClearableBeaconBlockId)
+	if err != nil {
+		logrus.Warnf("prune beacon block store err: %s", err.Error())
+	}
+
 	log := logrus.WithFields(logrus.Fields{
 		"eth1MinHeight":        minHeight,
 		"eth1RemoveCacheCount": eth1RemoveCacheCount,
 		"eth2MinHeight":        maxClearableBeaconBlockId,
 		"eth2RemoveCacheCount": eth2RemoveCacheCount,
+		"storeRemoveCount":     storeRemoveCount,
 		"minHeightLsd":         minHeightSrv.lsdTokenAddress.String(),
 	})
-	if eth1RemoveCacheCount == 0 && eth2RemoveCacheCount == 0 {
+	if eth1RemoveCacheCount == 0 && eth2RemoveCacheCount == 0 && storeRemoveCount == 0 {
 		log.Trace("prune cache blocks")
 	} else {
 		log.Info("prune cache blocks")
@@ -11,6 +11,7 @@ import (
 	"github.com/pkg/errors"
 	"github.com/shopspring/decimal"
 	"github.com/sirupsen/logrus"
+	"github.com/stafiprotocol/eth-lsd-relay/pkg/destorage"
 	"github.com/stafiprotocol/eth-lsd-relay/pkg/utils"
 )
 
@@ -58,16 +59,9 @@ func (s *Service) setMerkleRoot() error {
 			return err
 		}
 
-		fileBytes, err := s.dds.DownloadFile(preCid, utils.NodeRewardsFileNameAtEpoch(s.lsdTokenAddress.String(), s.chainID, dealtEpochOnchain))
+		fileBytes, err := s.downloadNodeRewards(preCid, dealtEpochOnchain)
 		if err != nil {
-			if strings.Contains(err.Error(), "404") {
-				// try old
-				if fileBytes, err = s.dds.DownloadFile(preCid, utils.NodeRewardsFileNameAtEpochOld(s.lsdTokenAddress.String(), dealtEpochOnchain)); err != nil {
-					return err
-				}
-			} else {
-				return err
-			}
+			return err
 		}
 
 		err = json.Unmarshal(fileBytes, &preNodeRewardList)
@@ -84,12 +78,57 @@ func (s *Service) setMerkleRoot() error {
 		}
 	}
 
+	finalNodeRewardsList, rootHash, err := s.buildNodeRewardsList(preNodeRewardList, dealtEth1BlockHeight, targetEpoch, targetEth1BlockHeight)
+	if err != nil {
+		return err
+	}
+
+	// upload file
+	fileBts, err := json.Marshal(finalNodeRewardsList)
+	if err != nil {
+		return err
+	}
+	filePath := utils.NodeRewardsFileNameAtEpoch(s.lsdTokenAddress.String(), s.chainID, targetEpoch)
+	localCid, err := destorage.ComputeCid(filePath, fileBts)
+	if err != nil {
+		return err
+	}
+	if err = s.archiveNodeRewards(targetEpoch, localCid, filePath, fileBts); err != nil {
+		return errors.Wrap(err, "archiveNodeRewards failed")
+	}
+	// dry run records the proposal with the local cid, nothing is uploaded
+	cid := localCid
+	if !s.dryRun {
+		cid, err = s.dds.UploadFile(fileBts, filePath)
+		if err != nil {
+			return err
+		}
+		// only vote for a cid pointing to the bytes the merkle root is built from
+		if cid != localCid {
+			return fmt.Errorf("uploaded node rewards file cid %s does not match local cid %s", cid, localCid)
+		}
+	}
+
+	var merkleTreeRootHash [32]byte
+	copy(merkleTreeRootHash[:], rootHash)
+
+	if err = s.sendSetMerkleRootTx(int64(targetEpoch), merkleTreeRootHash, cid); err != nil {
+		return err
+	}
+
+	s.clearFeePoolBalancesCache() // clear current round cache
+	return nil
+}
+
+// buildNodeRewardsList accumulates node rewards between dealtEth1BlockHeight and targetEth1BlockHeight onto the
+// previous list, it returns the list with proofs and the merkle root, which is zero if the list is empty
+func (s *Service) buildNodeRewardsList(preNodeRewardList NodeRewardsList, dealtEth1BlockHeight, targetEpoch, targetEth1BlockHeight uint64) (NodeRewardsList, utils.NodeHash, error) {
 	preNodeRewardMap := make(NodeRewardsMap)
 	for _, nodeReward := range preNodeRewardList.List {
 		address := common.HexToAddress(nodeReward.Address)
 		_, exist := preNodeRewardMap[address]
 		if exist {
-			return fmt.Errorf("duplicate node address: %s", nodeReward.Address)
+			return NodeRewardsList{}, nil, fmt.Errorf("duplicate node address: %s", nodeReward.Address)
 		}
 		nodeReward.TotalRewardAmount = nodeReward.TotalRewardAmount.Floor()
 		preNodeRewardMap[address] = nodeReward
@@ -97,7 +136,7 @@ func (s *Service) setMerkleRoot() error {
 
 	newNodeRewardsMap, err := s.getNodeNewRewardsBetween(dealtEth1BlockHeight, targetEth1BlockHeight)
 	if err != nil {
-		return err
+		return NodeRewardsList{}, nil, err
 	}
 
 	// cal finalNodeRewardsMap
@@ -153,7 +192,7 @@ func (s *Service) setMerkleRoot() error {
 	for _, node := range finalNodeRewardsMap {
 		// check deposit amount
 		if node.TotalExitDepositAmount.GreaterThan(node.TotalDepositAmount) {
-			return fmt.Errorf("node %s TotalExitDepositAmount %s GreaterThan TotalDepositAmount %s ",
+			return NodeRewardsList{}, nil, fmt.Errorf("node %s TotalExitDepositAmount %s GreaterThan TotalDepositAmount %s ",
 				node.Address, node.TotalExitDepositAmount.StringFixed(0), node.TotalDepositAmount.StringFixed(0))
 		}
 		// append
@@ -172,11 +211,11 @@ func (s *Service) setMerkleRoot() error {
 		// build merkle tree
 		tree, err := buildMerkleTree(finalNodeRewardsList)
 		if err != nil {
-			return err
+			return NodeRewardsList{}, nil, err
 		}
 		rootHash, err = tree.GetRootHash()
 		if err != nil {
-			return err
+			return NodeRewardsList{}, nil, err
 		}
 
 		// calc proof
@@ -185,7 +224,7 @@ func (s *Service) setMerkleRoot() error {
 				nodeReward.TotalRewardAmount.BigInt(), nodeReward.TotalExitDepositAmount.BigInt())
 			proofList, err := tree.GetProof(nodeHash)
 			if err != nil {
-				return errors.Wrap(err, "tree.GetProof failed")
+				return NodeRewardsList{}, nil, errors.Wrap(err, "tree.GetProof failed")
 			}
 
 			proofStrList := make([]string, len(proofList))
@@ -197,26 +236,7 @@ func (s *Service) setMerkleRoot() error {
 		}
 	}
 
-	// upload file
-	fileBts, err := json.Marshal(finalNodeRewardsList)
-	if err != nil {
-		return err
-	}
-	filePath := utils.NodeRewardsFileNameAtEpoch(s.lsdTokenAddress.String(), s.chainID, targetEpoch)
-	cid, err := s.dds.UploadFile(fileBts, filePath)
-	if err != nil {
-		return err
-	}
-
-	var merkleTreeRootHash [32]byte
-	copy(merkleTreeRootHash[:], rootHash)
-
-	if err = s.sendSetMerkleRootTx(int64(targetEpoch), merkleTreeRootHash, cid); err != nil {
-		return err
-	}
-
-	s.clearFeePoolBalancesCache() // clear current round cache
-	return nil
+	return finalNodeRewardsList, rootHash, nil
 }
 
 func buildMerkleTree(nodelist NodeRewardsList) (*utils.MerkleTree, error) {
@@ -273,38 +293,14 @@ func (s *Service) checkStateForSetMerkleRoot() (uint64, uint64, uint64, bool, er
 }
 
 func (s *Service) sendSetMerkleRootTx(targetEpoch int64, rootHash [32]byte, cid string) error {
-	err := s.connection.LockAndUpdateTxOpts()
-	if err != nil {
-		return fmt.Errorf("LockAndUpdateTxOpts err: %w", err)
-	}
-	defer s.connection.UnlockTxOpts()
-
 	encodeBts, err := s.networkWithdrawAbi.Pack("setMerkleRoot", big.NewInt(targetEpoch), rootHash, cid)
 	if err != nil {
 		return err
 	}
 
-	proposalId := utils.ProposalId(s.networkWithdrawAddress, encodeBts, big.NewInt(targetEpoch))
-
-	// check voted
-	hasVoted, err := s.networkProposalContract.HasVoted(nil, proposalId, s.connection.Keypair().CommonAddress())
-	if err != nil {
-		return fmt.Errorf("networkProposalContract.HasVoted err: %s", err)
-	}
-	if hasVoted {
-		return nil
-	}
-
 	s.log.WithFields(logrus.Fields{
 		"cid": cid,
 	}).Info("will sendSetMerkleRootTx")
 
-	tx, err := s.networkProposalContract.ExecProposal(s.connection.TxOpts(), s.networkWithdrawAddress, encodeBts, big.NewInt(int64(targetEpoch)))
-	if err != nil {
-		return err
-	}
-
-	s.log.Info("send setMerkleRoot tx hash: ", tx.Hash().String())
-
-	return s.waitProposalTxOk(tx.Hash(), proposalId)
+	return s.execProposal("setMerkleRoot", newProposalCall(s.networkWithdrawAddress, encodeBts, big.NewInt(targetEpoch)))
 }
@@ -0,0 +1,177 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/samber/lo"
+	"github.com/sirupsen/logrus"
+)
+
+// bump it when the layout or the meaning of persisted sections changes, old states will be discarded.
+// 2: events are synced up to the safe block only, states of older relays may hold events of reorged blocks
+const stateVersion = 2
+
+const (
+	stateSectionMeta              = "meta"
+	stateSectionValidators        = "validators"
+	stateSectionNodes             = "nodes"
+	stateSectionGovDeposits       = "govDeposits"
+	stateSectionStakerWithdrawals = "stakerWithdrawals"
+	stateSectionExitElections     = "exitElections"
+)
+
+type stateMeta struct {
+	Version                      uint64
+	NetworkCreateBlock           uint64
+	StartAtBlock                 uint64
+	LatestBlockOfSyncEvents      uint64
+	LatestBlockOfUpdateValidator uint64
+	LatestEpochOfUpdateValidator uint64
+}
+
+// checkpointState saves cursors and caches built by syncEvents and updateValidatorsFromNetwork,
+// only sections changed since the last checkpoint are rewritten
+func (s *Service) checkpointState() error {
+	if s.stateStore == nil {
+		return nil
+	}
+
+	meta := stateMeta{
+		Version:                      stateVersion,
+		NetworkCreateBlock:           s.networkCreateBlock,
+		StartAtBlock:                 s.startAtBlock,
+		LatestBlockOfSyncEvents:      s.latestBlockOfSyncEvents,
+		LatestBlockOfUpdateValidator: s.latestBlockOfUpdateValidator,
+		LatestEpochOfUpdateValidator: s.latestEpochOfUpdateValidator,
+	}
+
+	sectionValues := map[string]interface{}{
+		stateSectionMeta:              meta,
+		stateSectionValidators:        s.validators,
+		stateSectionNodes:             s.nodes,
+		stateSectionGovDeposits:       s.govDeposits,
+		stateSectionStakerWithdrawals: s.stakerWithdrawals,
+		stateSectionExitElections:     s.exitElections,
+	}
+	sections := make(map[string][]byte, len(sectionValues))
+	digests := make(map[string][32]byte, len(sectionValues))
+	for name, value := range sectionValues {
+		bts, err := json.Marshal(value)
+		if err != nil {
+			return fmt.Errorf("marshal state section %s err: %w", name, err)
+		}
+		digest := sha256.Sum256(bts)
+		if s.stateDigests[name] == digest {
+			continue
+		}
+		sections[name] = bts
+		digests[name] = digest
+	}
+
+	if len(sections) == 0 {
+		return nil
+	}
+	if err := s.stateStore.WriteState(s.lsdTokenAddress.String(), sections); err != nil {
+		return fmt.Errorf("write state err: %w", err)
+	}
+	for name, digest := range digests {
+		s.stateDigests[name] = digest
+	}
+
+	s.log.WithFields(logrus.Fields{
+		"sections":                lo.Keys(sections),
+		"latestBlockOfSyncEvents": s.latestBlockOfSyncEvents,
+	}).Debug("checkpoint state")
+
+	return nil
+}
+
+// loadState restores the checkpoint saved by checkpointState, it should be called after initLatestBlockOfSyncBlock.
+// return false if there is no usable checkpoint
+func (s *Service) loadState() (bool, error) {
+	if s.stateStore == nil {
+		return false, nil
+	}
+	sections, err := s.stateStore.ReadState(s.lsdTokenAddress.String())
+	if err != nil {
+		return false, fmt.Errorf("read state err: %w", err)
+	}
+	metaBts, exist := sections[stateSectionMeta]
+	if !exist {
+		return false, nil
+	}
+
+	meta := stateMeta{}
+	if err := json.Unmarshal(metaBts, &meta); err != nil {
+		return false, fmt.Errorf("unmarshal state meta err: %w", err)
+	}
+	if meta.Version != stateVersion || meta.NetworkCreateBlock != s.networkCreateBlock {
+		s.log.WithFields(logrus.Fields{
+			"version":            meta.Version,
+			"networkCreateBlock": meta.NetworkCreateBlock,
+		}).Warn("discard outdated state")
+		return false, s.stateStore.DeleteState(s.lsdTokenAddress.String())
+	}
+
+	validators := make(map[string]*Validator)
+	nodes := make(map[common.Address]*Node)
+	govDeposits := make(map[string][][]byte)
+	stakerWithdrawals := make(map[uint64]*StakerWithdrawal)
+	exitElections := make(map[uint64]*ExitElection)
+	sectionValues := map[string]interface{}{
+		stateSectionValidators:        &validators,
+		stateSectionNodes:             &nodes,
+		stateSectionGovDeposits:       &govDeposits,
+		stateSectionStakerWithdrawals: &stakerWithdrawals,
+		stateSectionExitElections:     &exitElections,
+	}
+	for name, value := range sectionValues {
+		bts, exist := sections[name]
+		if !exist {
+			return false, fmt.Errorf("state section %s missing", name)
+		}
+		if err := json.Unmarshal(bts, value); err != nil {
+			return false, fmt.Errorf("unmarshal state section %s err: %w", name, err)
+		}
+		s.stateDigests[name] = sha256.Sum256(bts)
+	}
+	s.stateDigests[stateSectionMeta] = sha256.Sum256(metaBts)
+
+	s.validators = validators
+	s.nodes = nodes
+	s.govDeposits = govDeposits
+	s.stakerWithdrawals = stakerWithdrawals
+	s.exitElections = exitElections
+
+	s.validatorsByIndexMutex.Lock()
+	for _, validator := range s.validators {
+		if validator.ValidatorIndex > 0 {
+			s.validatorsByIndex[validator.ValidatorIndex] = validator
+		}
+	}
+	s.validatorsByIndexMutex.Unlock()
+
+	s.startAtBlock = meta.StartAtBlock
+	s.latestBlockOfSyncEvents = meta.LatestBlockOfSyncEvents
+	s.latestBlockOfUpdateValidator = meta.LatestBlockOfUpdateValidator
+	s.latestEpochOfUpdateValidator = meta.LatestEpochOfUpdateValidator
+
+	// a checkpoint only exists after the first node stake event was found
+	s.waitFirstNodeStakeEvent = false
+	if s.latestBlockOfSyncBlock < s.startAtBlock {
+		s.latestBlockOfSyncBlock = s.startAtBlock
+	}
+
+	s.log.WithFields(logrus.Fields{
+		"validators":                   len(s.validators),
+		"nodes":                        len(s.nodes),
+		"latestBlockOfSyncEvents":      s.latestBlockOfSyncEvents,
+		"latestBlockOfUpdateValidator": s.latestBlockOfUpdateValidator,
+		"latestEpochOfUpdateValidator": s.latestEpochOfUpdateValidator,
+	}).Info("state loaded")
+
+	return true, nil
+}
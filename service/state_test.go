@@ -0,0 +1,111 @@
+package service
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/shopspring/decimal"
+	"github.com/sirupsen/logrus"
+	"github.com/stafiprotocol/eth-lsd-relay/pkg/local_store"
+	"github.com/stretchr/testify/assert"
+)
+
+func newStateTestService(store local_store.StateStore) *Service {
+	return &Service{
+		log:                logrus.WithField("test", true),
+		lsdTokenAddress:    common.HexToAddress("0x61135C59A4Eb452b89963188eD6B6a7487049764"),
+		stateStore:         store,
+		stateDigests:       make(map[string][32]byte),
+		networkCreateBlock: 100,
+		startAtBlock:       100,
+		govDeposits:        make(map[string][][]byte),
+		validators:         make(map[string]*Validator),
+		validatorsByIndex:  make(map[uint64]*Validator),
+		nodes:              make(map[common.Address]*Node),
+		stakerWithdrawals:  make(map[uint64]*StakerWithdrawal),
+		exitElections:      make(map[uint64]*ExitElection),
+	}
+}
+
+func TestCheckpointAndLoadState(t *testing.T) {
+	store, err := local_store.NewLevelDBStateStore(t.TempDir())
+	assert.Nil(t, err)
+	defer store.Close()
+
+	nodeAddress := common.HexToAddress("0x179386303fC2B51c306Ae9D961C73Ea9a9EA0C8d")
+	s := newStateTestService(store)
+	s.startAtBlock = 120
+	s.latestBlockOfSyncEvents = 500
+	s.latestBlockOfUpdateValidator = 490
+	s.latestEpochOfUpdateValidator = 30
+	s.validators["0a0b"] = &Validator{
+		Pubkey:                []byte{0x0a, 0x0b},
+		NodeAddress:           nodeAddress,
+		NodeDepositAmountDeci: decimal.RequireFromString("1000000000000000000000000"),
+		ValidatorIndex:        7,
+		Status:                2,
+	}
+	s.nodes[nodeAddress] = &Node{NodeAddress: nodeAddress, NodeType: 2, PubkeyNumber: 1}
+	s.govDeposits["0a0b"] = [][]byte{{0x01}}
+	s.stakerWithdrawals[3] = &StakerWithdrawal{WithdrawIndex: 3, EthAmount: decimal.NewFromInt(5)}
+	s.exitElections[9] = &ExitElection{WithdrawCycle: 9, ValidatorIndexList: []uint64{7}}
+	assert.Nil(t, s.checkpointState())
+
+	loaded := newStateTestService(store)
+	loaded.waitFirstNodeStakeEvent = true
+	ok, err := loaded.loadState()
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.False(t, loaded.waitFirstNodeStakeEvent)
+	assert.Equal(t, uint64(120), loaded.startAtBlock)
+	assert.Equal(t, uint64(120), loaded.latestBlockOfSyncBlock)
+	assert.Equal(t, uint64(500), loaded.latestBlockOfSyncEvents)
+	assert.Equal(t, uint64(490), loaded.latestBlockOfUpdateValidator)
+	assert.Equal(t, uint64(30), loaded.latestEpochOfUpdateValidator)
+	assert.Equal(t, s.nodes, loaded.nodes)
+	assert.Equal(t, s.govDeposits, loaded.govDeposits)
+	assert.Equal(t, s.exitElections, loaded.exitElections)
+	assert.True(t, s.stakerWithdrawals[3].EthAmount.Equal(loaded.stakerWithdrawals[3].EthAmount))
+	assert.True(t, s.validators["0a0b"].NodeDepositAmountDeci.Equal(loaded.validators["0a0b"].NodeDepositAmountDeci))
+	val, exist := loaded.getValidatorByIndex(7)
+	assert.True(t, exist)
+	assert.Equal(t, nodeAddress, val.NodeAddress)
+
+	// nothing changed, nothing to write
+	assert.Nil(t, loaded.checkpointState())
+
+	// state of another network deployment is discarded
+	other := newStateTestService(store)
+	other.networkCreateBlock = 101
+	ok, err = other.loadState()
+	assert.Nil(t, err)
+	assert.False(t, ok)
+	sections, err := store.ReadState(s.lsdTokenAddress.String())
+	assert.Nil(t, err)
+	assert.Len(t, sections, 0)
+}
+
+func TestLoadStateOfOlderVersion(t *testing.T) {
+	store, err := local_store.NewLevelDBStateStore(t.TempDir())
+	assert.Nil(t, err)
+	defer store.Close()
+
+	// relays before version 2 synced events up to the latest block, their states may hold events of reorged blocks
+	s := newStateTestService(store)
+	s.latestBlockOfSyncEvents = 500
+	assert.Nil(t, s.checkpointState())
+	meta, err := json.Marshal(stateMeta{Version: 1, NetworkCreateBlock: s.networkCreateBlock, LatestBlockOfSyncEvents: 500})
+	assert.Nil(t, err)
+	assert.Nil(t, store.WriteState(s.lsdTokenAddress.String(), map[string][]byte{stateSectionMeta: meta}))
+
+	loaded := newStateTestService(store)
+	ok, err := loaded.loadState()
+	assert.Nil(t, err)
+	assert.False(t, ok)
+	assert.Equal(t, uint64(0), loaded.latestBlockOfSyncEvents)
+
+	sections, err := store.ReadState(s.lsdTokenAddress.String())
+	assert.Nil(t, err)
+	assert.Empty(t, sections)
+}
@@ -0,0 +1,143 @@
+package service
+
+import (
+	"sort"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stafiprotocol/eth-lsd-relay/pkg/metrics"
+)
+
+// HandlerStatus records the latest run of a handler started by startGroupHandlers
+type HandlerStatus struct {
+	Name           string    `json:"name"`
+	Runs           uint64    `json:"runs"`
+	Failures       uint64    `json:"failures"`
+	RetryTimes     int       `json:"retryTimes"` // consecutive failed rounds of the handler group
+	LastRunAt      time.Time `json:"lastRunAt"`
+	LastDurationMs int64     `json:"lastDurationMs"`
+	LastError      string    `json:"lastError,omitempty"`
+	LastErrorAt    time.Time `json:"lastErrorAt,omitempty"`
+}
+
+// ServiceStatus is a snapshot of the cursors and caches of a service
+type ServiceStatus struct {
+	LsdToken  string    `json:"lsdToken"`
+	UpdatedAt time.Time `json:"updatedAt"`
+
+	WaitFirstNodeStakeEvent           bool   `json:"waitFirstNodeStakeEvent"`
+	StartAtBlock                      uint64 `json:"startAtBlock"`
+	LatestBlockOfSyncBlock            uint64 `json:"latestBlockOfSyncBlock"`
+	LatestSlotOfSyncBlock             uint64 `json:"latestSlotOfSyncBlock"`
+	LatestBlockOfSyncEvents           uint64 `json:"latestBlockOfSyncEvents"`
+	LatestBlockOfUpdateValidator      uint64 `json:"latestBlockOfUpdateValidator"`
+	LatestEpochOfUpdateValidator      uint64 `json:"latestEpochOfUpdateValidator"`
+	LatestDistributeWithdrawalsHeight uint64 `json:"latestDistributeWithdrawalsHeight"`
+	LatestDistributePriorityFeeHeight uint64 `json:"latestDistributePriorityFeeHeight"`
+	LatestMerkleRootEpoch             uint64 `json:"latestMerkleRootEpoch"`
+
+	Nodes              int           `json:"nodes"`
+	NodesByType        map[uint8]int `json:"nodesByType"` // 1 light node 2 trust node
+	Validators         int           `json:"validators"`
+	ValidatorsByStatus map[uint8]int `json:"validatorsByStatus"` // status details defined in pkg/utils/eth2.go
+
+	ExitElections            []*ExitElection     `json:"exitElections"`
+	PendingStakerWithdrawals []*StakerWithdrawal `json:"pendingStakerWithdrawals"`
+
+	Handlers []HandlerStatus `json:"handlers"`
+}
+
+func (s *Service) recordHandlerRun(name string, startAt time.Time, err error, retryTimes int) {
+	status := HandlerStatus{Name: name}
+	if old, exist := s.handlerStatuses.Load(name); exist {
+		status = old.(HandlerStatus)
+	}
+	status.Runs++
+	status.RetryTimes = retryTimes
+	status.LastRunAt = startAt
+	status.LastDurationMs = time.Since(startAt).Milliseconds()
+	if err != nil {
+		status.Failures++
+		status.LastError = err.Error()
+		status.LastErrorAt = time.Now()
+	}
+	s.handlerStatuses.Store(name, status)
+
+	metrics.ObserveHandler(s.lsdTokenAddress.String(), name, startAt, err, retryTimes)
+}
+
+// refreshStatus rebuilds the status snapshot. It runs in the slot handler group, the only one writing the nodes
+// and events caches and adding validators, but validator fields are also updated by updateValidatorsFromBeacon
+// in the epoch handler group, so they are read under validatorsByIndexMutex
+func (s *Service) refreshStatus() error {
+	status := ServiceStatus{
+		LsdToken:                          s.lsdTokenAddress.String(),
+		UpdatedAt:                         time.Now(),
+		WaitFirstNodeStakeEvent:           s.waitFirstNodeStakeEvent,
+		StartAtBlock:                      s.startAtBlock,
+		LatestBlockOfSyncBlock:            s.latestBlockOfSyncBlock,
+		LatestSlotOfSyncBlock:             s.latestSlotOfSyncBlock,
+		LatestBlockOfSyncEvents:           s.latestBlockOfSyncEvents,
+		LatestBlockOfUpdateValidator:      s.latestBlockOfUpdateValidator,
+		LatestEpochOfUpdateValidator:      s.latestEpochOfUpdateValidator,
+		LatestDistributeWithdrawalsHeight: s.latestDistributeWithdrawalsHeight,
+		LatestDistributePriorityFeeHeight: s.latestDistributePriorityFeeHeight,
+		LatestMerkleRootEpoch:             s.latestMerkleRootEpoch,
+
+		Nodes:                    len(s.nodes),
+		NodesByType:              make(map[uint8]int),
+		Validators:               len(s.validators),
+		ValidatorsByStatus:       make(map[uint8]int),
+		ExitElections:            make([]*ExitElection, 0, len(s.exitElections)),
+		PendingStakerWithdrawals: make([]*StakerWithdrawal, 0),
+	}
+	for _, node := range s.nodes {
+		status.NodesByType[node.NodeType]++
+	}
+	s.validatorsByIndexMutex.RLock()
+	for _, val := range s.validators {
+		status.ValidatorsByStatus[val.Status]++
+	}
+	s.validatorsByIndexMutex.RUnlock()
+	for _, election := range s.exitElections {
+		copied := *election
+		status.ExitElections = append(status.ExitElections, &copied)
+	}
+	sort.Slice(status.ExitElections, func(i, j int) bool {
+		return status.ExitElections[i].WithdrawCycle < status.ExitElections[j].WithdrawCycle
+	})
+	for _, sw := range s.stakerWithdrawals {
+		if sw.ClaimedBlockNumber == 0 {
+			copied := *sw
+			status.PendingStakerWithdrawals = append(status.PendingStakerWithdrawals, &copied)
+		}
+	}
+	sort.Slice(status.PendingStakerWithdrawals, func(i, j int) bool {
+		return status.PendingStakerWithdrawals[i].WithdrawIndex < status.PendingStakerWithdrawals[j].WithdrawIndex
+	})
+
+	s.status.Store(&status)
+	return nil
+}
+
+// Status returns the latest status snapshot with live handler statuses, nil if not ready
+func (s *Service) Status() *ServiceStatus {
+	snapshot := s.status.Load()
+	if snapshot == nil {
+		return nil
+	}
+	status := *snapshot
+	status.Handlers = make([]HandlerStatus, 0)
+	s.handlerStatuses.Range(func(_, value any) bool {
+		status.Handlers = append(status.Handlers, value.(HandlerStatus))
+		return true
+	})
+	sort.Slice(status.Handlers, func(i, j int) bool {
+		return status.Handlers[i].Name < status.Handlers[j].Name
+	})
+	return &status
+}
+
+func (s *Service) LsdTokenAddress() common.Address {
+	return s.lsdTokenAddress
+}
@@ -5,6 +5,8 @@ import (
 	"encoding/hex"
 	"fmt"
 	"math/big"
+	"sort"
+	"strconv"
 	"time"
 
 	"github.com/pkg/errors"
@@ -13,9 +15,51 @@ import (
 	"github.com/sirupsen/logrus"
 	"github.com/stafiprotocol/eth-lsd-relay/pkg/connection/beacon"
 	"github.com/stafiprotocol/eth-lsd-relay/pkg/connection/types"
+	"github.com/stafiprotocol/eth-lsd-relay/pkg/notify"
 	"github.com/stafiprotocol/eth-lsd-relay/pkg/utils"
 )
 
+var ErrZeroLsdTokenTotalSupply = fmt.Errorf("lsd token total supply is zero")
+
+// BalancesBreakdown is what submitBalances computes for a target epoch, amounts are in wei
+type BalancesBreakdown struct {
+	LsdToken    string `json:"lsdToken"`
+	TargetEpoch uint64 `json:"targetEpoch"`
+	TargetBlock uint64 `json:"targetBlock"`
+
+	LatestDistributeWithdrawalsHeight uint64 `json:"latestDistributeWithdrawalsHeight"`
+	LatestDistributePriorityFeeHeight uint64 `json:"latestDistributePriorityFeeHeight"`
+
+	TotalUserEthFromValidator     decimal.Decimal `json:"totalUserEthFromValidator"`
+	UserDepositPoolBalance        decimal.Decimal `json:"userDepositPoolBalance"`
+	UserUndistributedWithdrawals  decimal.Decimal `json:"userUndistributedWithdrawals"`
+	UserUndistributedPriorityFee  decimal.Decimal `json:"userUndistributedPriorityFee"`
+	TotalMissingAmountForWithdraw decimal.Decimal `json:"totalMissingAmountForWithdraw"`
+	TotalUserEth                  decimal.Decimal `json:"totalUserEth"`
+	TotalUserEthAdjusted          bool            `json:"totalUserEthAdjusted"` // raised to lsdTokenTotalSupply
+	LsdTokenTotalSupply           decimal.Decimal `json:"lsdTokenTotalSupply"`
+
+	OldExchangeRate    decimal.Decimal `json:"oldExchangeRate"`
+	NewExchangeRate    decimal.Decimal `json:"newExchangeRate"`
+	RateChange         decimal.Decimal `json:"rateChange"`
+	RateChangeLimit    decimal.Decimal `json:"rateChangeLimit"`
+	RateChangeExceeded bool            `json:"rateChangeExceeded"`
+
+	Validators []*ValidatorBalance `json:"validators"`
+}
+
+// ValidatorBalance is the contribution of a validator to the total user eth
+type ValidatorBalance struct {
+	Pubkey         string          `json:"pubkey"`
+	NodeAddress    string          `json:"nodeAddress"`
+	NodeType       uint8           `json:"nodeType"`
+	ValidatorIndex uint64          `json:"validatorIndex"`
+	OnchainStatus  uint8           `json:"onchainStatus"` // status of GetPubkeyInfoList at target block
+	BeaconStatus   uint8           `json:"beaconStatus"`  // status mapped from beacon at target epoch, zero if not queried
+	Balance        uint64          `json:"balance"`       // beacon balance at target epoch, unit Gwei
+	UserEth        decimal.Decimal `json:"userEth"`
+}
+
 func (s *Service) submitBalances() error {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Minute*5)
 	defer cancel()
@@ -51,21 +95,69 @@ func (s *Service) submitBalances() error {
 		"balancesBlockOnChain": snapshotOnchain.Block.Uint64(),
 	}).Debug("epochInfo")
 
+	breakdown, err := s.computeBalances(ctx, targetEpoch, targetBlock)
+	if err != nil {
+		if errors.Is(err, ErrZeroLsdTokenTotalSupply) {
+			return nil
+		}
+		return err
+	}
+	if err := s.writeBalancesReport(breakdown); err != nil {
+		s.log.WithError(err).Warn("write balances report failed")
+	}
+
+	rateInfoLog := s.log.WithFields(logrus.Fields{
+		"targetBlockNumber":                 targetBlock,
+		"targetEpoch":                       targetEpoch,
+		"latestDistributeWithdrawalsHeight": breakdown.LatestDistributeWithdrawalsHeight,
+		"latestDistributePriorityFeeHeight": breakdown.LatestDistributePriorityFeeHeight,
+		"totalUserEthFromValidator":         breakdown.TotalUserEthFromValidator.StringFixed(0),
+		"userDepositPoolBalanceDeci":        breakdown.UserDepositPoolBalance.StringFixed(0),
+		"userUndistributedWithdrawalsDeci":  breakdown.UserUndistributedWithdrawals.StringFixed(0),
+		"userUndistributedPriorityFeeDeci":  breakdown.UserUndistributedPriorityFee.StringFixed(0),
+		"totalMissingAmountForWithdrawDeci": breakdown.TotalMissingAmountForWithdraw.StringFixed(0),
+		"totalUserEth":                      breakdown.TotalUserEth.StringFixed(0),
+		"lsdTokenTotalSupply":               breakdown.LsdTokenTotalSupply.StringFixed(0),
+		"newExchangeRate":                   breakdown.NewExchangeRate.StringFixed(0),
+		"oldExchangeRate":                   breakdown.OldExchangeRate.StringFixed(0),
+		"rateChange":                        breakdown.RateChange.StringFixed(0),
+	})
+	if breakdown.RateChangeExceeded {
+		rateInfoLog.Error("exchangeRateInfo")
+		err := fmt.Errorf("exceed rate change limit %s, newExchangeRate %s, oldExchangeRate %s",
+			breakdown.RateChangeLimit.String(), breakdown.NewExchangeRate.String(), breakdown.OldExchangeRate.String())
+		notify.Notify(notify.Alert{
+			Key:      "rate-change-limit/" + s.lsdTokenAddress.String(),
+			Severity: notify.SeverityCritical,
+			Title:    "submitBalances exceeds rate change limit",
+			Message:  err.Error(),
+			Fields:   map[string]string{"lsdToken": s.lsdTokenAddress.String(), "targetEpoch": strconv.FormatUint(targetEpoch, 10)},
+		})
+		return err
+	}
+	rateInfoLog.Info("exchangeRateInfo")
+
+	return s.sendSubmitBalancesTx(big.NewInt(int64(targetBlock)), breakdown.TotalUserEth.BigInt(), breakdown.LsdTokenTotalSupply.BigInt())
+}
+
+// computeBalances computes total user eth and the new exchange rate at the start block of targetEpoch,
+// beacon blocks and validators must be synced to targetBlock
+func (s *Service) computeBalances(ctx context.Context, targetEpoch, targetBlock uint64) (*BalancesBreakdown, error) {
 	targetCallOpts := s.connection.CallOpts(big.NewInt(int64(targetBlock)))
 
 	lsdTokenTotalSupply, err := s.lsdTokenContract.TotalSupply(targetCallOpts)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	lsdTokenTotalSupplyDeci := decimal.NewFromBigInt(lsdTokenTotalSupply, 0)
 	if lsdTokenTotalSupplyDeci.IsZero() {
-		return nil
+		return nil, ErrZeroLsdTokenTotalSupply
 	}
 
 	// deposit pool balance
 	userDepositPoolBalance, err := s.userDepositContract.GetBalance(targetCallOpts)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	userDepositPoolBalanceDeci := decimal.NewFromBigInt(userDepositPoolBalance, 0)
 
@@ -75,127 +167,155 @@ func (s *Service) submitBalances() error {
 	}).Debug("validatorDepositedList")
 
 	pubkeyInfoAtTargetBlock, err := s.nodeDepositContract.GetPubkeyInfoList(
-		s.connection.CallOpts(big.NewInt(int64(targetBlock))),
+		targetCallOpts,
 		lo.Map(targetValidators, func(v *Validator, _ int) []byte { return v.Pubkey }),
 	)
 	if err != nil {
-		return errors.Wrapf(err, "get target pubkey info list, len: %d", len(targetValidators))
+		return nil, errors.Wrapf(err, "get target pubkey info list, len: %d", len(targetValidators))
 	}
 
 	// user eth from validators
 	totalUserEthFromValidatorDeci := decimal.Zero
+	validatorBalances := make([]*ValidatorBalance, 0, len(targetValidators))
 	for _, validator := range targetValidators {
-		targetInfo, ok := pubkeyInfoAtTargetBlock[hex.EncodeToString(validator.Pubkey)]
+		pubkey := hex.EncodeToString(validator.Pubkey)
+		targetInfo, ok := pubkeyInfoAtTargetBlock[pubkey]
 		if !ok {
-			return fmt.Errorf("fail to get pubkey target info for %s", hex.EncodeToString(validator.Pubkey))
+			return nil, fmt.Errorf("fail to get pubkey target info for %s", pubkey)
 		}
-		userAllEth, err := s.getUserEthInfoFromValidatorBalance(ctx, targetInfo.Status, validator, targetEpoch)
+		userAllEth, beaconStatus, balance, err := s.getUserEthInfoFromValidatorBalance(ctx, targetInfo.Status, validator, targetEpoch)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		totalUserEthFromValidatorDeci = totalUserEthFromValidatorDeci.Add(userAllEth)
+		validatorBalances = append(validatorBalances, &ValidatorBalance{
+			Pubkey:         pubkey,
+			NodeAddress:    validator.NodeAddress.String(),
+			NodeType:       validator.NodeType,
+			ValidatorIndex: validator.ValidatorIndex,
+			OnchainStatus:  targetInfo.Status,
+			BeaconStatus:   beaconStatus,
+			Balance:        balance,
+			UserEth:        userAllEth,
+		})
 	}
+	sort.Slice(validatorBalances, func(i, j int) bool {
+		return validatorBalances[i].Pubkey < validatorBalances[j].Pubkey
+	})
 
 	// total missing amount for withdraw
 	totalMissingAmount, err := s.networkWithdrawContract.TotalMissingAmountForWithdraw(targetCallOpts)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	totalMissingAmountDeci := decimal.NewFromBigInt(totalMissingAmount, 0)
 
 	// user eth from undistributed withdrawals
-	latestDistributeWithdrawalsHeight, err := s.networkWithdrawContract.LatestDistributeWithdrawalsHeight(targetCallOpts)
+	latestDistributeWithdrawalsHeight, latestDistributePriorityFeeHeight, err := s.latestDistributeHeightsAt(targetBlock)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	if latestDistributeWithdrawalsHeight.Cmp(big.NewInt(0)) == 0 {
-		latestDistributeWithdrawalsHeight = big.NewInt(int64(s.startAtBlock))
-	}
-	userEthFromWithdrawDeci, _, _, _, err := s.getUserNodePlatformFromWithdrawals(latestDistributeWithdrawalsHeight.Uint64(), targetBlock)
+	userEthFromWithdrawDeci, _, _, _, err := s.getUserNodePlatformFromWithdrawals(latestDistributeWithdrawalsHeight, targetBlock)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// user eth from undistributed priority fee
-	latestDistributePriorityFeeHeight, err := s.networkWithdrawContract.LatestDistributePriorityFeeHeight(targetCallOpts)
-	if err != nil {
-		return err
-	}
-	if latestDistributePriorityFeeHeight.Cmp(big.NewInt(0)) == 0 {
-		latestDistributePriorityFeeHeight = big.NewInt(int64(s.startAtBlock))
-	}
 	log := s.log.WithFields(logrus.Fields{
 		"submitBalances": true,
 	})
-	userEthFromPriorityFeeDeci, _, _, _, err := s.getUserNodePlatformFromPriorityFee(log, latestDistributePriorityFeeHeight.Uint64(), targetBlock)
+	userEthFromPriorityFeeDeci, _, _, _, err := s.getUserNodePlatformFromPriorityFee(log, latestDistributePriorityFeeHeight, targetBlock)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// ----final: total user eth = total user eth from validator + deposit pool balance + user undistributedWithdrawals +
 	// 								+ user undistributed priority fee  - totalMissingAmountForWithdraw
 	totalUserEthDeci := totalUserEthFromValidatorDeci.Add(userDepositPoolBalanceDeci).Add(userEthFromWithdrawDeci).
 		Add(userEthFromPriorityFeeDeci).Sub(totalMissingAmountDeci)
+	totalUserEthAdjusted := false
 	if totalUserEthDeci.BigInt().Cmp(lsdTokenTotalSupply) < 0 {
 		s.log.WithFields(logrus.Fields{
 			"old_totalUserEthDeci": totalUserEthDeci.StringFixed(0),
 		}).Warn("adjust totalUserEthDeci to lsdTokenTotalSupply")
 		totalUserEthDeci = decimal.NewFromBigInt(lsdTokenTotalSupply, 0)
+		totalUserEthAdjusted = true
 	}
 
 	// check exchange rate
 	oldExchangeRate, err := s.networkBalancesContract.GetExchangeRate(targetCallOpts)
 	if err != nil {
-		return fmt.Errorf("rethContract.GetExchangeRate err: %s", err)
+		return nil, fmt.Errorf("rethContract.GetExchangeRate err: %s", err)
 	}
 	oldExchangeRateDeci := decimal.NewFromBigInt(oldExchangeRate, 0)
 
 	newExchangeRateDeci := totalUserEthDeci.Mul(decimal.NewFromInt(1e18)).Div(lsdTokenTotalSupplyDeci)
 	rateChangeLimit, err := s.networkBalancesContract.RateChangeLimit(nil)
 	if err != nil {
-		return err
+		return nil, err
 	}
+	rateChangeLimitDeci := decimal.NewFromBigInt(rateChangeLimit, 0)
 
 	one18 := decimal.NewFromBigInt(big.NewInt(1), 18)
 	rateChange := newExchangeRateDeci.Sub(oldExchangeRateDeci).Abs().Mul(one18).Div(oldExchangeRateDeci)
-	rateInfoLog := s.log.WithFields(logrus.Fields{
-		"targetBlockNumber":                 targetBlock,
-		"targetEpoch":                       targetEpoch,
-		"latestDistributeWithdrawalsHeight": latestDistributeWithdrawalsHeight.String(),
-		"latestDistributePriorityFeeHeight": latestDistributePriorityFeeHeight.String(),
-		"totalUserEthFromValidator":         totalUserEthFromValidatorDeci.StringFixed(0),
-		"userDepositPoolBalanceDeci":        userDepositPoolBalanceDeci.StringFixed(0),
-		"userUndistributedWithdrawalsDeci":  userEthFromWithdrawDeci.StringFixed(0),
-		"userUndistributedPriorityFeeDeci":  userEthFromPriorityFeeDeci.StringFixed(0),
-		"totalMissingAmountForWithdrawDeci": totalMissingAmountDeci.StringFixed(0),
-		"totalUserEth":                      totalUserEthDeci.StringFixed(0),
-		"lsdTokenTotalSupply":               lsdTokenTotalSupplyDeci.StringFixed(0),
-		"newExchangeRate":                   newExchangeRateDeci.StringFixed(0),
-		"oldExchangeRate":                   oldExchangeRateDeci.StringFixed(0),
-		"rateChange":                        rateChange.StringFixed(0),
-	})
-	if rateChange.GreaterThan(decimal.NewFromBigInt(rateChangeLimit, 0)) {
-		rateInfoLog.Error("exchangeRateInfo")
-		return fmt.Errorf("exceed rate change limit %s, newExchangeRate %s, oldExchangeRate %s",
-			rateChangeLimit.String(), newExchangeRateDeci.String(), oldExchangeRateDeci.String())
-	}
-	rateInfoLog.Info("exchangeRateInfo")
 
-	return s.sendSubmitBalancesTx(big.NewInt(int64(targetBlock)), totalUserEthDeci.BigInt(), lsdTokenTotalSupply)
+	return &BalancesBreakdown{
+		LsdToken:                          s.lsdTokenAddress.String(),
+		TargetEpoch:                       targetEpoch,
+		TargetBlock:                       targetBlock,
+		LatestDistributeWithdrawalsHeight: latestDistributeWithdrawalsHeight,
+		LatestDistributePriorityFeeHeight: latestDistributePriorityFeeHeight,
+		TotalUserEthFromValidator:         totalUserEthFromValidatorDeci,
+		UserDepositPoolBalance:            userDepositPoolBalanceDeci,
+		UserUndistributedWithdrawals:      userEthFromWithdrawDeci,
+		UserUndistributedPriorityFee:      userEthFromPriorityFeeDeci,
+		TotalMissingAmountForWithdraw:     totalMissingAmountDeci,
+		TotalUserEth:                      totalUserEthDeci,
+		TotalUserEthAdjusted:              totalUserEthAdjusted,
+		LsdTokenTotalSupply:               lsdTokenTotalSupplyDeci,
+		OldExchangeRate:                   oldExchangeRateDeci,
+		NewExchangeRate:                   newExchangeRateDeci,
+		RateChange:                        rateChange,
+		RateChangeLimit:                   rateChangeLimitDeci,
+		RateChangeExceeded:                rateChange.GreaterThan(rateChangeLimitDeci),
+		Validators:                        validatorBalances,
+	}, nil
+}
 
+// latestDistributeHeightsAt returns the latest distribute withdrawals and priority fee heights at block,
+// the network start block is used if not distributed yet
+func (s *Service) latestDistributeHeightsAt(block uint64) (uint64, uint64, error) {
+	callOpts := s.connection.CallOpts(big.NewInt(int64(block)))
+	latestDistributeWithdrawalsHeight, err := s.networkWithdrawContract.LatestDistributeWithdrawalsHeight(callOpts)
+	if err != nil {
+		return 0, 0, err
+	}
+	if latestDistributeWithdrawalsHeight.Cmp(big.NewInt(0)) == 0 {
+		latestDistributeWithdrawalsHeight = big.NewInt(int64(s.startAtBlock))
+	}
+	latestDistributePriorityFeeHeight, err := s.networkWithdrawContract.LatestDistributePriorityFeeHeight(callOpts)
+	if err != nil {
+		return 0, 0, err
+	}
+	if latestDistributePriorityFeeHeight.Cmp(big.NewInt(0)) == 0 {
+		latestDistributePriorityFeeHeight = big.NewInt(int64(s.startAtBlock))
+	}
+	return latestDistributeWithdrawalsHeight.Uint64(), latestDistributePriorityFeeHeight.Uint64(), nil
 }
 
-func (task *Service) getUserEthInfoFromValidatorBalance(ctx context.Context, pubkeyEth1TargetStatus uint8, validator *Validator, targetEpoch uint64) (decimal.Decimal, error) {
+// getUserEthInfoFromValidatorBalance returns (user eth, beacon status, beacon balance), beacon status and
+// balance are zero if the validator is not queried on beacon
+func (task *Service) getUserEthInfoFromValidatorBalance(ctx context.Context, pubkeyEth1TargetStatus uint8, validator *Validator, targetEpoch uint64) (decimal.Decimal, uint8, uint64, error) {
 	switch pubkeyEth1TargetStatus {
 	case utils.ValidatorStatusDeposited, utils.ValidatorStatusWithdrawMatch, utils.ValidatorStatusWithdrawUnmatch:
 		switch validator.NodeType {
 		case utils.NodeTypeSolo:
-			return decimal.Zero, nil
+			return decimal.Zero, 0, 0, nil
 		case utils.NodeTypeTrust:
-			return decimal.NewFromBigInt(big.NewInt(int64(task.manager.cfg.TrustNodeDepositAmount)), 18), nil
+			return decimal.NewFromBigInt(big.NewInt(int64(task.manager.cfg.TrustNodeDepositAmount)), 18), 0, 0, nil
 		default:
 			// common node and trust node should not happen here
-			return decimal.Zero, fmt.Errorf("unknown node type: %d", validator.NodeType)
+			return decimal.Zero, 0, 0, fmt.Errorf("unknown node type: %d", validator.NodeType)
 		}
 	}
 
@@ -203,18 +323,19 @@ func (task *Service) getUserEthInfoFromValidatorBalance(ctx context.Context, pub
 		Epoch: &targetEpoch,
 	})
 	if err != nil {
-		return decimal.Zero, err
+		return decimal.Zero, 0, 0, err
 	}
 
 	status, err := mapValidatorStatus(&validatorStatus)
 	if err != nil {
-		return decimal.Zero, fmt.Errorf("unknown validator status: %d", status)
+		return decimal.Zero, 0, 0, fmt.Errorf("unknown validator status: %d", status)
 	}
+	balance := validatorStatus.Balance
 
 	switch status {
 	case utils.ValidatorStatusStaked, utils.ValidatorStatusWaiting:
 		userDepositBalance := utils.StandardEffectiveBalanceDeci.Sub(validator.NodeDepositAmountDeci)
-		return userDepositBalance, nil
+		return userDepositBalance, status, balance, nil
 
 	case utils.ValidatorStatusActive, utils.ValidatorStatusExited, utils.ValidatorStatusWithdrawable, utils.ValidatorStatusWithdrawDone,
 		utils.ValidatorStatusActiveSlash, utils.ValidatorStatusExitedSlash, utils.ValidatorStatusWithdrawableSlash, utils.ValidatorStatusWithdrawDoneSlash:
@@ -222,20 +343,20 @@ func (task *Service) getUserEthInfoFromValidatorBalance(ctx context.Context, pub
 		userDepositBalance := utils.StandardEffectiveBalanceDeci.Sub(validator.NodeDepositAmountDeci)
 		// case: activeEpoch 155747 > targetEpoch 155700
 		if validator.ActiveEpoch > targetEpoch {
-			return userDepositBalance, nil
+			return userDepositBalance, status, balance, nil
 		}
 
 		userDepositPlusReward, err := task.getUserDepositPlusReward(validator.NodeDepositAmountDeci, decimal.NewFromInt(int64(validatorStatus.Balance)).Mul(utils.GweiDeci))
 		if err != nil {
-			return decimal.Zero, errors.Wrap(err, "getUserDepositPlusReward failed")
+			return decimal.Zero, 0, 0, errors.Wrap(err, "getUserDepositPlusReward failed")
 		}
-		return userDepositPlusReward, nil
+		return userDepositPlusReward, status, balance, nil
 
 	case utils.ValidatorStatusDistributed, utils.ValidatorStatusDistributedSlash:
-		return decimal.Zero, nil
+		return decimal.Zero, status, balance, nil
 
 	default:
-		return decimal.Zero, fmt.Errorf("unknown validator status: %d", status)
+		return decimal.Zero, 0, 0, fmt.Errorf("unknown validator status: %d", status)
 	}
 }
 
@@ -268,35 +389,10 @@ func (s *Service) getUserDepositPlusReward(nodeDepositAmount, validatorBalance d
 }
 
 func (s *Service) sendSubmitBalancesTx(block, totalUserEth, lsdTokenTotalSupply *big.Int) error {
-	err := s.connection.LockAndUpdateTxOpts()
-	if err != nil {
-		return fmt.Errorf("LockAndUpdateTxOpts err: %w", err)
-	}
-	defer s.connection.UnlockTxOpts()
-
 	encodeBts, err := s.networkBalancesAbi.Pack("submitBalances", block, totalUserEth, lsdTokenTotalSupply)
 	if err != nil {
 		return err
 	}
 
-	proposalId := utils.ProposalId(s.networkBalancesAddress, encodeBts, block)
-
-	// check voted
-	hasVoted, err := s.networkProposalContract.HasVoted(nil, proposalId, s.connection.Keypair().CommonAddress())
-	if err != nil {
-		return fmt.Errorf("networkProposalContract.HasVoted err: %s", err)
-	}
-	if hasVoted {
-		s.log.Info("already voted wait other voters")
-		return nil
-	}
-
-	tx, err := s.networkProposalContract.ExecProposal(s.connection.TxOpts(), s.networkBalancesAddress, encodeBts, block)
-	if err != nil {
-		return err
-	}
-
-	s.log.Info("send submitBalances tx hash: ", tx.Hash().String())
-
-	return s.waitProposalTxOk(tx.Hash(), proposalId)
+	return s.execProposal("submitBalances", newProposalCall(s.networkBalancesAddress, encodeBts, block))
 }
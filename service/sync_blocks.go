@@ -5,6 +5,8 @@ import (
 	"time"
 
 	"github.com/sirupsen/logrus"
+	"github.com/stafiprotocol/eth-lsd-relay/pkg/metrics"
+	"github.com/stafiprotocol/eth-lsd-relay/pkg/notify"
 	"github.com/stafiprotocol/eth-lsd-relay/pkg/utils"
 	"golang.org/x/sync/errgroup"
 )
@@ -19,6 +21,9 @@ func (s *Service) syncBlocks() error {
 	if err != nil {
 		return err
 	}
+	defer func() {
+		metrics.SetSyncLag(s.lsdTokenAddress.String(), beaconHead.FinalizedSlot, s.latestSlotOfSyncBlock)
+	}()
 
 	if beaconHead.FinalizedSlot <= s.latestSlotOfSyncBlock {
 		s.log.WithField("handler", "syncBlocks").
@@ -104,7 +109,15 @@ func (s *Service) syncBlocks() error {
 			if beaconBlock.ExecutionBlockNumber > s.latestBlockOfSyncBlock {
 				if beaconBlock.ExecutionBlockNumber-s.latestBlockOfSyncBlock > 1 {
 					// rpc error missing some blocks
-					return fmt.Errorf("%w at slot: %d desired eth1 block: %d", ErrMissingEth1Block, beaconBlock.BeaconBlockId, s.latestBlockOfSyncBlock+1)
+					err := fmt.Errorf("%w at slot: %d desired eth1 block: %d", ErrMissingEth1Block, beaconBlock.BeaconBlockId, s.latestBlockOfSyncBlock+1)
+					notify.Notify(notify.Alert{
+						Key:      "missing-eth1-block",
+						Severity: notify.SeverityCritical,
+						Title:    "beacon chain missing eth1 block",
+						Message:  err.Error(),
+						Fields:   map[string]string{"lsdToken": s.lsdTokenAddress.String()},
+					})
+					return err
 				}
 				s.latestBlockOfSyncBlock = beaconBlock.ExecutionBlockNumber
 			}
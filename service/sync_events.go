@@ -8,15 +8,18 @@ import (
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/shopspring/decimal"
 	"github.com/sirupsen/logrus"
+	"github.com/stafiprotocol/eth-lsd-relay/pkg/connection/beacon"
 )
 
 const (
-	fetchEth1WaitBlockNumbers = uint64(2)
-	depositEventPreBlocks     = 14400 // 2days
+	depositEventPreBlocks = 14400 // 2days
+
+	// the finalized slot may be empty, earlier slots are tried up to this many
+	maxFinalizedSlotsLookback = 64
 )
 
 func (s *Service) syncEvents() error {
-	latestBlockNumber, err := s.connection.Eth1LatestBlock()
+	latestBlockNumber, err := s.eventsSafeBlock(s.connection)
 	if err != nil {
 		return err
 	}
@@ -39,19 +42,13 @@ func (s *Service) syncEvents() error {
 	}
 	s.latestMerkleRootEpoch = latestMerkleRootEpoch.Uint64()
 
-	if latestBlockNumber > fetchEth1WaitBlockNumbers {
-		latestBlockNumber -= fetchEth1WaitBlockNumbers
-	}
-
 	s.log.Debugf("latestBlockNumber: %d, latestBlockOfSyncEvents: %d", latestBlockNumber, s.latestBlockOfSyncEvents)
 
-	if latestBlockNumber <= uint64(s.latestBlockOfSyncEvents) {
+	start, end, ok := nextEventsRange(s.latestBlockOfSyncEvents, latestBlockNumber)
+	if !ok {
 		return nil
 	}
 
-	start := uint64(s.latestBlockOfSyncEvents + 1)
-	end := latestBlockNumber
-
 	for i := start; i <= end; i += s.eventFilterMaxSpanBlocks {
 		subStart := i
 		subEnd := i + s.eventFilterMaxSpanBlocks - 1
@@ -96,6 +93,62 @@ func (s *Service) syncEvents() error {
 	return nil
 }
 
+// nextEventsRange returns the blocks syncEvents fetches next. ok is false if the safe block is not beyond the
+// synced one, e.g. after eventConfirmationBlocks was raised, the synced events are kept and nothing is fetched
+// until the safe block passes them
+func nextEventsRange(latestBlockOfSyncEvents, safeBlock uint64) (start, end uint64, ok bool) {
+	if safeBlock <= latestBlockOfSyncEvents {
+		return 0, 0, false
+	}
+	return latestBlockOfSyncEvents + 1, safeBlock, true
+}
+
+// safeBlockSource is the part of the connection eventsSafeBlock reads
+type safeBlockSource interface {
+	Eth1LatestBlock() (uint64, error)
+	BeaconHead() (beacon.BeaconHead, error)
+	GetBeaconBlock(slot uint64) (beacon.BeaconBlock, bool, error)
+}
+
+// eventsSafeBlock returns the latest block whose events can be synced without being reorged out later: the
+// finalized execution block by default, or the latest block minus eventConfirmationBlocks if configured.
+// Events are only kept in memory and checkpointed up to it, so a reorg never leaves stale events behind
+func (s *Service) eventsSafeBlock(src safeBlockSource) (uint64, error) {
+	if s.eventConfirmationBlocks > 0 {
+		latestBlockNumber, err := src.Eth1LatestBlock()
+		if err != nil {
+			return 0, err
+		}
+		if latestBlockNumber <= s.eventConfirmationBlocks {
+			return 0, nil
+		}
+		return latestBlockNumber - s.eventConfirmationBlocks, nil
+	}
+
+	beaconHead, err := src.BeaconHead()
+	if err != nil {
+		return 0, err
+	}
+	if beaconHead.FinalizedSlot == s.finalizedSlotOfSafeBlock && s.safeBlockOfEvents != 0 {
+		return s.safeBlockOfEvents, nil
+	}
+	for slot := beaconHead.FinalizedSlot; slot+maxFinalizedSlotsLookback > beaconHead.FinalizedSlot; slot-- {
+		beaconBlock, exist, err := src.GetBeaconBlock(slot)
+		if err != nil {
+			return 0, fmt.Errorf("fail to get beacon block[%d]: %w", slot, err)
+		}
+		if exist && beaconBlock.ExecutionBlockNumber != 0 {
+			s.finalizedSlotOfSafeBlock = beaconHead.FinalizedSlot
+			s.safeBlockOfEvents = beaconBlock.ExecutionBlockNumber
+			return s.safeBlockOfEvents, nil
+		}
+		if slot == 0 {
+			break
+		}
+	}
+	return 0, fmt.Errorf("no execution block found before finalized slot %d", beaconHead.FinalizedSlot)
+}
+
 func (s *Service) fetchDepositContractEventsAndCache(start, end uint64) error {
 	iterDeposited, err := s.govDepositContract.FilterDepositEvent(&bind.FilterOpts{
 		Start:   start,
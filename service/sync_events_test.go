@@ -0,0 +1,118 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stafiprotocol/eth-lsd-relay/pkg/connection/beacon"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeSafeBlockSource struct {
+	latestBlock uint64
+	head        beacon.BeaconHead
+	headErr     error
+	blocks      map[uint64]beacon.BeaconBlock
+	blockErr    error
+	fetched     []uint64
+}
+
+func (f *fakeSafeBlockSource) Eth1LatestBlock() (uint64, error) {
+	return f.latestBlock, nil
+}
+
+func (f *fakeSafeBlockSource) BeaconHead() (beacon.BeaconHead, error) {
+	return f.head, f.headErr
+}
+
+func (f *fakeSafeBlockSource) GetBeaconBlock(slot uint64) (beacon.BeaconBlock, bool, error) {
+	f.fetched = append(f.fetched, slot)
+	if f.blockErr != nil {
+		return beacon.BeaconBlock{}, false, f.blockErr
+	}
+	block, exist := f.blocks[slot]
+	return block, exist, nil
+}
+
+func TestEventsSafeBlockConfirmations(t *testing.T) {
+	// the beacon chain is not read when eventConfirmationBlocks is set
+	src := &fakeSafeBlockSource{latestBlock: 1000, headErr: errors.New("beacon head read")}
+	s := &Service{eventConfirmationBlocks: 64}
+
+	safeBlock, err := s.eventsSafeBlock(src)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(936), safeBlock)
+
+	src.latestBlock = 64
+	safeBlock, err = s.eventsSafeBlock(src)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(0), safeBlock)
+	assert.Empty(t, src.fetched)
+}
+
+func TestEventsSafeBlockFinalized(t *testing.T) {
+	src := &fakeSafeBlockSource{
+		head: beacon.BeaconHead{FinalizedSlot: 1000},
+		blocks: map[uint64]beacon.BeaconBlock{
+			// pre-merge blocks have no execution payload
+			999: {Slot: 999},
+			997: {Slot: 997, ExecutionBlockNumber: 5000},
+		},
+	}
+	s := &Service{}
+
+	// 1000 and 998 were missed
+	safeBlock, err := s.eventsSafeBlock(src)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(5000), safeBlock)
+	assert.Equal(t, []uint64{1000, 999, 998, 997}, src.fetched)
+
+	// cached until the finalized slot moves
+	safeBlock, err = s.eventsSafeBlock(src)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(5000), safeBlock)
+	assert.Len(t, src.fetched, 4)
+
+	src.head.FinalizedSlot = 1032
+	src.blocks[1032] = beacon.BeaconBlock{Slot: 1032, ExecutionBlockNumber: 5030}
+	safeBlock, err = s.eventsSafeBlock(src)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(5030), safeBlock)
+}
+
+func TestEventsSafeBlockLookback(t *testing.T) {
+	// only maxFinalizedSlotsLookback slots are tried
+	src := &fakeSafeBlockSource{
+		head:   beacon.BeaconHead{FinalizedSlot: 1000},
+		blocks: map[uint64]beacon.BeaconBlock{1000 - maxFinalizedSlotsLookback: {ExecutionBlockNumber: 5000}},
+	}
+	s := &Service{}
+	_, err := s.eventsSafeBlock(src)
+	assert.ErrorContains(t, err, "no execution block found before finalized slot 1000")
+	assert.Len(t, src.fetched, maxFinalizedSlotsLookback)
+	assert.Equal(t, uint64(1000-maxFinalizedSlotsLookback+1), src.fetched[len(src.fetched)-1])
+
+	// the lookback stops at genesis
+	src = &fakeSafeBlockSource{head: beacon.BeaconHead{FinalizedSlot: 2}}
+	_, err = s.eventsSafeBlock(src)
+	assert.NotNil(t, err)
+	assert.Equal(t, []uint64{2, 1, 0}, src.fetched)
+
+	src = &fakeSafeBlockSource{head: beacon.BeaconHead{FinalizedSlot: 1000}, blockErr: errors.New("timeout")}
+	_, err = s.eventsSafeBlock(src)
+	assert.ErrorContains(t, err, "fail to get beacon block[1000]")
+	assert.Equal(t, uint64(0), s.safeBlockOfEvents)
+}
+
+func TestNextEventsRange(t *testing.T) {
+	start, end, ok := nextEventsRange(500, 600)
+	assert.True(t, ok)
+	assert.Equal(t, uint64(501), start)
+	assert.Equal(t, uint64(600), end)
+
+	_, _, ok = nextEventsRange(600, 600)
+	assert.False(t, ok)
+	// the safe block is below the synced block after eventConfirmationBlocks was raised
+	_, _, ok = nextEventsRange(600, 536)
+	assert.False(t, ok)
+}
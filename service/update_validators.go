@@ -173,6 +173,9 @@ func (s *Service) updateValidatorsFromBeacon() error {
 		"validatorStatuses len": len(validatorStatusMap),
 	}).Debug("validator statuses")
 
+	// validators are shared with the slot handler group, refreshStatus reads them under the lock
+	s.validatorsByIndexMutex.Lock()
+	defer s.validatorsByIndexMutex.Unlock()
 	for pubkey, status := range validatorStatusMap {
 		pubkeyStr := pubkey.String()
 		if status.Exists {
@@ -223,13 +226,11 @@ func (s *Service) updateValidatorsFromBeacon() error {
 	}
 
 	// cache validators by index
-	s.validatorsByIndexMutex.Lock()
 	for _, validator := range s.validators {
 		if validator.ValidatorIndex > 0 {
 			s.validatorsByIndex[validator.ValidatorIndex] = validator
 		}
 	}
-	s.validatorsByIndexMutex.Unlock()
 
 	s.latestEpochOfUpdateValidator = finalEpoch
 
@@ -0,0 +1,335 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	network_withdraw "github.com/stafiprotocol/eth-lsd-relay/bindings/NetworkWithdraw"
+	"github.com/stafiprotocol/eth-lsd-relay/pkg/utils"
+)
+
+// MerkleRootAudit is the result of checking a merkle root voted on chain, the root is sound if there is no issue
+type MerkleRootAudit struct {
+	LsdToken           string   `json:"lsdToken"`
+	Epoch              uint64   `json:"epoch"`
+	IsLatest           bool     `json:"isLatest"`
+	MerkleRoot         string   `json:"merkleRoot"`
+	NodeRewardsFileCid string   `json:"nodeRewardsFileCid"`
+	FileMerkleRoot     string   `json:"fileMerkleRoot"`
+	Nodes              int      `json:"nodes"`
+	PreviousEpoch      uint64   `json:"previousEpoch"` // 0 if this is the first merkle root
+	PreviousFileCid    string   `json:"previousFileCid"`
+	Recomputed         bool     `json:"recomputed"`
+	RecomputedRoot     string   `json:"recomputedRoot,omitempty"`
+	Issues             []string `json:"issues"`
+}
+
+func (a *MerkleRootAudit) addIssue(format string, args ...any) {
+	a.Issues = append(a.Issues, fmt.Sprintf(format, args...))
+}
+
+// VerifyMerkleRoot audits the merkle root of lsdToken set on chain at epoch (the latest one if epoch is 0),
+// with recompute the rewards since the previous root are recomputed from chain data as submitted by setMerkleRoot
+func (m *ServiceManager) VerifyMerkleRoot(ctx context.Context, lsdToken string, epoch uint64, recompute bool) (*MerkleRootAudit, error) {
+	srvConfig := *m.cfg
+	srvConfig.Contracts.LsdTokenAddress = lsdToken
+	srv, err := NewService(&srvConfig, m, m.connection, m.localStore, m.stateStore)
+	if err != nil {
+		return nil, fmt.Errorf("new service for lsd token %s err %s", lsdToken, err.Error())
+	}
+	defer srv.Stop()
+
+	if err = srv.initialize(); err != nil {
+		return nil, fmt.Errorf("init service for lsd token %s err %s", lsdToken, err.Error())
+	}
+	return srv.auditMerkleRoot(ctx, epoch, recompute)
+}
+
+func (s *Service) auditMerkleRoot(ctx context.Context, epoch uint64, recompute bool) (*MerkleRootAudit, error) {
+	latestBlock, err := s.connection.Eth1LatestBlock()
+	if err != nil {
+		return nil, err
+	}
+	callOpts := s.connection.CallOpts(new(big.Int).SetUint64(latestBlock))
+	latestEpochBig, err := s.networkWithdrawContract.LatestMerkleRootEpoch(callOpts)
+	if err != nil {
+		return nil, err
+	}
+	latestEpoch := latestEpochBig.Uint64()
+	if latestEpoch == 0 {
+		return nil, ErrNoMerkleRoot
+	}
+	if epoch == 0 {
+		epoch = latestEpoch
+	}
+	if epoch > latestEpoch {
+		return nil, fmt.Errorf("%w: epoch %d is after the latest merkle root epoch %d", ErrMerkleRootNotFound, epoch, latestEpoch)
+	}
+
+	event, err := s.setMerkleRootEventOfEpoch(epoch, latestBlock)
+	if err != nil {
+		return nil, err
+	}
+	audit := &MerkleRootAudit{
+		LsdToken:           s.lsdTokenAddress.String(),
+		Epoch:              epoch,
+		IsLatest:           epoch == latestEpoch,
+		MerkleRoot:         common.Hash(event.MerkleRoot).String(),
+		NodeRewardsFileCid: event.NodeRewardsFileCid,
+		Issues:             make([]string, 0),
+	}
+	if audit.IsLatest {
+		root, err := s.networkWithdrawContract.MerkleRoot(callOpts)
+		if err != nil {
+			return nil, err
+		}
+		cid, err := s.networkWithdrawContract.NodeRewardsFileCid(callOpts)
+		if err != nil {
+			return nil, err
+		}
+		if root != event.MerkleRoot || cid != event.NodeRewardsFileCid {
+			audit.addIssue("contract state (root %s, cid %s) differs from the SetMerkleRoot event", common.Hash(root).String(), cid)
+		}
+	}
+
+	list, err := s.nodeRewardsListOf(event.NodeRewardsFileCid, epoch)
+	if err != nil {
+		return nil, err
+	}
+	audit.Nodes = len(list.List)
+	audit.FileMerkleRoot = checkNodeRewardsList(audit, list, event.MerkleRoot).String()
+
+	// accumulative amounts never decrease from the previous root
+	preList := NodeRewardsList{}
+	dealtEth1BlockHeight := s.startAtBlock
+	preEvent, err := s.previousSetMerkleRootEvent(event.Raw.BlockNumber, epoch)
+	if err != nil {
+		return nil, err
+	}
+	if preEvent != nil {
+		audit.PreviousEpoch = preEvent.DealedEpoch.Uint64()
+		audit.PreviousFileCid = preEvent.NodeRewardsFileCid
+		if preList, err = s.nodeRewardsListOf(preEvent.NodeRewardsFileCid, audit.PreviousEpoch); err != nil {
+			return nil, err
+		}
+		checkAccumulativeNodeRewards(audit, preList, list)
+		if dealtEth1BlockHeight, err = s.getEpochStartBlocknumberWithCheck(audit.PreviousEpoch); err != nil {
+			return nil, err
+		}
+	}
+
+	if !recompute {
+		return audit, nil
+	}
+	targetEth1BlockHeight, err := s.getEpochStartBlocknumberWithCheck(epoch)
+	if err != nil {
+		return nil, err
+	}
+	if err = s.syncToBlock(ctx, dealtEth1BlockHeight, targetEth1BlockHeight); err != nil {
+		return nil, err
+	}
+	recomputedList, rootHash, err := s.buildNodeRewardsList(preList, dealtEth1BlockHeight, epoch, targetEth1BlockHeight)
+	if err != nil {
+		return nil, fmt.Errorf("recompute node rewards err: %w", err)
+	}
+	audit.Recomputed = true
+	audit.RecomputedRoot = common.BytesToHash(rootHash).String()
+	if common.BytesToHash(rootHash) != common.Hash(event.MerkleRoot) {
+		audit.addIssue("recomputed root %s differs from the root on chain", audit.RecomputedRoot)
+		compareNodeRewardsLists(audit, recomputedList, list)
+	}
+	return audit, nil
+}
+
+// nodeRewardsListOf downloads the rewards file of epoch and checks its cid
+func (s *Service) nodeRewardsListOf(cid string, epoch uint64) (NodeRewardsList, error) {
+	fileBytes, err := s.downloadNodeRewards(cid, epoch)
+	if err != nil {
+		return NodeRewardsList{}, err
+	}
+	list := NodeRewardsList{}
+	if err = json.Unmarshal(fileBytes, &list); err != nil {
+		return NodeRewardsList{}, err
+	}
+	if list.Epoch != epoch {
+		return NodeRewardsList{}, fmt.Errorf("node rewards file epoch %d does not match %d, cid: %s", list.Epoch, epoch, cid)
+	}
+	return list, nil
+}
+
+// previousSetMerkleRootEvent scans back from beforeBlock for the latest SetMerkleRoot event of an epoch before epoch,
+// it returns nil if there is none since the lsd network was created
+func (s *Service) previousSetMerkleRootEvent(beforeBlock, epoch uint64) (*network_withdraw.NetworkWithdrawSetMerkleRoot, error) {
+	for end := beforeBlock; end >= s.startAtBlock; {
+		start := s.startAtBlock
+		if end-start+1 > s.eventFilterMaxSpanBlocks {
+			start = end - s.eventFilterMaxSpanBlocks + 1
+		}
+		iter, err := s.networkWithdrawContract.FilterSetMerkleRoot(&bind.FilterOpts{
+			Start: start,
+			End:   &end,
+		}, nil)
+		if err != nil {
+			return nil, fmt.Errorf("filter set merkle root err: %w", err)
+		}
+		var previous *network_withdraw.NetworkWithdrawSetMerkleRoot
+		for iter.Next() {
+			if iter.Event.DealedEpoch.Uint64() < epoch {
+				previous = iter.Event
+			}
+		}
+		err = iter.Error()
+		iter.Close()
+		if err != nil {
+			return nil, err
+		}
+		if previous != nil {
+			return previous, nil
+		}
+		if start == 0 {
+			break
+		}
+		end = start - 1
+	}
+	return nil, nil
+}
+
+// checkNodeRewardsList checks the layout of list and every proof in it against root, it returns the root of list
+func checkNodeRewardsList(audit *MerkleRootAudit, list NodeRewardsList, root common.Hash) common.Hash {
+	seen := make(map[common.Address]bool, len(list.List))
+	for i, node := range list.List {
+		address := common.HexToAddress(node.Address)
+		if seen[address] {
+			audit.addIssue("duplicate node %s", node.Address)
+		}
+		seen[address] = true
+		if node.Index != uint32(i) {
+			audit.addIssue("node %s has index %d at position %d", node.Address, node.Index, i)
+		}
+		if i > 0 && list.List[i-1].Address >= node.Address {
+			audit.addIssue("node %s is not sorted by address", node.Address)
+		}
+		if node.TotalRewardAmount.IsNegative() || node.TotalExitDepositAmount.IsNegative() {
+			audit.addIssue("node %s has negative amounts", node.Address)
+		}
+		if node.TotalExitDepositAmount.GreaterThan(node.TotalDepositAmount) {
+			audit.addIssue("node %s totalExitDepositAmount %s greater than totalDepositAmount %s",
+				node.Address, node.TotalExitDepositAmount.StringFixed(0), node.TotalDepositAmount.StringFixed(0))
+		}
+	}
+
+	if len(list.List) == 0 {
+		if root != (common.Hash{}) {
+			audit.addIssue("empty rewards list with non-zero root")
+		}
+		return common.Hash{}
+	}
+	tree, err := buildMerkleTree(list)
+	if err != nil {
+		audit.addIssue("build merkle tree err: %s", err.Error())
+		return common.Hash{}
+	}
+	rootHash, err := tree.GetRootHash()
+	if err != nil {
+		audit.addIssue("get merkle root err: %s", err.Error())
+		return common.Hash{}
+	}
+	fileRoot := common.BytesToHash(rootHash)
+	if fileRoot != root {
+		audit.addIssue("root of rewards file %s differs from the root on chain", fileRoot.String())
+	}
+
+	for _, node := range list.List {
+		leaf := utils.GetNodeHash(big.NewInt(int64(node.Index)), common.HexToAddress(node.Address),
+			node.TotalRewardAmount.BigInt(), node.TotalExitDepositAmount.BigInt())
+		proofList, err := tree.GetProof(leaf)
+		if err != nil {
+			audit.addIssue("node %s get proof err: %s", node.Address, err.Error())
+			continue
+		}
+		if !utils.VerifyProof(leaf, proofList, root.Bytes()) {
+			audit.addIssue("proof of node %s does not verify against the root on chain", node.Address)
+		}
+		if !proofEqual(node.Proof, proofList) {
+			audit.addIssue("proof of node %s in rewards file is not the one of the tree", node.Address)
+		}
+	}
+	return fileRoot
+}
+
+// checkAccumulativeNodeRewards checks that no node disappears and no accumulative amount decreases
+func checkAccumulativeNodeRewards(audit *MerkleRootAudit, preList, list NodeRewardsList) {
+	nodes := make(map[common.Address]*NodeReward, len(list.List))
+	for _, node := range list.List {
+		nodes[common.HexToAddress(node.Address)] = node
+	}
+	for _, pre := range preList.List {
+		node, exist := nodes[common.HexToAddress(pre.Address)]
+		if !exist {
+			audit.addIssue("node %s of epoch %d is missing", pre.Address, preList.Epoch)
+			continue
+		}
+		if node.TotalRewardAmount.LessThan(pre.TotalRewardAmount.Floor()) {
+			audit.addIssue("node %s totalRewardAmount decreased from %s to %s",
+				pre.Address, pre.TotalRewardAmount.StringFixed(0), node.TotalRewardAmount.StringFixed(0))
+		}
+		if node.TotalExitDepositAmount.LessThan(pre.TotalExitDepositAmount) {
+			audit.addIssue("node %s totalExitDepositAmount decreased from %s to %s",
+				pre.Address, pre.TotalExitDepositAmount.StringFixed(0), node.TotalExitDepositAmount.StringFixed(0))
+		}
+		if node.TotalDepositAmount.LessThan(pre.TotalDepositAmount) {
+			audit.addIssue("node %s totalDepositAmount decreased from %s to %s",
+				pre.Address, pre.TotalDepositAmount.StringFixed(0), node.TotalDepositAmount.StringFixed(0))
+		}
+	}
+}
+
+// compareNodeRewardsLists reports nodes whose amounts differ between the recomputed list and the one on chain
+func compareNodeRewardsLists(audit *MerkleRootAudit, recomputed, list NodeRewardsList) {
+	nodes := make(map[common.Address]*NodeReward, len(list.List))
+	for _, node := range list.List {
+		nodes[common.HexToAddress(node.Address)] = node
+	}
+	for _, expected := range recomputed.List {
+		address := common.HexToAddress(expected.Address)
+		node, exist := nodes[address]
+		if !exist {
+			audit.addIssue("node %s is missing, recomputed totalRewardAmount %s", expected.Address, expected.TotalRewardAmount.StringFixed(0))
+			continue
+		}
+		delete(nodes, address)
+		if !node.TotalRewardAmount.Equal(expected.TotalRewardAmount) ||
+			!node.TotalExitDepositAmount.Equal(expected.TotalExitDepositAmount) ||
+			!node.TotalDepositAmount.Equal(expected.TotalDepositAmount) {
+			audit.addIssue("node %s amounts (reward %s, exitDeposit %s, deposit %s) differ from recomputed (%s, %s, %s)", expected.Address,
+				node.TotalRewardAmount.StringFixed(0), node.TotalExitDepositAmount.StringFixed(0), node.TotalDepositAmount.StringFixed(0),
+				expected.TotalRewardAmount.StringFixed(0), expected.TotalExitDepositAmount.StringFixed(0), expected.TotalDepositAmount.StringFixed(0))
+		}
+	}
+	for address := range nodes {
+		audit.addIssue("node %s is not in the recomputed list", address.String())
+	}
+}
+
+// proofEqual compares a proof stored in rewards files, hashes joined by ':', with proofList
+func proofEqual(proof string, proofList []utils.NodeHash) bool {
+	if proof == "" {
+		return len(proofList) == 0
+	}
+	parts := strings.Split(proof, ":")
+	if len(parts) != len(proofList) {
+		return false
+	}
+	for i, part := range parts {
+		if !bytes.Equal(common.FromHex(part), proofList[i]) {
+			return false
+		}
+	}
+	return true
+}
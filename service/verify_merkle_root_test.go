@@ -0,0 +1,87 @@
+package service
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestNodeRewardsList(epoch uint64, rewards ...int64) NodeRewardsList {
+	list := NodeRewardsList{Epoch: epoch}
+	for i, reward := range rewards {
+		list.List = append(list.List, &NodeReward{
+			Address:            common.BigToAddress(big.NewInt(int64(i + 1))).String(),
+			Index:              uint32(i),
+			TotalRewardAmount:  decimal.NewFromInt(reward),
+			TotalDepositAmount: decimal.NewFromInt(1e18),
+		})
+	}
+	return list
+}
+
+func TestCheckNodeRewardsList(t *testing.T) {
+	list := newTestNodeRewardsList(900, 1e15, 2e15, 3e15)
+	tree, err := buildMerkleTree(list)
+	assert.Nil(t, err)
+	rootHash, err := tree.GetRootHash()
+	assert.Nil(t, err)
+	root := common.BytesToHash(rootHash)
+	rewards, err := newVerifiedNodeRewards(list, "", root)
+	assert.Nil(t, err)
+	for _, node := range list.List {
+		proof, err := rewards.proofOf(common.HexToAddress(node.Address))
+		assert.Nil(t, err)
+		// stored the way setMerkleRoot does, without 0x
+		for i, p := range proof.Proof {
+			if i > 0 {
+				node.Proof += ":"
+			}
+			node.Proof += p[2:]
+		}
+	}
+
+	audit := &MerkleRootAudit{}
+	assert.Equal(t, root, checkNodeRewardsList(audit, list, root))
+	assert.Empty(t, audit.Issues)
+
+	audit = &MerkleRootAudit{}
+	checkNodeRewardsList(audit, list, common.Hash{1})
+	// root mismatch plus every proof failing
+	assert.Len(t, audit.Issues, 4)
+
+	list.List[1].TotalExitDepositAmount = decimal.NewFromInt(2e18)
+	list.List[2].Index = 5
+	audit = &MerkleRootAudit{}
+	checkNodeRewardsList(audit, list, root)
+	assert.Contains(t, audit.Issues, "node "+list.List[1].Address+" totalExitDepositAmount 2000000000000000000 greater than totalDepositAmount 1000000000000000000")
+	assert.Contains(t, audit.Issues, "node "+list.List[2].Address+" has index 5 at position 2")
+
+	audit = &MerkleRootAudit{}
+	checkNodeRewardsList(audit, NodeRewardsList{}, common.Hash{})
+	assert.Empty(t, audit.Issues)
+}
+
+func TestCheckAccumulativeNodeRewards(t *testing.T) {
+	pre := newTestNodeRewardsList(675, 1e15, 2e15)
+	cur := newTestNodeRewardsList(900, 1e15, 3e15, 1e15)
+
+	audit := &MerkleRootAudit{}
+	checkAccumulativeNodeRewards(audit, pre, cur)
+	assert.Empty(t, audit.Issues)
+
+	cur.List[0].TotalRewardAmount = decimal.NewFromInt(1e14)
+	audit = &MerkleRootAudit{}
+	checkAccumulativeNodeRewards(audit, pre, cur)
+	assert.Equal(t, []string{"node " + cur.List[0].Address + " totalRewardAmount decreased from 1000000000000000 to 100000000000000"}, audit.Issues)
+
+	audit = &MerkleRootAudit{}
+	checkAccumulativeNodeRewards(audit, pre, newTestNodeRewardsList(900, 1e15))
+	assert.Len(t, audit.Issues, 1)
+
+	audit = &MerkleRootAudit{}
+	compareNodeRewardsLists(audit, newTestNodeRewardsList(900, 1e15, 3e15), newTestNodeRewardsList(900, 1e15, 4e15))
+	assert.Len(t, audit.Issues, 1)
+}
@@ -8,13 +8,13 @@ import (
 	"math/big"
 	"time"
 
-	"github.com/ethereum/go-ethereum/common"
 	"github.com/prysmaticlabs/prysm/v4/contracts/deposit"
 	ethpb "github.com/prysmaticlabs/prysm/v4/proto/prysm/v1alpha1"
 	"github.com/samber/lo"
 	"github.com/sirupsen/logrus"
 	"github.com/stafiprotocol/eth-lsd-relay/pkg/connection/beacon"
 	"github.com/stafiprotocol/eth-lsd-relay/pkg/connection/types"
+	"github.com/stafiprotocol/eth-lsd-relay/pkg/notify"
 	"github.com/stafiprotocol/eth-lsd-relay/pkg/utils"
 )
 
@@ -92,6 +92,18 @@ func (s *Service) voteWithdrawCredentials() error {
 			"pubkey": validator.Pubkey,
 			"match":  match,
 		}).Debug("match info")
+		if !match {
+			notify.Notify(notify.Alert{
+				Key:      "withdraw-credentials-mismatch/" + validatorPubkey.String(),
+				Severity: notify.SeverityWarning,
+				Title:    "validator voted as withdraw credentials mismatch",
+				Fields: map[string]string{
+					"lsdToken":              s.lsdTokenAddress.String(),
+					"pubkey":                validatorPubkey.String(),
+					"withdrawalCredentials": validatorStatus.WithdrawalCredentials.String(),
+				},
+			})
+		}
 
 		validatorPubkeys = append(validatorPubkeys, validator.Pubkey)
 		validatorMatches = append(validatorMatches, match)
@@ -119,62 +131,21 @@ func (s *Service) voteWithdrawCredentialsTx(validatorPubkeys [][]byte, matches [
 		return fmt.Errorf("validators and matches len not match")
 	}
 
-	tos := make([]common.Address, 0)
-	callDatas := make([][]byte, 0)
-	blocks := make([]*big.Int, 0)
-	proposalIds := make([][32]byte, 0)
-
+	calls := make([]proposalCall, 0, len(validatorPubkeys))
 	for i := 0; i < len(validatorPubkeys); i++ {
 		encodeBts, err := s.nodeDepositAbi.Pack("voteWithdrawCredentials", validatorPubkeys[i], matches[i])
 		if err != nil {
 			return err
 		}
-
-		proposalId := utils.ProposalId(s.nodeDepositAddress, encodeBts, big.NewInt(0))
-
-		// check voted
-		hasVoted, err := s.networkProposalContract.HasVoted(nil, proposalId, s.connection.Keypair().CommonAddress())
-		if err != nil {
-			return fmt.Errorf("networkProposalContract.HasVoted err: %s", err)
-		}
-		if hasVoted {
-			continue
-		}
-
-		tos = append(tos, s.nodeDepositAddress)
-		callDatas = append(callDatas, encodeBts)
-		blocks = append(blocks, big.NewInt(0))
-		proposalIds = append(proposalIds, proposalId)
-	}
-
-	if len(tos) == 0 {
-		return nil
+		calls = append(calls, newProposalCall(s.nodeDepositAddress, encodeBts, big.NewInt(0)))
 	}
 
-	err := s.connection.LockAndUpdateTxOpts()
-	if err != nil {
-		return err
-	}
-	defer s.connection.UnlockTxOpts()
-
-	s.log.WithFields(logrus.Fields{
-		"gasPrice": s.connection.TxOpts().GasPrice.String(),
-		"gasLimit": s.connection.TxOpts().GasLimit,
-	}).Debug("tx opts")
-
 	s.log.WithFields(logrus.Fields{
 		"pubkeys": pubkeyToHex(validatorPubkeys),
 		"matches": matches,
 	}).Info("voteForNode")
 
-	tx, err := s.networkProposalContract.BatchExecProposals(s.connection.TxOpts(), tos, callDatas, blocks)
-	if err != nil {
-		return err
-	}
-
-	s.log.Info("send vote tx hash: ", tx.Hash().String())
-
-	return s.waitProposalsTxOk(tx.Hash(), proposalIds)
+	return s.batchExecProposals("voteWithdrawCredentials", calls)
 }
 
 func pubkeyToHex(pubkeys [][]byte) []string {